@@ -0,0 +1,143 @@
+package generate
+
+import (
+	"math"
+	"time"
+)
+
+// ArchipelagoOptions controls GenerateArchipelago
+type ArchipelagoOptions struct {
+	Islands int     // how many landmasses to generate
+	Scale   float64 // size of the coastline features in tiles, larger is smoother
+	Bridges bool    // connect the islands with roads, crossing water with TileBridge
+	Boats   bool    // place a TileBoat on the "markers" layer next to each island
+}
+
+// DefaultArchipelagoOptions returns options for a handful of islands connected by bridges
+func DefaultArchipelagoOptions() ArchipelagoOptions {
+	return ArchipelagoOptions{
+		Islands: 4,
+		Scale:   12,
+		Bridges: true,
+		Boats:   false,
+	}
+}
+
+// Land heights for the different biomes, heights are in the range 0-1
+const (
+	archipelagoShore = 0.3
+	archipelagoBeach = 0.38
+)
+
+// GenerateArchipelago generates multiple islands separated by water. Each island is noise shaped by a falloff from
+// its center, water is TileWater (BiomeOcean), coasts are TileSand (BiomeBeach) and the inland is TileGrass and
+// TileTree (BiomeGrassland and BiomeForest). Open land isn't a room, so the map has none
+func (world *World) GenerateArchipelago(opts ArchipelagoOptions, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("Archipelago", 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	if opts.Islands <= 0 || opts.Scale <= 0 {
+		return ErrNotEnoughSpace
+	}
+	w, h, b := world.Width, world.Height, world.Border
+	radius := float64(minInt(w, h)-b*2) / math.Sqrt(float64(opts.Islands)) / 2
+	if radius < 3 {
+		return ErrNotEnoughSpace
+	}
+
+	// Spread the island centers out, relaxing the spacing if they don't fit
+	centers := make([]Point, 0, opts.Islands)
+	margin := int(radius / 2)
+	if w-b*2-margin*2 <= 0 || h-b*2-margin*2 <= 0 {
+		return ErrNotEnoughSpace
+	}
+	spacing := radius * 1.5
+	for len(centers) < opts.Islands {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		}
+		p := Point{X: world.randInt(b+margin, w-b-margin-1), Y: world.randInt(b+margin, h-b-margin-1)}
+		ok := true
+		for _, c := range centers {
+			if p.distance(c) < spacing {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			centers = append(centers, p)
+		} else {
+			spacing *= 0.99
+		}
+	}
+
+	land, trees := newNoise(world.rng), newNoise(world.rng)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var falloff float64
+			for _, c := range centers {
+				d := Point{X: x, Y: y}.distance(c) / radius
+				falloff = math.Max(falloff, 1-d*d)
+			}
+			height := falloff * (0.5 + 0.5*land.Fractal(float64(x)/opts.Scale, float64(y)/opts.Scale, 4))
+
+			switch {
+			case height < archipelagoShore || x < b || y < b || x >= w-b || y >= h-b:
+				world.SetTile(x, y, TileWater)
+				world.Biomes[y][x] = BiomeOcean
+			case height < archipelagoBeach:
+				world.SetTile(x, y, TileSand)
+				world.Biomes[y][x] = BiomeBeach
+			case trees.Fractal(float64(x)/6, float64(y)/6, 2) > 0.6:
+				world.SetTile(x, y, TileTree)
+				world.Biomes[y][x] = BiomeForest
+			default:
+				world.SetTile(x, y, TileGrass)
+				world.Biomes[y][x] = BiomeGrassland
+			}
+		}
+	}
+
+	if opts.Bridges {
+		if err := world.ConnectPOIs(centers, RoadStyleDirt); err != nil {
+			return err
+		}
+	}
+
+	if opts.Boats {
+		markers := world.Layer("markers")
+		isLand := func(x, y int) bool {
+			return world.Biomes[y][x] != BiomeOcean
+		}
+		for _, c := range centers {
+			// The closest shore to the island's center
+			dist := world.bfs(c, isLand)
+			best, bestDist := Point{X: -1}, -1
+			for y := b; y < h-b; y++ {
+				for x := b; x < w-b; x++ {
+					if world.Tiles[y][x] != TileWater || (bestDist != -1 && dist[y][x] >= bestDist) {
+						continue
+					}
+					for _, o := range polarOffsets {
+						ox, oy := x+o.X, y+o.Y
+						if ox < 0 || oy < 0 || ox >= w || oy >= h {
+							continue
+						}
+						if d := dist[oy][ox]; d != -1 && (bestDist == -1 || d < bestDist) {
+							best, bestDist = Point{X: x, Y: y}, d
+						}
+					}
+				}
+			}
+			if best.X != -1 {
+				markers[best.Y][best.X] = TileBoat
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package generate
+
+import "time"
+
+// ArenaOptions controls GenerateArena
+type ArenaOptions struct {
+	CoverDensity  float64 // fraction (0-1) of the floor to cover with obstacles
+	LowWallRatio  float64 // fraction (0-1) of the obstacles which are TileLowWall rather than TilePillar
+	Entrances     int     // number of entrances placed on the edges of the arena, from 0 up to 4
+	MinVisibility float64 // fraction (0-1) of the floor which must stay visible from the center of the arena
+}
+
+// DefaultArenaOptions returns options for a fairly open arena with an entrance on every side
+func DefaultArenaOptions() ArenaOptions {
+	return ArenaOptions{
+		CoverDensity:  0.08,
+		LowWallRatio:  0.5,
+		Entrances:     4,
+		MinVisibility: 0.6,
+	}
+}
+
+// GenerateArena generates the world as a single large room filled with cover for wave based game modes. Cover is
+// either TilePillar, which blocks movement and sight, or TileLowWall, which only blocks movement. Cover is only kept if
+// the whole floor can still be walked and enough of it can be seen from the center of the arena, so there can be less
+// cover than opts.CoverDensity asks for when no more fits.
+// Entrances are placed in the middle of the arena's sides, recorded in world.Doors and tagged with TagEntrance
+func (world *World) GenerateArena(opts ArenaOptions, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("Arena", 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	wt := wallGap(world.WallThickness)
+	room := Rect{
+		X: world.Border + wt,
+		Y: world.Border + wt,
+		W: world.Width - (world.Border+wt)*2,
+		H: world.Height - (world.Border+wt)*2,
+	}
+	if room.W < 5 || room.H < 5 {
+		return ErrNotEnoughSpace
+	}
+	if err := world.placeRoom(room.X, room.Y, room.W, room.H, wt); err != nil {
+		return err
+	}
+	world.TagRoom(room, TagArena)
+
+	center := Point{X: room.X + room.W/2, Y: room.Y + room.H/2}
+	floorCount := room.W * room.H
+	valid := func() bool {
+		// Everything must be reachable and visible enough
+		dist := world.DistanceField(center)
+		var reachable, visible int
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				if world.Tiles[y][x] != TileFloor {
+					continue
+				}
+				if dist[y][x] != -1 {
+					reachable++
+				}
+				if world.lineOfSight(center, Point{X: x, Y: y}) {
+					visible++
+				}
+			}
+		}
+		return reachable == floorCount && float64(visible) >= float64(floorCount)*opts.MinVisibility
+	}
+
+	// Cover, kept a tile away from other cover and the walls so it can be walked around. A pass is as many tries as the
+	// room has tiles, when a whole pass places nothing there's no room left for more
+	target := int(float64(room.W*room.H) * opts.CoverDensity)
+	for covered, misses := 0, 0; covered < target && misses < room.W*room.H; misses++ {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			break
+		}
+
+		var cover Rect
+		tile := TilePillar
+		if world.rng.Float64() < opts.LowWallRatio {
+			tile = TileLowWall
+			if world.rng.Int()%2 == 0 {
+				cover = Rect{W: world.randInt(3, 5), H: 1}
+			} else {
+				cover = Rect{W: 1, H: world.randInt(3, 5)}
+			}
+		} else {
+			s := world.randInt(1, 2)
+			cover = Rect{W: s, H: s}
+		}
+		if room.W-cover.W-4 < 0 || room.H-cover.H-4 < 0 {
+			continue
+		}
+		cover.X = world.randInt(room.X+2, room.X+room.W-cover.W-2)
+		cover.Y = world.randInt(room.Y+2, room.Y+room.H-cover.H-2)
+		if cover.contains(center.X, center.Y) {
+			continue
+		}
+
+		clear := true
+		for x := cover.X - 1; x < cover.X+cover.W+1 && clear; x++ {
+			for y := cover.Y - 1; y < cover.Y+cover.H+1; y++ {
+				if world.Tiles[y][x] != TileFloor {
+					clear = false
+					break
+				}
+			}
+		}
+		if !clear {
+			continue
+		}
+
+		for x := cover.X; x < cover.X+cover.W; x++ {
+			for y := cover.Y; y < cover.Y+cover.H; y++ {
+				world.SetTile(x, y, tile)
+			}
+		}
+		floorCount -= cover.W * cover.H
+		if !valid() {
+			for x := cover.X; x < cover.X+cover.W; x++ {
+				for y := cover.Y; y < cover.Y+cover.H; y++ {
+					world.setFloor(x, y, FloorKindRoom)
+				}
+			}
+			floorCount += cover.W * cover.H
+			continue
+		}
+		covered += cover.W * cover.H
+		misses = -1
+	}
+
+	// Entrances through the middle of each side
+	cs := world.randInt(world.MinCorridorSize, world.MaxCorridorSize)
+	sides := world.rng.Perm(4)
+	for _, side := range sides[:maxInt(minInt(opts.Entrances, 4), 0)] {
+		var entrance Rect
+		dir := DoorDirectionHorizontal
+		switch Direction(side) {
+		case DirectionNorth:
+			entrance = Rect{X: centered(room.X, room.W, cs), Y: room.Y - wt, W: cs, H: wt}
+		case DirectionSouth:
+			entrance = Rect{X: centered(room.X, room.W, cs), Y: room.Y + room.H, W: cs, H: wt}
+		case DirectionWest:
+			entrance = Rect{X: room.X - wt, Y: centered(room.Y, room.H, cs), W: wt, H: cs}
+			dir = DoorDirectionVertical
+		case DirectionEast:
+			entrance = Rect{X: room.X + room.W, Y: centered(room.Y, room.H, cs), W: wt, H: cs}
+			dir = DoorDirectionVertical
+		}
+		for x := entrance.X; x < entrance.X+entrance.W; x++ {
+			for y := entrance.Y; y < entrance.Y+entrance.H; y++ {
+				world.setFloor(x, y, FloorKindCorridor)
+			}
+		}
+		world.TagDoor(world.addDoorway(entrance, dir), TagEntrance)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package generate
+
+// TileToIndex maps tiles to the indexes of a tileset atlas for ToIndexGrid. Autotiled tiles have 16 variants in a row
+// of the atlas, picked by a bitmask of which of their neighbours they join: 1 << Direction for each of north, east,
+// south and west, so a tile joined on the north and south is the variant at Autotile[tile]+5
+type TileToIndex struct {
+	Tiles    map[Tile]int    // index of each plain tile
+	Autotile map[Tile]int    // index of the first of the 16 variants of each autotiled tile
+	Joins    map[Tile][]Tile // tiles an autotiled tile joins other than itself, such as walls joining doors
+	Edges    bool            // autotiled tiles join the edges of the map
+	Default  int             // index of tiles which aren't mapped, such as -1 for an empty cell
+}
+
+// ToIndexGrid returns the atlas index of every tile, indexed [y][x], ready to hand to a tilemap renderer
+func (world *World) ToIndexGrid(mapping TileToIndex) [][]int {
+	grid := make([][]int, world.Height)
+	for y := range grid {
+		grid[y] = make([]int, world.Width)
+		for x := range grid[y] {
+			t := world.Tiles[y][x]
+			if base, ok := mapping.Autotile[t]; ok {
+				grid[y][x] = base + world.autotileMask(x, y, mapping)
+			} else if index, ok := mapping.Tiles[t]; ok {
+				grid[y][x] = index
+			} else {
+				grid[y][x] = mapping.Default
+			}
+		}
+	}
+	return grid
+}
+
+// autotileMask returns the bitmask of the neighbours the tile at x,y joins
+func (world *World) autotileMask(x, y int, mapping TileToIndex) int {
+	t := world.Tiles[y][x]
+	mask := 0
+	for d := DirectionNorth; d <= DirectionWest; d++ {
+		nx, ny := x+d.Dx(), y+d.Dy()
+		if nx < 0 || ny < 0 || nx >= world.Width || ny >= world.Height {
+			if mapping.Edges {
+				mask |= 1 << d
+			}
+			continue
+		}
+		if n := world.Tiles[ny][nx]; n == t || hasTile(mapping.Joins[t], n) {
+			mask |= 1 << d
+		}
+	}
+	return mask
+}
+
+// hasTile returns true if tiles contains t
+func hasTile(tiles []Tile, t Tile) bool {
+	for _, o := range tiles {
+		if o == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package generate
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrNoGenerator is returned when a WorldConfig has no Generate function
+var ErrNoGenerator = errors.New("Config has no Generate function")
+
+// WorldConfig describes how to build and generate a World so many can be made from it
+type WorldConfig struct {
+	Width, Height int
+	Seed          int64 // base seed for batches, world i of a batch is seeded with Seed+i
+
+	Border                    int
+	BorderStyle               BorderStyle
+	WallThickness             int
+	MinCorridorSize           int
+	MaxCorridorSize           int
+	AllowRandomCorridorOffset bool
+	NarrowDoorways            bool
+	MaxRoomWidth              int
+	MaxRoomHeight             int
+	MinRoomWidth              int
+	MinRoomHeight             int
+	MinIslandSize             int
+	MinRoomSeparation         int
+	FlushRooms                bool
+	RoomOverlap               float64
+	GridCellHeight            int
+	Zones                     []Zone
+	Directions                []Direction
+	DoorSides                 [4]float64
+	Stats                     StatsRecorder // shared by every world
+	Validate                  bool          // run World.Validate after Generate, failing the world if it's invalid
+
+	// Generate runs the generator and any passes after it, such as AddWalls
+	Generate func(world *World) error
+}
+
+// NewWorldConfig returns a WorldConfig with the same defaults as NewWorld. Generate must be set before use
+func NewWorldConfig(width, height int) WorldConfig {
+	return newWorld(width, height).config()
+}
+
+// config returns the world's current config
+func (world *World) config() WorldConfig {
+	return WorldConfig{
+		Width:  world.Width,
+		Height: world.Height,
+
+		Border:                    world.Border,
+		BorderStyle:               world.BorderStyle,
+		WallThickness:             world.WallThickness,
+		MinCorridorSize:           world.MinCorridorSize,
+		MaxCorridorSize:           world.MaxCorridorSize,
+		AllowRandomCorridorOffset: world.AllowRandomCorridorOffset,
+		NarrowDoorways:            world.NarrowDoorways,
+		MaxRoomWidth:              world.MaxRoomWidth,
+		MaxRoomHeight:             world.MaxRoomHeight,
+		MinRoomWidth:              world.MinRoomWidth,
+		MinRoomHeight:             world.MinRoomHeight,
+		MinIslandSize:             world.MinIslandSize,
+		MinRoomSeparation:         world.MinRoomSeparation,
+		FlushRooms:                world.FlushRooms,
+		RoomOverlap:               world.RoomOverlap,
+		GridCellHeight:            world.GridCellHeight,
+		Zones:                     append([]Zone(nil), world.Zones...),
+		Directions:                append([]Direction(nil), world.Directions...),
+		DoorSides:                 world.DoorSides,
+		Stats:                     world.Stats,
+	}
+}
+
+// build returns an empty world using the config
+func (cfg WorldConfig) build() *World {
+	world := newWorld(cfg.Width, cfg.Height)
+	cfg.apply(world)
+	return world
+}
+
+// apply sets the world's settings from the config, leaving its size and tiles alone
+func (cfg WorldConfig) apply(world *World) {
+	world.Border = cfg.Border
+	world.BorderStyle = cfg.BorderStyle
+	world.WallThickness = cfg.WallThickness
+	world.MinCorridorSize = cfg.MinCorridorSize
+	world.MaxCorridorSize = cfg.MaxCorridorSize
+	world.AllowRandomCorridorOffset = cfg.AllowRandomCorridorOffset
+	world.NarrowDoorways = cfg.NarrowDoorways
+	world.MaxRoomWidth = cfg.MaxRoomWidth
+	world.MaxRoomHeight = cfg.MaxRoomHeight
+	world.MinRoomWidth = cfg.MinRoomWidth
+	world.MinRoomHeight = cfg.MinRoomHeight
+	world.MinIslandSize = cfg.MinIslandSize
+	world.MinRoomSeparation = cfg.MinRoomSeparation
+	world.FlushRooms = cfg.FlushRooms
+	world.RoomOverlap = cfg.RoomOverlap
+	world.GridCellHeight = cfg.GridCellHeight
+	world.Zones = append([]Zone(nil), cfg.Zones...)
+	world.Directions = append([]Direction(nil), cfg.Directions...)
+	world.DoorSides = cfg.DoorSides
+	world.Stats = cfg.Stats
+}
+
+// GenerateBatch generates count worlds from cfg using a pool of workers (runtime.NumCPU() if workers <= 0). Each world
+// has its own random source seeded from cfg.Seed and its place in the batch, so the batch is the same every time no
+// matter how many workers there are.
+// Every world is returned in order along with the first error any of them returned
+func GenerateBatch(cfg WorldConfig, count int, workers int) ([]*World, error) {
+	if cfg.Generate == nil {
+		return nil, ErrNoGenerator
+	}
+	worlds, errs := generateBatch(cfg, count, workers)
+	for _, err := range errs {
+		if err != nil {
+			return worlds, err
+		}
+	}
+	return worlds, nil
+}
+
+// generateBatch generates count worlds from cfg and returns the error each one returned
+func generateBatch(cfg WorldConfig, count int, workers int) ([]*World, []error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = minInt(workers, count)
+
+	worlds := make([]*World, count)
+	errs := make([]error, count)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				world := cfg.build()
+				world.reseed(cfg.Seed + int64(j))
+				errs[j] = cfg.Generate(world)
+				if errs[j] == nil && cfg.Validate {
+					errs[j] = world.Validate()
+				}
+				worlds[j] = world
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return worlds, errs
+}
+
+// DefaultScore prefers worlds with long walks, a few loops and few dead ends
+func DefaultScore(world *World) float64 {
+	m := world.Metrics()
+	return float64(m.Diameter) + 10*float64(m.Loops) - 5*float64(m.DeadEnds)
+}
+
+// GenerateBest generates n worlds from cfg concurrently and returns the one with the highest score, using DefaultScore
+// if score is nil. Worlds which fail to generate aren't considered, nil is returned if they all fail
+func GenerateBest(cfg WorldConfig, n int, score func(*World) float64) *World {
+	if score == nil {
+		score = DefaultScore
+	}
+	if cfg.Generate == nil {
+		return nil
+	}
+	worlds, errs := generateBatch(cfg, n, 0)
+
+	var best *World
+	bestScore := 0.0
+	for i, world := range worlds {
+		if errs[i] != nil {
+			continue
+		}
+		s := score(world)
+		if best == nil || s > bestScore {
+			best, bestScore = world, s
+		}
+	}
+	return best
+}
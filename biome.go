@@ -0,0 +1,25 @@
+package generate
+
+// Biome is the environment of an area, stored per tile in world.Biomes
+type Biome int8
+
+// Biomes
+const (
+	BiomeNone Biome = iota
+	BiomeDungeon
+	BiomeCave
+	BiomeCrypt
+	BiomeGrassland
+	BiomeForest
+	BiomeBeach
+	BiomeOcean
+)
+
+// SetBiomeRect sets the biome of every tile inside of r
+func (world *World) SetBiomeRect(r Rect, biome Biome) {
+	for y := maxInt(r.Y, 0); y < minInt(r.Y+r.H, world.Height); y++ {
+		for x := maxInt(r.X, 0); x < minInt(r.X+r.W, world.Width); x++ {
+			world.Biomes[y][x] = biome
+		}
+	}
+}
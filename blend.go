@@ -0,0 +1,174 @@
+package generate
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrBlendMismatch is returned by BlendWorlds when the worlds are different sizes
+var ErrBlendMismatch = errors.New("Worlds to blend are different sizes")
+
+// blendScale is the size of the patches BlendWorlds takes from each world, in tiles
+const blendScale = 12
+
+// BlendWorlds is experimental. It returns a new world which mixes a and b, for remixed levels such as daily
+// challenges made from two seeds. t (0-1) is how much of b is used: each part of the map is taken from a or b by
+// noise, so 0 is a copy of a's layout and 1 is a copy of b's. Rooms are kept whole from whichever world their middle
+// is taken from, unless they'd overlap a room already kept, along with their tags and names. The seams are then
+// repaired: walls are rebuilt with AddWalls, floor cut off from the first room is joined back with CarveCorridor and
+// doors which no longer fit are moved with FixDoors. The new world uses a's settings and has no decoration
+func BlendWorlds(a, b *World, t float64) (*World, error) {
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, ErrBlendMismatch
+	}
+	world := a.config().build()
+	world.reseed(a.rng.Int63())
+
+	t = clampFloat(t, 0, 1)
+	noise := newNoise(world.rng)
+	from := func(x, y int) *World {
+		// Noise is never exactly 0 or 1, so t at either end always picks one world
+		n := noise.Fractal(float64(x)/blendScale, float64(y)/blendScale, 3)
+		if n < t {
+			return b
+		}
+		return a
+	}
+
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			src := from(x, y)
+			if tile := src.Tiles[y][x]; tile != TileWall && tile != TilePreWall {
+				world.Tiles[y][x] = tile
+				world.FloorKinds[y][x] = src.FloorKinds[y][x]
+			}
+			world.Biomes[y][x] = src.Biomes[y][x]
+		}
+	}
+
+	// Rooms are stamped whole so none are cut in half by the seam, rooms from the other world under them are dropped
+	sep := world.roomSeparation(wallGap(world.WallThickness))
+	kept := make([]Rect, 0)
+	for _, src := range [2]*World{a, b} {
+		for _, room := range src.RoomsOrdered() {
+			if from(room.X+room.W/2, room.Y+room.H/2) != src {
+				continue
+			}
+			clear := true
+			for _, k := range kept {
+				if k.Expand(sep).overlaps(room) {
+					clear = false
+					break
+				}
+			}
+			if !clear {
+				continue
+			}
+			kept = append(kept, room)
+			world.addRoom(room)
+			for y := room.Y; y < room.Y+room.H; y++ {
+				for x := room.X; x < room.X+room.W; x++ {
+					world.Tiles[y][x] = src.Tiles[y][x]
+					world.FloorKinds[y][x] = src.FloorKinds[y][x]
+					world.Biomes[y][x] = src.Biomes[y][x]
+				}
+			}
+			if tags, ok := src.RoomTags[room]; ok {
+				world.RoomTags[room] = append([]Tag(nil), tags...)
+			}
+			if name, ok := src.RoomNames[room]; ok {
+				world.RoomNames[room] = name
+			}
+		}
+	}
+
+	// Corridors and doors come from the world their whole length was taken from
+	for _, src := range [2]*World{a, b} {
+		for _, c := range src.Corridors {
+			whole := true
+			for _, p := range c.Path {
+				if from(p.X, p.Y) != src {
+					whole = false
+					break
+				}
+			}
+			if !whole {
+				continue
+			}
+			c.Path = append([]Point(nil), c.Path...)
+			c.Rooms = append([]Rect(nil), c.Rooms...)
+			world.Corridors = append(world.Corridors, c)
+		}
+		for door, dir := range src.Doors {
+			if from(door.X, door.Y) != src || !world.walkable(door.X, door.Y) {
+				continue
+			}
+			world.Doors[door] = dir
+			if tags, ok := src.DoorTags[door]; ok {
+				world.DoorTags[door] = append([]Tag(nil), tags...)
+			}
+		}
+	}
+
+	world.AddWalls()
+	world.joinFloor()
+	world.FixDoors()
+	return world, nil
+}
+
+// joinFloor carves corridors from floor which can't be reached from the first room, or the first floor tile, to the
+// nearest floor which can
+func (world *World) joinFloor() {
+	var start Point
+	found := false
+	if rooms := world.RoomsOrdered(); len(rooms) > 0 {
+		start = Point{X: rooms[0].X + rooms[0].W/2, Y: rooms[0].Y + rooms[0].H/2}
+		found = world.walkable(start.X, start.Y)
+	}
+	for y := 0; y < world.Height && !found; y++ {
+		for x := 0; x < world.Width && !found; x++ {
+			if world.walkable(x, y) {
+				start, found = Point{X: x, Y: y}, true
+			}
+		}
+	}
+	if !found {
+		return
+	}
+
+	failed := make(map[Point]bool)
+	for {
+		dist := world.DistanceField(start)
+		var cut Point
+		stranded := false
+		for y := 0; y < world.Height && !stranded; y++ {
+			for x := 0; x < world.Width && !stranded; x++ {
+				if dist[y][x] < 0 && world.Tiles[y][x] == TileFloor && !failed[Point{X: x, Y: y}] {
+					cut, stranded = Point{X: x, Y: y}, true
+				}
+			}
+		}
+		if !stranded {
+			return
+		}
+
+		best, bestDist := Point{}, math.MaxInt32
+		for y := range dist {
+			for x, d := range dist[y] {
+				if d >= 0 && absInt(x-cut.X)+absInt(y-cut.Y) < bestDist {
+					best, bestDist = Point{X: x, Y: y}, absInt(x-cut.X)+absInt(y-cut.Y)
+				}
+			}
+		}
+		if _, err := world.CarveCorridor(cut, best, 1, CorridorStyleShort); err != nil {
+			// Leave the rest of the piece it's in alone too
+			for y, row := range world.bfs(cut, world.walkable) {
+				for x, d := range row {
+					if d >= 0 {
+						failed[Point{X: x, Y: y}] = true
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package generate
+
+// BorderStyle controls how AddWalls treats the edge of the map
+type BorderStyle int8
+
+// Border styles
+const (
+	BorderStyleNone   BorderStyle = iota // the edge is left however the generator left it
+	BorderStyleSolid                     // a ring of TileWall world.WallThickness thick seals the map edge
+	BorderStyleRagged                    // rock eats into the empty space around the edge with a noisy, natural outline
+	BorderStyleOpen                      // the outermost ring is cleared of walls so floors reaching it can be stitched
+)
+
+// applyBorderStyle finishes the edge of the map according to world.BorderStyle
+func (world *World) applyBorderStyle() {
+	w, h := world.Width, world.Height
+	edgeDistance := func(x, y int) int {
+		return minInt(minInt(x, y), minInt(w-1-x, h-1-y))
+	}
+
+	switch world.BorderStyle {
+	case BorderStyleSolid:
+		t := maxInt(world.WallThickness, 1)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if edgeDistance(x, y) < t {
+					world.SetTile(x, y, TileWall)
+				}
+			}
+		}
+	case BorderStyleRagged:
+		depth := maxInt(maxInt(world.Border, world.WallThickness), 2)
+		n := newNoise(world.rng)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if world.Tiles[y][x] != TileVoid {
+					continue
+				}
+				// Fractal noise stays close to 0.5, so stretch it to get deep bites and bare patches
+				d := float64(edgeDistance(x, y))
+				if d < 1+float64(depth)*(4*n.Fractal(float64(x)/5, float64(y)/5, 3)-1) {
+					world.SetTile(x, y, TileWall)
+				}
+			}
+		}
+	case BorderStyleOpen:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if edgeDistance(x, y) == 0 && world.Tiles[y][x] == TileWall {
+					world.SetTile(x, y, TileVoid)
+				}
+			}
+		}
+	}
+}
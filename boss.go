@@ -0,0 +1,146 @@
+package generate
+
+import "sort"
+
+// DefaultBossArena returns a pillared hall for PlaceBossArena
+func DefaultBossArena() Prefab {
+	p, _ := ParsePrefab(
+		"................",
+		"................",
+		"..OO........OO..",
+		"..OO........OO..",
+		"................",
+		"................",
+		"..OO........OO..",
+		"..OO........OO..",
+		"................",
+		"................",
+	)
+	return p
+}
+
+// PlaceBossArena stamps the prefab as a new room as far as it can be walked from the start room, attached by a single
+// corridor corridorWidth wide to the furthest room with space next to it. The arena is tagged with TagBoss and
+// TagArena, TagBoss is removed from every other room, and the door is tagged with TagBoss.
+// Call after AddWalls, and call AddWalls again afterwards
+func (world *World) PlaceBossArena(p Prefab, start Rect, corridorWidth int) (Rect, error) {
+	if len(world.Rooms) == 0 {
+		return Rect{}, ErrNoRooms
+	}
+	corridorWidth = minInt(maxInt(corridorWidth, 1), minInt(p.Width, p.Height))
+
+	// Rooms from furthest to closest to the start
+	dist := world.DistanceField(Point{X: start.X + start.W/2, Y: start.Y + start.H/2})
+	rooms := world.sortedRooms()
+	sort.SliceStable(rooms, func(i, j int) bool {
+		a, b := rooms[i], rooms[j]
+		return dist[a.Y+a.H/2][a.X+a.W/2] > dist[b.Y+b.H/2][b.X+b.W/2]
+	})
+
+	wt := world.WallThickness
+	sep := world.roomSeparation(wt)
+	for _, room := range rooms {
+		if dist[room.Y+room.H/2][room.X+room.W/2] < 0 {
+			continue
+		}
+		for _, side := range world.rng.Perm(4) {
+			var arena, corridor Rect
+			var dir DoorDirection
+			// entrance returns true if the prefab's tiles where the corridor meets it can be walked on
+			var entrance func() bool
+			switch Direction(side) {
+			case DirectionNorth, DirectionSouth:
+				arena.X = centered(room.X, room.W, p.Width)
+				lo, hi := maxInt(room.X, arena.X), minInt(room.X+room.W, arena.X+p.Width)
+				if hi-lo < corridorWidth {
+					continue
+				}
+				corridor = Rect{X: centered(lo, hi-lo, corridorWidth), W: corridorWidth, H: sep}
+				row := 0
+				if Direction(side) == DirectionNorth {
+					arena.Y = room.Y - sep - p.Height
+					corridor.Y = room.Y - sep
+					row = p.Height - 1
+				} else {
+					arena.Y = room.Y + room.H + sep
+					corridor.Y = room.Y + room.H
+				}
+				dir = DoorDirectionHorizontal
+				entrance = func() bool {
+					for x := corridor.X; x < corridor.X+corridor.W; x++ {
+						if !p.Tiles[row][x-arena.X].Walkable() {
+							return false
+						}
+					}
+					return true
+				}
+			case DirectionWest, DirectionEast:
+				arena.Y = centered(room.Y, room.H, p.Height)
+				lo, hi := maxInt(room.Y, arena.Y), minInt(room.Y+room.H, arena.Y+p.Height)
+				if hi-lo < corridorWidth {
+					continue
+				}
+				corridor = Rect{Y: centered(lo, hi-lo, corridorWidth), W: sep, H: corridorWidth}
+				col := 0
+				if Direction(side) == DirectionWest {
+					arena.X = room.X - sep - p.Width
+					corridor.X = room.X - sep
+					col = p.Width - 1
+				} else {
+					arena.X = room.X + room.W + sep
+					corridor.X = room.X + room.W
+				}
+				dir = DoorDirectionVertical
+				entrance = func() bool {
+					for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+						if !p.Tiles[y-arena.Y][col].Walkable() {
+							return false
+						}
+					}
+					return true
+				}
+			}
+			arena.W, arena.H = p.Width, p.Height
+
+			if !entrance() || world.checkRoom(arena.X, arena.Y, arena.W, arena.H, wt) != nil {
+				continue
+			}
+			// The corridor has to cross the source room's walls without running into anything else
+			if !world.corridorClear(corridor, room) {
+				continue
+			}
+
+			if err := world.stampPrefab(p, arena.X, arena.Y, wt); err != nil {
+				continue
+			}
+			for x := corridor.X; x < corridor.X+corridor.W; x++ {
+				for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+					world.setFloor(x, y, FloorKindCorridor)
+				}
+			}
+			door := world.addDoorway(corridor, dir)
+
+			for other := range world.Rooms {
+				world.UntagRoom(other, TagBoss)
+			}
+			world.TagRoom(arena, TagBoss, TagArena)
+			world.TagDoor(door, TagBoss)
+			return arena, nil
+		}
+	}
+	return Rect{}, ErrNotEnoughSpace
+}
+
+// corridorClear returns true if every tile of corridor is inside the world and isn't floor, ignoring tiles inside of
+// from
+func (world *World) corridorClear(corridor, from Rect) bool {
+	for x := corridor.X; x < corridor.X+corridor.W; x++ {
+		for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+			tile, err := world.GetTile(x, y)
+			if err != nil || (tile == TileFloor && !from.contains(x, y)) {
+				return false
+			}
+		}
+	}
+	return true
+}
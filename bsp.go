@@ -0,0 +1,128 @@
+package generate
+
+import (
+	"sort"
+	"time"
+)
+
+// bspJoinAttempts is how many of the nearest pairs of rooms either side of a split GenerateBSP tries to join
+const bspJoinAttempts = 4
+
+// GenerateBSP generates the world by splitting the map in two across its longer side, then splitting each half, depth
+// times, and placing a room of a random size somewhere in each of the up to 2^depth parts. Parts too small to split
+// are left whole. Each split is then joined by a corridor between the nearest rooms either side of it, from the
+// smallest splits up, so every room is connected and the rooms are packed evenly over the map. If the rooms on either
+// side of a split can't be joined the layout is started again, until world.DurationBeforeError is exceeded and
+// ErrGenerationTimeout is returned.
+// world.WallThickness, world.MinRoomWidth|Height, world.MaxRoomWidth|Height, world.MinRoomSeparation and
+// world.FlushRooms are used
+func (world *World) GenerateBSP(depth int, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("BSP", 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+
+	wt := wallGap(world.WallThickness)
+	sep := world.roomSeparation(wt)
+	m := world.edgeMargin(wt)
+	// Each part keeps sep free along its right and bottom for the walls before the next part, the last parts share
+	// theirs with the margin
+	bounds := Rect{X: m, Y: m, W: world.Width - m*2 + sep, H: world.Height - m*2 + sep}
+	if depth < 0 || world.MinRoomWidth < 1 || world.MinRoomHeight < 1 ||
+		bounds.W < world.MinRoomWidth+sep || bounds.H < world.MinRoomHeight+sep {
+		return ErrNotEnoughSpace
+	}
+
+	for {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		}
+		world.ResetWorld(world.Width, world.Height)
+
+		// Every room is placed before any corridors so corridors can't get in the way of rooms
+		splits := make([][2][]Rect, 0)
+		if _, ok := world.splitBSP(bounds, depth, sep, &splits); ok {
+			joined := true
+			for _, s := range splits {
+				if !world.joinBSP(s[0], s[1]) {
+					joined = false
+					break
+				}
+			}
+			if joined {
+				return nil
+			}
+		}
+		world.Report.Retries++
+	}
+}
+
+// splitBSP splits part depth more times and places the rooms of the parts, returning them. The rooms either side of
+// each split are added to splits, smallest splits first. false is returned if a room couldn't be placed
+func (world *World) splitBSP(part Rect, depth, sep int, splits *[][2][]Rect) ([]Rect, bool) {
+	p := world.paramsAt(part.X+part.W/2, part.Y+part.H/2)
+	if depth > 0 {
+		across := part.W >= part.H
+		length, least := part.H, p.MinRoomHeight+sep
+		if across {
+			length, least = part.W, p.MinRoomWidth+sep
+		}
+		if length >= least*2 {
+			// Split somewhere in the middle fifth, as long as both sides fit a room
+			at := world.randInt(maxInt(least, length*2/5), minInt(length-least, length*3/5))
+			a, b := part, part
+			if across {
+				a.W, b.X, b.W = at, part.X+at, part.W-at
+			} else {
+				a.H, b.Y, b.H = at, part.Y+at, part.H-at
+			}
+			roomsA, ok := world.splitBSP(a, depth-1, sep, splits)
+			if !ok {
+				return nil, false
+			}
+			roomsB, ok := world.splitBSP(b, depth-1, sep, splits)
+			if !ok {
+				return nil, false
+			}
+			*splits = append(*splits, [2][]Rect{roomsA, roomsB})
+			return append(roomsA, roomsB...), true
+		}
+	}
+
+	room := Rect{
+		W: world.randInt(p.MinRoomWidth, minInt(p.MaxRoomWidth, part.W-sep)),
+		H: world.randInt(p.MinRoomHeight, minInt(p.MaxRoomHeight, part.H-sep)),
+	}
+	room.X = world.randInt(part.X, part.X+part.W-sep-room.W)
+	room.Y = world.randInt(part.Y, part.Y+part.H-sep-room.H)
+	if err := world.placeRoom(room.X, room.Y, room.W, room.H, p.WallThickness); err != nil {
+		world.Report.Rollbacks++
+		return nil, false
+	}
+	return []Rect{room}, true
+}
+
+// joinBSP carves a corridor between the nearest pair of rooms from as and bs which can be joined, returning false if
+// none of the nearest few can
+func (world *World) joinBSP(as, bs []Rect) bool {
+	type pair struct {
+		a, b Rect
+		dist int
+	}
+	pairs := make([]pair, 0, len(as)*len(bs))
+	for _, a := range as {
+		for _, b := range bs {
+			dist := absInt(a.X+a.W/2-b.X-b.W/2) + absInt(a.Y+a.H/2-b.Y-b.H/2)
+			pairs = append(pairs, pair{a: a, b: b, dist: dist})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+	for i := 0; i < minInt(len(pairs), bspJoinAttempts); i++ {
+		if path := world.linkPath(pairs[i].a, pairs[i].b); path != nil {
+			world.carveLink(path, pairs[i].b)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package generate
+
+import "math/rand"
+
+// BudgetOptions controls how AllocateBudgets shares out decoration points
+type BudgetOptions struct {
+	Room           int             // points for every room
+	Corridor       int             // points for every corridor
+	PerTile        float64         // points per floor tile, so content density stays the same on any size of map
+	DistanceWeight float64         // how much budgets grow towards the area furthest from the start
+	TagWeights     map[Tag]float64 // multiplier for rooms with a tag
+	TagPoints      map[Tag]int     // points added to rooms with a tag, after TagWeights
+}
+
+// DefaultBudgetOptions returns options where the start and safe rooms are left bare and the boss and treasure rooms
+// get the most
+func DefaultBudgetOptions() BudgetOptions {
+	return BudgetOptions{
+		Room:           10,
+		Corridor:       5,
+		PerTile:        0.2,
+		DistanceWeight: 1,
+		TagWeights: map[Tag]float64{
+			TagStart: 0,
+			TagSafe:  0,
+		},
+		TagPoints: map[Tag]int{
+			TagBoss:     100,
+			TagTreasure: 30,
+		},
+	}
+}
+
+// BudgetItem is something a budget can be spent on
+type BudgetItem struct {
+	Tile Tile
+	Cost int
+}
+
+// budget is the points of a room or corridor and the floor tiles they can be spent on
+type budget struct {
+	total, left int
+	tiles       []Point
+}
+
+// AllocateBudgets gives every room and corridor a budget of points which dressing passes spend with SpendBudget.
+// Corridors are identified by the bounds of their path. Budgets grow with the area's size, its walking distance from
+// start and its tags. Redecorate refunds everything that was spent, so call this again after changing the structure
+func (world *World) AllocateBudgets(start Point, opts BudgetOptions) {
+	dist := world.DistanceField(start)
+	maxDist := 1
+	for y := range dist {
+		for x := range dist[y] {
+			maxDist = maxInt(maxDist, dist[y][x])
+		}
+	}
+	scale := func(p Point) float64 {
+		if d := dist[p.Y][p.X]; d > 0 {
+			return 1 + opts.DistanceWeight*float64(d)/float64(maxDist)
+		}
+		return 1
+	}
+
+	world.budgets = make(map[Rect]*budget)
+	for room := range world.Rooms {
+		tiles := world.roomFloor(room)
+		points := (float64(opts.Room) + opts.PerTile*float64(len(tiles))) *
+			scale(Point{X: room.X + room.W/2, Y: room.Y + room.H/2})
+		for _, tag := range world.RoomTags[room] {
+			if w, ok := opts.TagWeights[tag]; ok {
+				points *= w
+			}
+		}
+		for _, tag := range world.RoomTags[room] {
+			points += float64(opts.TagPoints[tag])
+		}
+		world.budgets[room] = &budget{total: maxInt(int(points), 0), tiles: tiles}
+	}
+	for _, c := range world.Corridors {
+		if len(c.Path) == 0 {
+			continue
+		}
+		tiles := make([]Point, 0, len(c.Path))
+		for _, p := range c.Path {
+			if _, ok := world.RoomAt(p.X, p.Y); !ok && world.Tiles[p.Y][p.X] == TileFloor {
+				tiles = append(tiles, p)
+			}
+		}
+		points := (float64(opts.Corridor) + opts.PerTile*float64(len(tiles))) * scale(c.Path[len(c.Path)/2])
+		world.budgets[pathBounds(c.Path)] = &budget{total: maxInt(int(points), 0), tiles: tiles}
+	}
+	world.refundBudgets()
+}
+
+// refundBudgets resets every budget to its full amount
+func (world *World) refundBudgets() {
+	for _, b := range world.budgets {
+		b.left = b.total
+	}
+}
+
+// BudgetAreas returns every room and corridor with a budget, sorted by position
+func (world *World) BudgetAreas() []Rect {
+	areas := make([]Rect, 0, len(world.budgets))
+	for area := range world.budgets {
+		areas = append(areas, area)
+	}
+	sortRects(areas)
+	return areas
+}
+
+// Budget returns the points an area has left
+func (world *World) Budget(area Rect) int {
+	if b, ok := world.budgets[area]; ok {
+		return b.left
+	}
+	return 0
+}
+
+// SpendBudget takes cost points from an area's budget, returning false without spending anything if it can't afford it
+func (world *World) SpendBudget(area Rect, cost int) bool {
+	b, ok := world.budgets[area]
+	if !ok || b.left < cost {
+		return false
+	}
+	b.left -= cost
+	return true
+}
+
+// BudgetDressing returns a pass which spends each area's budget on random items, placing them on free floor tiles of
+// the named layer until the area can't afford anything else or runs out of room. Items are picked evenly, so cheap
+// items fill up what's left once the expensive ones are out of reach
+func BudgetDressing(name string, items ...BudgetItem) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		layer := world.Layer(name)
+		for _, area := range world.BudgetAreas() {
+			free := make([]Point, 0)
+			for _, p := range world.budgets[area].tiles {
+				if layer[p.Y][p.X] == TileVoid {
+					free = append(free, p)
+				}
+			}
+			for len(free) > 0 {
+				affordable := make([]BudgetItem, 0, len(items))
+				for _, item := range items {
+					if item.Cost <= world.Budget(area) && item.Cost > 0 {
+						affordable = append(affordable, item)
+					}
+				}
+				if len(affordable) == 0 {
+					break
+				}
+				item := affordable[rng.Intn(len(affordable))]
+				i := rng.Intn(len(free))
+				world.SpendBudget(area, item.Cost)
+				layer[free[i].Y][free[i].X] = item.Tile
+				free = append(free[:i], free[i+1:]...)
+			}
+		}
+	}
+}
+
+// pathBounds returns the smallest rect containing every point of path
+func pathBounds(path []Point) Rect {
+	minX, minY, maxX, maxY := path[0].X, path[0].Y, path[0].X, path[0].Y
+	for _, p := range path[1:] {
+		minX, maxX = minInt(minX, p.X), maxInt(maxX, p.X)
+		minY, maxY = minInt(minY, p.Y), maxInt(maxY, p.Y)
+	}
+	return Rect{X: minX, Y: minY, W: maxX - minX + 1, H: maxY - minY + 1}
+}
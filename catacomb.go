@@ -0,0 +1,150 @@
+package generate
+
+import "time"
+
+// CatacombOptions controls GenerateCatacombs
+type CatacombOptions struct {
+	ModuleSize    int     // length of corridor per burial cell, each cell is ModuleSize-1 wide with a wall between cells
+	NicheDepth    int     // how far the niches go into the walls
+	AlcoveDensity float64 // chance (0-1) of each cell along a corridor side having a niche
+	SegmentLength int     // max length of a straight corridor, in lattice steps
+}
+
+// DefaultCatacombOptions returns options for densely packed 2 wide niches
+func DefaultCatacombOptions() CatacombOptions {
+	return CatacombOptions{
+		ModuleSize:    3,
+		NicheDepth:    2,
+		AlcoveDensity: 0.8,
+		SegmentLength: 3,
+	}
+}
+
+// GenerateCatacombs generates the world as a network of straight corridors lined with small burial niches. The
+// corridors follow a lattice, so they cross and loop, and segments is the number of straight corridors carved.
+// Niches are tagged with FloorKindAlcove and the corridors with FloorKindCorridor.
+// Crossings wide enough to be rooms are recorded in world.Rooms, see InferRooms.
+// world.WallThickness and world.MaxCorridorSize are used
+func (world *World) GenerateCatacombs(segments int, opts CatacombOptions, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("Catacombs", 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	if opts.ModuleSize < 2 || opts.NicheDepth < 1 || opts.SegmentLength < 1 {
+		return ErrNotEnoughSpace
+	}
+
+	cw := maxInt(world.MaxCorridorSize, 1)
+	wt := wallGap(world.WallThickness)
+	spacing := cw + 2*(opts.NicheDepth+wt)
+	margin := world.Border + opts.NicheDepth + wt
+	nw := (world.Width - margin*2 - cw) / spacing
+	nh := (world.Height - margin*2 - cw) / spacing
+	if nw < 1 || nh < 1 {
+		return ErrNotEnoughSpace
+	}
+
+	node := func(nx, ny int) (int, int) {
+		return margin + nx*spacing, margin + ny*spacing
+	}
+
+	type segment struct {
+		x, y, w, h int
+		horizontal bool
+	}
+	carved := make([]segment, 0, segments)
+
+	nx, ny := nw/2, nh/2
+	for s := 0; s < segments; s++ {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		}
+
+		step := world.randomDirection()
+		dx, dy := step.Dx(), step.Dy()
+		steps := world.randInt(1, opts.SegmentLength)
+		tx := minInt(maxInt(nx+dx*steps, 0), nw)
+		ty := minInt(maxInt(ny+dy*steps, 0), nh)
+		if tx == nx && ty == ny {
+			s--
+			continue
+		}
+
+		x1, y1 := node(nx, ny)
+		x2, y2 := node(tx, ty)
+		seg := segment{
+			x:          minInt(x1, x2),
+			y:          minInt(y1, y2),
+			w:          absInt(x2-x1) + cw,
+			h:          absInt(y2-y1) + cw,
+			horizontal: dy == 0,
+		}
+		for x := seg.x; x < seg.x+seg.w; x++ {
+			for y := seg.y; y < seg.y+seg.h; y++ {
+				world.setFloor(x, y, FloorKindCorridor)
+			}
+		}
+		carved = append(carved, seg)
+		dir := DoorDirectionHorizontal
+		if seg.horizontal {
+			dir = DoorDirectionVertical
+		}
+		world.addStraightCorridor(Rect{X: seg.x, Y: seg.y, W: seg.w, H: seg.h}, dir, Rect{})
+		nx, ny = tx, ty
+	}
+
+	// Line the corridors with niches, skipping the junctions at either end
+	canCarve := func(r Rect, corridor Rect) bool {
+		for x := r.X - wt; x < r.X+r.W+wt; x++ {
+			for y := r.Y - wt; y < r.Y+r.H+wt; y++ {
+				if corridor.contains(x, y) {
+					continue
+				}
+				if tile, err := world.GetTile(x, y); err != nil || tile == TileFloor {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	nicheWidth := opts.ModuleSize - 1
+	for _, seg := range carved {
+		corridor := Rect{X: seg.x, Y: seg.y, W: seg.w, H: seg.h}
+		length := seg.w
+		if !seg.horizontal {
+			length = seg.h
+		}
+		for offset := cw + wt; offset+nicheWidth <= length-cw-wt; offset += opts.ModuleSize {
+			for _, side := range [2]int{-1, 1} {
+				if world.rng.Float64() >= opts.AlcoveDensity {
+					continue
+				}
+				var niche Rect
+				switch {
+				case seg.horizontal && side == -1:
+					niche = Rect{X: seg.x + offset, Y: seg.y - opts.NicheDepth, W: nicheWidth, H: opts.NicheDepth}
+				case seg.horizontal:
+					niche = Rect{X: seg.x + offset, Y: seg.y + seg.h, W: nicheWidth, H: opts.NicheDepth}
+				case side == -1:
+					niche = Rect{X: seg.x - opts.NicheDepth, Y: seg.y + offset, W: opts.NicheDepth, H: nicheWidth}
+				default:
+					niche = Rect{X: seg.x + seg.w, Y: seg.y + offset, W: opts.NicheDepth, H: nicheWidth}
+				}
+				if !canCarve(niche, corridor) {
+					continue
+				}
+				for x := niche.X; x < niche.X+niche.W; x++ {
+					for y := niche.Y; y < niche.Y+niche.H; y++ {
+						world.setFloor(x, y, FloorKindAlcove)
+					}
+				}
+			}
+		}
+	}
+
+	world.InferRooms()
+	return nil
+}
@@ -0,0 +1,121 @@
+package generate
+
+// roomEntrances returns the tiles on the edge of room which touch a walkable tile outside of the room
+func (world *World) roomEntrances(room Rect) []Point {
+	entrances := make([]Point, 0)
+	for x := room.X; x < room.X+room.W; x++ {
+		for y := room.Y; y < room.Y+room.H; y++ {
+			if x != room.X && x != room.X+room.W-1 && y != room.Y && y != room.Y+room.H-1 {
+				continue
+			}
+			for _, o := range polarOffsets {
+				ox, oy := x+o.X, y+o.Y
+				if !room.contains(ox, oy) && world.walkable(ox, oy) {
+					entrances = append(entrances, Point{X: x, Y: y})
+					break
+				}
+			}
+		}
+	}
+	return entrances
+}
+
+// contains returns true if x,y is inside of the rect
+func (r Rect) contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H
+}
+
+// beside returns true if x,y is outside the rect and next to one of its sides, not counting the corners
+func (r Rect) beside(x, y int) bool {
+	inX, inY := x >= r.X && x < r.X+r.W, y >= r.Y && y < r.Y+r.H
+	return inX && (y == r.Y-1 || y == r.Y+r.H) || inY && (x == r.X-1 || x == r.X+r.W)
+}
+
+// closedAround returns true for the tiles around the rect which a path into it mustn't use: the corners and every
+// other tile along each side, counting from the middle. A path can then only turn into the rect straight on, and not
+// run along its side or cut in past a corner
+func (r Rect) closedAround(x, y int) bool {
+	if r.contains(x, y) || !r.Expand(1).contains(x, y) {
+		return false
+	}
+	switch {
+	case !r.beside(x, y):
+		return true
+	case y == r.Y-1 || y == r.Y+r.H:
+		return (x-(r.X+r.W/2))%2 != 0
+	}
+	return (y-(r.Y+r.H/2))%2 != 0
+}
+
+// AddChasms carves a channel of fill (usually TileWater or TileChasm) through every room with an area of at least
+// minRoomArea, then places TileBridge tiles along the paths between the room's entrances so every part of the room
+// can still be reached. The number of rooms which were given a channel is returned.
+// Call after AddWalls so that the entrances can be found
+func (world *World) AddChasms(fill Tile, minRoomArea int) int {
+	var count int
+	for _, room := range world.RoomsOrdered() {
+		if room.W*room.H < minRoomArea {
+			continue
+		}
+
+		// Channels run along the longest side so both halves of the room stay a decent size
+		vertical := room.W >= room.H
+		length := room.W
+		if !vertical {
+			length = room.H
+		}
+		cw := world.randInt(1, 2)
+		if length < cw+4 {
+			cw = 1
+			if length < cw+4 {
+				continue
+			}
+		}
+
+		var channel Rect
+		if vertical {
+			channel = Rect{X: world.randInt(room.X+2, room.X+room.W-2-cw), Y: room.Y, W: cw, H: room.H}
+		} else {
+			channel = Rect{X: room.X, Y: world.randInt(room.Y+2, room.Y+room.H-2-cw), W: room.W, H: cw}
+		}
+
+		// Find the critical paths through the room before carving, these become bridges
+		bridges := make(map[Point]struct{})
+		inRoom := func(x, y int) bool {
+			return room.contains(x, y) && world.walkable(x, y)
+		}
+		entrances := world.roomEntrances(room)
+		if len(entrances) > 1 {
+			dist := world.bfs(entrances[0], inRoom)
+			for _, e := range entrances[1:] {
+				for _, p := range pathFromDistances(dist, e, nil) {
+					if channel.contains(p.X, p.Y) {
+						bridges[p] = struct{}{}
+					}
+				}
+			}
+		}
+		if len(bridges) == 0 {
+			// Keep the far side of the room reachable
+			for x := channel.X; x < channel.X+channel.W; x++ {
+				for y := channel.Y; y < channel.Y+channel.H; y++ {
+					if (vertical && y == room.Y+room.H/2) || (!vertical && x == room.X+room.W/2) {
+						bridges[Point{X: x, Y: y}] = struct{}{}
+					}
+				}
+			}
+		}
+
+		for x := channel.X; x < channel.X+channel.W; x++ {
+			for y := channel.Y; y < channel.Y+channel.H; y++ {
+				if _, ok := bridges[Point{X: x, Y: y}]; ok {
+					world.SetTile(x, y, TileBridge)
+				} else {
+					world.SetTile(x, y, fill)
+				}
+			}
+		}
+		count++
+	}
+	return count
+}
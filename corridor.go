@@ -0,0 +1,273 @@
+package generate
+
+// Corridor is a passage carved between rooms or points
+type Corridor struct {
+	Path     []Point // tiles along the middle of the corridor, from one end to the other
+	Width    int
+	From, To Point  // the ends of Path
+	Rooms    []Rect // rooms the corridor opens into
+	Door     Rect   // the door in the corridor, zero if it has none
+}
+
+// addCorridor records a corridor following path and returns it
+func (world *World) addCorridor(path []Point, width int, door Rect) Corridor {
+	c := Corridor{
+		Path:  path,
+		Width: width,
+		From:  path[0],
+		To:    path[len(path)-1],
+		Rooms: world.touchingRooms(path),
+		Door:  door,
+	}
+	world.Corridors = append(world.Corridors, c)
+	return c
+}
+
+// addStraightCorridor records the corridor filling area, which runs left to right for DoorDirectionVertical and top to
+// bottom for DoorDirectionHorizontal
+func (world *World) addStraightCorridor(area Rect, dir DoorDirection, door Rect) Corridor {
+	path := make([]Point, 0)
+	width := area.W
+	switch dir {
+	case DoorDirectionVertical:
+		y := area.Y + (area.H-1)/2
+		for x := area.X; x < area.X+area.W; x++ {
+			path = append(path, Point{X: x, Y: y})
+		}
+		width = area.H
+	case DoorDirectionHorizontal:
+		x := area.X + (area.W-1)/2
+		for y := area.Y; y < area.Y+area.H; y++ {
+			path = append(path, Point{X: x, Y: y})
+		}
+	}
+	return world.addCorridor(path, width, door)
+}
+
+// addDoorway adds the door for the straight corridor filling area, which runs left to right for DoorDirectionVertical
+// and top to bottom for DoorDirectionHorizontal, records the corridor and returns the door. The door spans the full
+// width of the corridor half way along it. If world.NarrowDoorways is set and the corridor is wider than a tile, both
+// ends of the corridor are narrowed to a single tile and the door is the doorway at its first end instead. If
+// world.DoorSides weighs the sides of the rooms the corridor meets differently, the door goes at the end on the side
+// with more weight, so doors face the way the game wants them to
+func (world *World) addDoorway(area Rect, dir DoorDirection) Rect {
+	door := area
+	switch dir {
+	case DoorDirectionVertical:
+		mid := area.Y + (area.H-1)/2
+		door.W = 1
+		door.X += (area.W/2 + area.W%2) - 1
+		if world.NarrowDoorways && area.H > 1 {
+			for _, x := range [2]int{area.X, area.X + area.W - 1} {
+				for y := area.Y; y < area.Y+area.H; y++ {
+					if y != mid {
+						world.SetTile(x, y, TileVoid)
+					}
+				}
+			}
+			door = Rect{X: area.X, Y: mid, W: 1, H: 1}
+		}
+		if x, ok := world.doorSideEnd(area.X, area.X+area.W-1, DirectionEast, DirectionWest); ok {
+			door.X = x
+		}
+	case DoorDirectionHorizontal:
+		mid := area.X + (area.W-1)/2
+		door.H = 1
+		door.Y += (area.H/2 + area.H%2) - 1
+		if world.NarrowDoorways && area.W > 1 {
+			for _, y := range [2]int{area.Y, area.Y + area.H - 1} {
+				for x := area.X; x < area.X+area.W; x++ {
+					if x != mid {
+						world.SetTile(x, y, TileVoid)
+					}
+				}
+			}
+			door = Rect{X: mid, Y: area.Y, W: 1, H: 1}
+		}
+		if y, ok := world.doorSideEnd(area.Y, area.Y+area.H-1, DirectionSouth, DirectionNorth); ok {
+			door.Y = y
+		}
+	}
+	world.Doors[door] = dir
+	world.addStraightCorridor(area, dir, door)
+	return door
+}
+
+// touchingRooms returns the rooms which any point in path is in or next to, in the order they're found
+func (world *World) touchingRooms(path []Point) []Rect {
+	rooms := make([]Rect, 0, 2)
+	seen := make(map[Rect]struct{})
+	for _, p := range path {
+		for _, o := range [5]Point{{0, 0}, {-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			if room, ok := world.RoomAt(p.X+o.X, p.Y+o.Y); ok {
+				if _, ok := seen[room]; !ok {
+					seen[room] = struct{}{}
+					rooms = append(rooms, room)
+				}
+			}
+		}
+	}
+	return rooms
+}
+
+// CorridorsOf returns the corridors opening into room
+func (world *World) CorridorsOf(room Rect) []Corridor {
+	corridors := make([]Corridor, 0)
+	for _, c := range world.Corridors {
+		for _, r := range c.Rooms {
+			if r == room {
+				corridors = append(corridors, c)
+				break
+			}
+		}
+	}
+	return corridors
+}
+
+// corridorRooms returns the two rooms joined by the corridor with door
+func (world *World) corridorRooms(door Rect) (Rect, Rect, bool) {
+	for _, c := range world.Corridors {
+		if c.Door == door && len(c.Rooms) == 2 {
+			return c.Rooms[0], c.Rooms[1], true
+		}
+	}
+	return Rect{}, Rect{}, false
+}
+
+// CorridorStyle controls the shape of corridors carved by CarveCorridor
+type CorridorStyle int8
+
+// Corridor styles
+const (
+	CorridorStyleElbow   CorridorStyle = iota // straight along one axis then the other, turning once
+	CorridorStyleShort                        // the shortest way round walls, reusing floor which is already there
+	CorridorStyleWinding                      // like CorridorStyleShort but wandering along a noise cost field
+)
+
+// corridorWallCost is how much more CarveCorridor avoids digging through walls than through void
+const corridorWallCost = 30
+
+// CarveCorridor digs a corridor width wide from from to to, such as to join custom structures to the rest of the map.
+// Tiles which can already be walked on are kept, walls are repaired the way AddWalls would and world.RouteCost is
+// applied to the routed styles. A door is added where the corridor leaves a room and where it enters one, and the
+// corridor is recorded in world.Corridors with the door at its to end (or its from end if it doesn't enter a room).
+// ErrOutOfBounds is returned if either end is outside of world.Border, ErrCorridorTooWide if width is less than 1 and
+// ErrNoPath if there's no way through
+func (world *World) CarveCorridor(from, to Point, width int, style CorridorStyle) (Corridor, error) {
+	if width < 1 {
+		return Corridor{}, ErrCorridorTooWide
+	}
+	b := world.Border
+	for _, p := range [2]Point{from, to} {
+		if p.X < b || p.Y < b || p.X >= world.Width-b || p.Y >= world.Height-b {
+			return Corridor{}, ErrOutOfBounds
+		}
+	}
+
+	var path []Point
+	switch style {
+	case CorridorStyleElbow:
+		path = elbowPath(from, to, world.rng.Intn(2) == 0)
+	default:
+		n := newNoise(world.rng)
+		cost := func(x, y int) float64 {
+			if x < b || y < b || x >= world.Width-b || y >= world.Height-b {
+				return -1
+			}
+			wind := 1.0
+			if style == CorridorStyleWinding {
+				wind += roadWinding * n.Fractal(float64(x)/10, float64(y)/10, 2)
+			}
+			switch {
+			case world.walkable(x, y):
+				return 0.5
+			case world.Tiles[y][x] == TileWall || world.Tiles[y][x] == TilePreWall:
+				return wind * corridorWallCost
+			}
+			return wind
+		}
+		minCost, cost := world.routeCost(0.5, cost)
+		path = world.findPath(from, to, minCost, cost)
+	}
+	if path == nil {
+		return Corridor{}, ErrNoPath
+	}
+
+	// Keep the part of the path between the rooms at either end
+	fromRoom, inFrom := world.RoomAt(from.X, from.Y)
+	toRoom, inTo := world.RoomAt(to.X, to.Y)
+	start, end := 0, len(path)
+	for inFrom && start < end-1 && fromRoom.contains(path[start].X, path[start].Y) {
+		start++
+	}
+	for inTo && end-1 > start && toRoom.contains(path[end-1].X, path[end-1].Y) {
+		end--
+	}
+	full := path
+	path = path[start:end]
+
+	// Tiles across the corridor at each point of the path
+	offset := (width - 1) / 2
+	tiles := make([]Point, 0, len(path)*width)
+	for _, p := range path {
+		for dy := -offset; dy < width-offset; dy++ {
+			for dx := -offset; dx < width-offset; dx++ {
+				x, y := p.X+dx, p.Y+dy
+				if x >= b && y >= b && x < world.Width-b && y < world.Height-b {
+					tiles = append(tiles, Point{X: x, Y: y})
+				}
+			}
+		}
+	}
+	world.repairWalls(pointBounds(tiles), func() {
+		for _, t := range tiles {
+			if !world.walkable(t.X, t.Y) {
+				world.setFloor(t.X, t.Y, FloorKindCorridor)
+			}
+		}
+	})
+
+	// doorAt adds a door across the corridor at p, the end of the path beside the room tile next
+	doorAt := func(p, next Point) Rect {
+		door := Rect{X: p.X - offset, Y: p.Y, W: width, H: 1}
+		dir := DoorDirectionHorizontal
+		if p.Y == next.Y {
+			door = Rect{X: p.X, Y: p.Y - offset, W: 1, H: width}
+			dir = DoorDirectionVertical
+		}
+		world.Doors[door] = dir
+		return door
+	}
+	var door Rect
+	if inFrom && start > 0 {
+		door = doorAt(path[0], full[start-1])
+	}
+	if inTo && end < len(full) {
+		door = doorAt(path[len(path)-1], full[end])
+	}
+	return world.addCorridor(path, width, door), nil
+}
+
+// elbowPath returns a path from from to to which goes straight along x then y, or y then x if yFirst is set
+func elbowPath(from, to Point, yFirst bool) []Point {
+	path := []Point{from}
+	p := from
+	step := func(axis *int, target int) {
+		for *axis != target {
+			if *axis < target {
+				*axis++
+			} else {
+				*axis--
+			}
+			path = append(path, p)
+		}
+	}
+	if yFirst {
+		step(&p.Y, to.Y)
+		step(&p.X, to.X)
+	} else {
+		step(&p.X, to.X)
+		step(&p.Y, to.Y)
+	}
+	return path
+}
@@ -0,0 +1,62 @@
+package generate
+
+// CostFunc returns the cost of routing a path through x,y. Negative costs can't be routed through
+type CostFunc func(world *World, x, y int) float64
+
+// FindPath returns the cheapest path from from to to (inclusive) where cost is the cost of stepping onto each tile, or
+// nil if there's no path
+func (world *World) FindPath(from, to Point, cost CostFunc) []Point {
+	return world.findPath(from, to, 0, func(x, y int) float64 {
+		return cost(world, x, y)
+	})
+}
+
+// routeCost applies world.RouteCost on top of a router's own cost. minCost is the router's lowest cost and is
+// returned as 0 if RouteCost could lower it
+func (world *World) routeCost(minCost float64, cost func(x, y int) float64) (float64, func(x, y int) float64) {
+	if world.RouteCost == nil {
+		return minCost, cost
+	}
+	return 0, func(x, y int) float64 {
+		c := cost(x, y)
+		if c < 0 {
+			return c
+		}
+		m := world.RouteCost(world, x, y)
+		if m < 0 {
+			return m
+		}
+		return c * m
+	}
+}
+
+// AvoidTiles returns a RouteCost which multiplies the cost of the given tiles by cost, use a negative cost to never
+// route through them
+func AvoidTiles(cost float64, tiles ...Tile) CostFunc {
+	avoid := make(map[Tile]struct{}, len(tiles))
+	for _, t := range tiles {
+		avoid[t] = struct{}{}
+	}
+	return func(world *World, x, y int) float64 {
+		if _, ok := avoid[world.Tiles[y][x]]; ok {
+			return cost
+		}
+		return 1
+	}
+}
+
+// HugWalls returns a RouteCost which makes tiles next to a wall cost factor (0-1) as much, so paths follow walls
+func HugWalls(factor float64) CostFunc {
+	return func(world *World, x, y int) float64 {
+		for _, o := range polarOffsets {
+			ox, oy := x+o.X, y+o.Y
+			if ox < 0 || oy < 0 || ox >= world.Width || oy >= world.Height {
+				continue
+			}
+			if t := world.Tiles[oy][ox]; t == TileWall || t == TilePreWall {
+				return factor
+			}
+		}
+		return 1
+	}
+}
@@ -0,0 +1,127 @@
+package generate
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// ErrDeltaMismatch is returned when a delta is applied to a world of a different size than it was encoded from
+var ErrDeltaMismatch = errors.New("Delta is for a different sized world")
+
+// delta is written by EncodeDelta, tile changes are [x, y, tile]
+type delta struct {
+	Width        int                 `json:"width"`
+	Height       int                 `json:"height"`
+	Tiles        [][3]int            `json:"tiles,omitempty"`
+	Layers       map[string][][3]int `json:"layers,omitempty"`
+	Doors        []deltaDoor         `json:"doors,omitempty"`
+	RemovedDoors []Rect              `json:"removedDoors,omitempty"`
+}
+
+// deltaDoor is a door added or changed since the base
+type deltaDoor struct {
+	Rect
+	Direction DoorDirection
+}
+
+// EncodeDelta writes the differences between the world and base as JSON, for save games which keep the seed and
+// regenerate base from it instead of saving the whole map. Tiles, decoration layers and doors are compared, other
+// structure such as rooms and corridors isn't saved. Use ApplyDelta on a fresh copy of base to restore the world
+func (world *World) EncodeDelta(base *World, w io.Writer) error {
+	if base.Width != world.Width || base.Height != world.Height {
+		return ErrDeltaMismatch
+	}
+	d := delta{Width: world.Width, Height: world.Height, Layers: make(map[string][][3]int)}
+	for y := range world.Tiles {
+		for x, t := range world.Tiles[y] {
+			if t != base.Tiles[y][x] {
+				d.Tiles = append(d.Tiles, [3]int{x, y, int(t)})
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for name := range world.Layers {
+		names[name] = true
+	}
+	for name := range base.Layers {
+		names[name] = true
+	}
+	for name := range names {
+		layer, baseLayer := world.Layers[name], base.Layers[name]
+		changes := make([][3]int, 0)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				var t, bt Tile
+				if layer != nil {
+					t = layer[y][x]
+				}
+				if baseLayer != nil {
+					bt = baseLayer[y][x]
+				}
+				if t != bt {
+					changes = append(changes, [3]int{x, y, int(t)})
+				}
+			}
+		}
+		if len(changes) > 0 {
+			d.Layers[name] = changes
+		}
+	}
+
+	for door, dir := range world.Doors {
+		if baseDir, ok := base.Doors[door]; !ok || baseDir != dir {
+			d.Doors = append(d.Doors, deltaDoor{Rect: door, Direction: dir})
+		}
+	}
+	sort.Slice(d.Doors, func(i, j int) bool {
+		a, b := d.Doors[i].Rect, d.Doors[j].Rect
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+	for door := range base.Doors {
+		if _, ok := world.Doors[door]; !ok {
+			d.RemovedDoors = append(d.RemovedDoors, door)
+		}
+	}
+	sortRects(d.RemovedDoors)
+
+	return json.NewEncoder(w).Encode(d)
+}
+
+// ApplyDelta reads a delta written by EncodeDelta and applies it to the world, which should be the base the delta was
+// encoded against. ErrDeltaMismatch is returned if the world is a different size
+func (world *World) ApplyDelta(r io.Reader) error {
+	var d delta
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return err
+	}
+	if d.Width != world.Width || d.Height != world.Height {
+		return ErrDeltaMismatch
+	}
+	for _, c := range d.Tiles {
+		if c[0] < 0 || c[1] < 0 || c[0] >= world.Width || c[1] >= world.Height {
+			return ErrOutOfBounds
+		}
+		world.Tiles[c[1]][c[0]] = Tile(c[2])
+	}
+	for name, changes := range d.Layers {
+		layer := world.Layer(name)
+		for _, c := range changes {
+			if c[0] < 0 || c[1] < 0 || c[0] >= world.Width || c[1] >= world.Height {
+				return ErrOutOfBounds
+			}
+			layer[c[1]][c[0]] = Tile(c[2])
+		}
+	}
+	for _, door := range d.RemovedDoors {
+		delete(world.Doors, door)
+		delete(world.DoorTags, door)
+		delete(world.Ledges, door)
+	}
+	for _, door := range d.Doors {
+		world.Doors[door.Rect] = door.Direction
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DescribeStyle controls how much RoomInfo.Describe says
+type DescribeStyle int8
+
+// Describe styles
+const (
+	DescribeTerse   DescribeStyle = iota // a single sentence for message logs
+	DescribeVerbose                      // a paragraph covering contents and exits, MUD style
+)
+
+// RoomInfo is a room along with everything the world knows about it
+type RoomInfo struct {
+	Rect     Rect
+	Biome    Biome
+	Tags     []Tag
+	Exits    []Direction  // which sides of the room corridors leave from, in the order north, east, south, west
+	Contents map[Tile]int // how many of each non floor tile are in the room, including decoration layers
+}
+
+// RoomInfo collects the metadata of room
+func (world *World) RoomInfo(room Rect) RoomInfo {
+	info := RoomInfo{
+		Rect:     room,
+		Biome:    world.Biomes[room.Y+room.H/2][room.X+room.W/2],
+		Tags:     world.RoomTags[room],
+		Contents: make(map[Tile]int),
+	}
+
+	exits := make(map[Direction]struct{})
+	for _, c := range world.CorridorsOf(room) {
+		for _, p := range [2]Point{c.From, c.To} {
+			switch {
+			case p.Y < room.Y:
+				exits[DirectionNorth] = struct{}{}
+			case p.Y >= room.Y+room.H:
+				exits[DirectionSouth] = struct{}{}
+			case p.X < room.X:
+				exits[DirectionWest] = struct{}{}
+			case p.X >= room.X+room.W:
+				exits[DirectionEast] = struct{}{}
+			}
+		}
+	}
+	for _, d := range [4]Direction{DirectionNorth, DirectionEast, DirectionSouth, DirectionWest} {
+		if _, ok := exits[d]; ok {
+			info.Exits = append(info.Exits, d)
+		}
+	}
+
+	for y := room.Y; y < room.Y+room.H; y++ {
+		for x := room.X; x < room.X+room.W; x++ {
+			if t := world.Tiles[y][x]; t != TileFloor {
+				info.Contents[t]++
+			}
+			for _, layer := range world.Layers {
+				if t := layer[y][x]; t != TileVoid {
+					info.Contents[t]++
+				}
+			}
+		}
+	}
+	return info
+}
+
+var (
+	biomeRoomNames = map[Biome][]string{
+		BiomeNone:      {"room", "chamber"},
+		BiomeDungeon:   {"chamber", "hall", "room"},
+		BiomeCave:      {"cavern", "grotto", "cave"},
+		BiomeCrypt:     {"burial chamber", "tomb", "vault"},
+		BiomeGrassland: {"clearing", "meadow"},
+		BiomeForest:    {"glade", "thicket"},
+		BiomeBeach:     {"cove", "stretch of sand"},
+		BiomeOcean:     {"lagoon", "shoal"},
+	}
+	tagSentences = map[Tag]string{
+		TagStart:    "This is where the journey begins.",
+		TagBoss:     "An oppressive presence fills the air.",
+		TagTreasure: "Something glitters in the dark.",
+		TagShop:     "A merchant has set up shop here.",
+		TagSafe:     "It feels safe here.",
+		TagArena:    "The floor is scarred from old battles.",
+		TagLocked:   "The way in was sealed.",
+		TagKey:      "Something important was left here.",
+		TagPuzzle:   "Strange mechanisms cover the floor.",
+		TagLandmark: "Something here stands out from afar.",
+	}
+	contentNames = map[Tile][2]string{
+		TileChest:      {"a chest", "chests"},
+		TilePillar:     {"a pillar", "pillars"},
+		TileLowWall:    {"a low wall", "low walls"},
+		TileWater:      {"a puddle", "pools of water"},
+		TileChasm:      {"a crack in the floor", "a yawning chasm"},
+		TileBridge:     {"a plank", "a bridge"},
+		TileTree:       {"a tree", "trees"},
+		TileCounter:    {"a counter", "a long counter"},
+		TileShelf:      {"a shelf", "shelves"},
+		TileNPC:        {"a lone figure", "a group of figures"},
+		TilePortal:     {"a shimmering portal", "shimmering portals"},
+		TileBush:       {"a bush", "bushes"},
+		TileStairs:     {"a step", "stairs"},
+		TileGas:        {"a wisp of gas", "clouds of foul gas"},
+		TileCold:       {"a patch of frost", "a bitter chill"},
+		TileDark:       {"a deep shadow", "an unnatural darkness"},
+		TileBlock:      {"a heavy block", "heavy blocks"},
+		TilePlate:      {"a pressure plate", "pressure plates"},
+		TileLever:      {"a lever", "a row of levers"},
+		TilePortcullis: {"a portcullis", "portcullises"},
+		TileTrack:      {"a length of track", "minecart tracks"},
+		TileJunction:   {"a set of points", "junctions in the track"},
+	}
+	directionNames = map[Direction]string{
+		DirectionNorth: "north",
+		DirectionEast:  "east",
+		DirectionSouth: "south",
+		DirectionWest:  "west",
+	}
+)
+
+// Describe returns a human readable description of the room. The same room always gets the same description
+func (info RoomInfo) Describe(style DescribeStyle) string {
+	// Pick words from the room's position so descriptions don't change between calls
+	pick := func(options []string, salt int) string {
+		return options[absInt(info.Rect.X*31+info.Rect.Y*17+salt)%len(options)]
+	}
+
+	area := info.Rect.W * info.Rect.H
+	var size string
+	switch {
+	case area < 20:
+		size = "cramped"
+	case area < 50:
+		size = "small"
+	case area < 120:
+		size = pick([]string{"modest", "plain"}, 1)
+	case area < 250:
+		size = "large"
+	default:
+		size = "vast"
+	}
+	switch {
+	case info.Rect.W >= info.Rect.H*3:
+		size += ", long"
+	case info.Rect.H >= info.Rect.W*3:
+		size += ", narrow"
+	}
+	names, ok := biomeRoomNames[info.Biome]
+	if !ok {
+		names = biomeRoomNames[BiomeNone]
+	}
+	name := pick(names, 2)
+
+	article := "A"
+	if strings.ContainsRune("aeiou", rune(size[0])) {
+		article = "An"
+	}
+	sentences := []string{fmt.Sprintf("%s %s %s", article, size, name)}
+
+	if style == DescribeTerse {
+		if len(info.Exits) > 0 {
+			sentences[0] += fmt.Sprintf(" with %d %s", len(info.Exits), plural(len(info.Exits), "exit", "exits"))
+		}
+		return sentences[0] + "."
+	}
+	sentences[0] += "."
+
+	tiles := make([]Tile, 0, len(info.Contents))
+	for t := range info.Contents {
+		tiles = append(tiles, t)
+	}
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i] < tiles[j] })
+	contents := make([]string, 0)
+	for _, t := range tiles {
+		if names, ok := contentNames[t]; ok {
+			if info.Contents[t] == 1 {
+				contents = append(contents, names[0])
+			} else {
+				contents = append(contents, names[1])
+			}
+		}
+	}
+	if len(contents) > 0 {
+		sentences = append(sentences, fmt.Sprintf("You see %s.", joinWords(contents)))
+	}
+
+	for _, tag := range info.Tags {
+		if s, ok := tagSentences[tag]; ok {
+			sentences = append(sentences, s)
+		}
+	}
+
+	exits := make([]string, 0, len(info.Exits))
+	for _, d := range info.Exits {
+		exits = append(exits, directionNames[d])
+	}
+	switch len(exits) {
+	case 0:
+		sentences = append(sentences, "There is no obvious way out.")
+	case 1:
+		sentences = append(sentences, fmt.Sprintf("The only exit leads %s.", exits[0]))
+	default:
+		sentences = append(sentences, fmt.Sprintf("Exits lead %s.", joinWords(exits)))
+	}
+	return strings.Join(sentences, " ")
+}
+
+// plural returns one if n is 1, otherwise many
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// joinWords joins words into a list like "a, b and c"
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
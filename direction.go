@@ -0,0 +1,99 @@
+package generate
+
+// Direction is one of the four compass directions, north is towards y = 0
+type Direction int8
+
+// Directions
+const (
+	DirectionNorth Direction = iota
+	DirectionEast
+	DirectionSouth
+	DirectionWest
+)
+
+// growDirections are the directions generators pick from when world.Directions is empty, in the order they've always
+// been picked in so seeds keep making the same maps
+var growDirections = []Direction{DirectionWest, DirectionEast, DirectionNorth, DirectionSouth}
+
+// Dx returns how far a step in the direction moves along x
+func (d Direction) Dx() int {
+	switch d {
+	case DirectionEast:
+		return 1
+	case DirectionWest:
+		return -1
+	}
+	return 0
+}
+
+// Dy returns how far a step in the direction moves along y
+func (d Direction) Dy() int {
+	switch d {
+	case DirectionSouth:
+		return 1
+	case DirectionNorth:
+		return -1
+	}
+	return 0
+}
+
+// Opposite returns the direction facing the other way
+func (d Direction) Opposite() Direction {
+	return (d + 2) % 4
+}
+
+// Neighbor returns the position one tile from x,y in dir
+func (world *World) Neighbor(x, y int, dir Direction) (int, int) {
+	return x + dir.Dx(), y + dir.Dy()
+}
+
+// directions returns the directions generators are allowed to grow in
+func (world *World) directions() []Direction {
+	if len(world.Directions) == 0 {
+		return growDirections
+	}
+	return world.Directions
+}
+
+// randomDirection returns one of the directions generators are allowed to grow in
+func (world *World) randomDirection() Direction {
+	dirs := world.directions()
+	return dirs[world.rng.Int()%len(dirs)]
+}
+
+// corridorDirection returns a random direction for a straight corridor to leave a room in, from the directions
+// generators are allowed to grow in. The corridor meets the room it leaves on that side and the next room on the
+// opposite side, so each direction is weighted by world.DoorSides of both. If every weight is 0 it's randomDirection
+func (world *World) corridorDirection() Direction {
+	dirs := world.directions()
+	weight := func(d Direction) float64 {
+		return world.DoorSides[d] + world.DoorSides[d.Opposite()]
+	}
+	total := 0.0
+	for _, d := range dirs {
+		total += weight(d)
+	}
+	if total <= 0 {
+		return world.randomDirection()
+	}
+	r := world.rng.Float64() * total
+	for _, d := range dirs {
+		if r -= weight(d); r < 0 {
+			return d
+		}
+	}
+	return dirs[len(dirs)-1]
+}
+
+// doorSideEnd returns which end of a straight corridor its door goes at: first, which meets a room on its firstSide,
+// or last, which meets a room on its lastSide, whichever has more weight in world.DoorSides. false is returned if
+// neither does
+func (world *World) doorSideEnd(first, last int, firstSide, lastSide Direction) (int, bool) {
+	switch a, b := world.DoorSides[firstSide], world.DoorSides[lastSide]; {
+	case a > b:
+		return first, true
+	case b > a:
+		return last, true
+	}
+	return 0, false
+}
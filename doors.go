@@ -0,0 +1,138 @@
+package generate
+
+// DoorViolation is a door which can't be opened properly, see CheckDoors
+type DoorViolation struct {
+	Door    Rect
+	Blocked bool // a tile on one side of the door can't be walked on, so it opens onto nothing
+	Exposed bool // the door isn't held between walls at both ends, it's out in the open or in a corner, see CheckDoors
+}
+
+// doorShift is how far FixDoors moves a door along its corridor looking for a better place for it
+const doorShift = 3
+
+// CheckDoors returns every door which doesn't have walkable tiles on both sides or isn't set between two walls, in
+// row order. Doors in cave passages only need to be clear
+func (world *World) CheckDoors() []DoorViolation {
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		doors = append(doors, door)
+	}
+	sortRects(doors)
+	violations := make([]DoorViolation, 0)
+	for _, door := range doors {
+		if v, bad := world.doorViolation(door, world.Doors[door]); bad {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// FixDoors moves every door CheckDoors finds fault with to the nearest place along its corridor where it's clear on
+// both sides and set between walls, updating the corridors, door tags, ledges and gates which refer to it. Doors with
+// nowhere to go stay where they are, so no room is cut off from the graph, and are returned for the caller to deal
+// with. The number of doors moved is returned along with them
+func (world *World) FixDoors() (moved int, stuck []DoorViolation) {
+	stuck = make([]DoorViolation, 0)
+	for _, v := range world.CheckDoors() {
+		door, dir := v.Door, world.Doors[v.Door]
+		step := doorStep(dir)
+		fixed := false
+		for k := 1; k <= doorShift && !fixed; k++ {
+			for _, s := range [2]int{k, -k} {
+				to := Rect{X: door.X + step.X*s, Y: door.Y + step.Y*s, W: door.W, H: door.H}
+				if world.doorFits(to, dir) {
+					world.moveDoor(door, to)
+					fixed = true
+					break
+				}
+			}
+		}
+		if fixed {
+			moved++
+		} else {
+			stuck = append(stuck, v)
+		}
+	}
+	return moved, stuck
+}
+
+// doorStep returns the direction through a door
+func doorStep(dir DoorDirection) Point {
+	if dir == DoorDirectionVertical {
+		return Point{X: 1}
+	}
+	return Point{Y: 1}
+}
+
+// doorViolation checks a door, returning true if there's something wrong with it
+func (world *World) doorViolation(door Rect, dir DoorDirection) (DoorViolation, bool) {
+	v := DoorViolation{Door: door}
+	step := doorStep(dir)
+	for y := door.Y; y < door.Y+door.H; y++ {
+		for x := door.X; x < door.X+door.W; x++ {
+			if !world.walkable(x-step.X, y-step.Y) || !world.walkable(x+step.X, y+step.Y) {
+				v.Blocked = true
+			}
+		}
+	}
+	// The jambs are just past either end of the door, across the way through it. Cave passages open straight into
+	// their chambers, so doors found in them by InferRooms are never between walls
+	if world.FloorKinds[door.Y][door.X] == FloorKindCave {
+		return v, v.Blocked
+	}
+	before := Point{X: door.X - step.Y, Y: door.Y - step.X}
+	after := Point{X: door.X + (door.W-1)*step.Y + step.Y, Y: door.Y + (door.H-1)*step.X + step.X}
+	if world.walkable(before.X, before.Y) || world.walkable(after.X, after.Y) {
+		v.Exposed = true
+	}
+	return v, v.Blocked || v.Exposed
+}
+
+// doorFits returns true if a door could be moved to door: every tile is walkable corridor outside of the rooms and
+// other doors, and it would be clear on both sides and set between walls
+func (world *World) doorFits(door Rect, dir DoorDirection) bool {
+	for y := door.Y; y < door.Y+door.H; y++ {
+		for x := door.X; x < door.X+door.W; x++ {
+			if !world.walkable(x, y) {
+				return false
+			}
+			if _, in := world.RoomAt(x, y); in {
+				return false
+			}
+		}
+	}
+	for other := range world.Doors {
+		if other.overlaps(door) {
+			return false
+		}
+	}
+	_, bad := world.doorViolation(door, dir)
+	return !bad
+}
+
+// moveDoor moves a door and everything which refers to it to to
+func (world *World) moveDoor(door, to Rect) {
+	dir := world.Doors[door]
+	tags, tagged := world.DoorTags[door]
+	room, ledge := world.Ledges[door]
+	delete(world.Doors, door)
+	delete(world.DoorTags, door)
+	delete(world.Ledges, door)
+	for i, c := range world.Corridors {
+		if c.Door == door {
+			world.Corridors[i].Door = to
+		}
+	}
+	for i, g := range world.Gates {
+		if g.Door == door {
+			world.Gates[i].Door = to
+		}
+	}
+	world.Doors[to] = dir
+	if tagged {
+		world.DoorTags[to] = tags
+	}
+	if ledge {
+		world.Ledges[to] = room
+	}
+}
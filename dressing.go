@@ -0,0 +1,84 @@
+package generate
+
+import "math/rand"
+
+// Layer is a grid of decoration tiles drawn on top of world.Tiles, indexed [y][x]. TileVoid means nothing is there
+type Layer [][]Tile
+
+// DressingPass decorates the world by writing to its layers. It must only use rng for randomness and must not change
+// the world's structure (Tiles, Rooms, Doors) so it can be re-run by Redecorate
+type DressingPass func(world *World, rng *rand.Rand)
+
+// Layer returns the named decoration layer, creating it if it doesn't exist yet
+func (world *World) Layer(name string) Layer {
+	if layer, ok := world.Layers[name]; ok {
+		return layer
+	}
+	layer := make(Layer, world.Height)
+	for i := range layer {
+		layer[i] = make([]Tile, world.Width)
+	}
+	world.Layers[name] = layer
+	return layer
+}
+
+// AddDressing registers a pass to be run by Redecorate. Passes are run in the order they were added
+func (world *World) AddDressing(pass DressingPass) {
+	world.dressing = append(world.dressing, pass)
+}
+
+// ClearDressing removes every decoration layer and puzzle and refunds the budgets, leaving the structure untouched
+func (world *World) ClearDressing() {
+	world.Layers = make(map[string]Layer)
+	world.Facing = make(map[Point]Direction)
+	world.Puzzles = nil
+	world.refundBudgets()
+}
+
+// Redecorate clears the decoration layers and re-runs every dressing pass using seed, without touching the world's
+// structure. The same seed always produces the same dressing for the same structure
+func (world *World) Redecorate(seed int64) {
+	world.ClearDressing()
+	r := rand.New(rand.NewSource(seed))
+	for _, pass := range world.dressing {
+		pass(world, r)
+	}
+}
+
+// ScatterDressing returns a pass which places t on the named layer on each floor tile of the given kind with the given
+// chance (0-1). Use FloorKindNone to scatter on every floor tile
+func ScatterDressing(name string, t Tile, kind FloorKind, chance float64) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		layer := world.Layer(name)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if world.Tiles[y][x] != TileFloor || (kind != FloorKindNone && world.FloorKinds[y][x] != kind) {
+					continue
+				}
+				if rng.Float64() < chance {
+					layer[y][x] = t
+				}
+			}
+		}
+	}
+}
+
+// FloatLayer is a grid of values computed by an analysis pass, indexed [y][x]
+type FloatLayer [][]float64
+
+// NewFloatLayer returns a FloatLayer filled with 0
+func NewFloatLayer(width, height int) FloatLayer {
+	layer := make(FloatLayer, height)
+	for i := range layer {
+		layer[i] = make([]float64, width)
+	}
+	return layer
+}
+
+// At returns the value at x,y, or 0 if x,y is outside of the layer
+func (layer FloatLayer) At(x, y int) float64 {
+	if y < 0 || y >= len(layer) || x < 0 || x >= len(layer[y]) {
+		return 0
+	}
+	return layer[y][x]
+}
@@ -0,0 +1,146 @@
+package generate
+
+// EdgeWalls is the world with thin walls along the edges between tiles instead of walls which fill whole tiles, for
+// games which move and draw that way. Walls run between every walkable tile and every tile which isn't
+type EdgeWalls struct {
+	Width, Height int
+	Open          [][]bool // indexed [y][x], tiles which can be walked on
+	North         [][]bool // indexed [y][x], Height+1 rows, a wall along the top of x,y. Row Height is the bottom edge
+	West          [][]bool // indexed [y][x], Width+1 columns, a wall along the left of x,y. Column Width is the right edge
+}
+
+// EdgeSegment is a straight run of edge walls between two corners, corner x,y is the top left corner of tile x,y
+type EdgeSegment struct {
+	From, To Point
+}
+
+// EdgeWalls converts the world to thin edge walls. It works the same way as AddWalls but puts a wall on each edge
+// of a walkable tile which faces a tile that isn't walkable, so it can be used before or instead of AddWalls
+func (world *World) EdgeWalls() *EdgeWalls {
+	w, h := world.Width, world.Height
+	edges := &EdgeWalls{
+		Width:  w,
+		Height: h,
+		Open:   make([][]bool, h),
+		North:  make([][]bool, h+1),
+		West:   make([][]bool, h),
+	}
+	for y := 0; y < h; y++ {
+		edges.Open[y] = make([]bool, w)
+		for x := 0; x < w; x++ {
+			edges.Open[y][x] = world.Tiles[y][x].Walkable()
+		}
+	}
+	open := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < w && y < h && edges.Open[y][x]
+	}
+	for y := 0; y <= h; y++ {
+		edges.North[y] = make([]bool, w)
+		for x := 0; x < w; x++ {
+			edges.North[y][x] = open(x, y) != open(x, y-1)
+		}
+	}
+	for y := 0; y < h; y++ {
+		edges.West[y] = make([]bool, w+1)
+		for x := 0; x <= w; x++ {
+			edges.West[y][x] = open(x, y) != open(x-1, y)
+		}
+	}
+	return edges
+}
+
+// Blocked returns true if moving from x,y one tile in dir crosses a wall or leaves the map
+func (edges *EdgeWalls) Blocked(x, y int, dir Direction) bool {
+	if x < 0 || y < 0 || x >= edges.Width || y >= edges.Height {
+		return true
+	}
+	switch dir {
+	case DirectionNorth:
+		return y == 0 || edges.North[y][x]
+	case DirectionSouth:
+		return y == edges.Height-1 || edges.North[y+1][x]
+	case DirectionWest:
+		return x == 0 || edges.West[y][x]
+	case DirectionEast:
+		return x == edges.Width-1 || edges.West[y][x+1]
+	}
+	return true
+}
+
+// Tiles converts the edge walls back to whole tiles, at twice the size plus one so every edge and corner gets a tile.
+// Tile x,y becomes 2x+1,2y+1, open tiles and the open edges between them become TileFloor and walls become TileWall
+func (edges *EdgeWalls) Tiles() [][]Tile {
+	w, h := edges.Width*2+1, edges.Height*2+1
+	tiles := make([][]Tile, h)
+	for y := range tiles {
+		tiles[y] = make([]Tile, w)
+	}
+	for y := 0; y < edges.Height; y++ {
+		for x := 0; x < edges.Width; x++ {
+			if edges.Open[y][x] {
+				tiles[y*2+1][x*2+1] = TileFloor
+				// Open edges between two open tiles
+				if x+1 < edges.Width && edges.Open[y][x+1] && !edges.West[y][x+1] {
+					tiles[y*2+1][x*2+2] = TileFloor
+				}
+				if y+1 < edges.Height && edges.Open[y+1][x] && !edges.North[y+1][x] {
+					tiles[y*2+2][x*2+1] = TileFloor
+				}
+			}
+			// Corners in the middle of open space
+			if x > 0 && y > 0 && edges.Open[y][x] && edges.Open[y-1][x] && edges.Open[y][x-1] && edges.Open[y-1][x-1] {
+				tiles[y*2][x*2] = TileFloor
+			}
+		}
+	}
+	for y := 0; y <= edges.Height; y++ {
+		for x := 0; x < edges.Width; x++ {
+			if edges.North[y][x] {
+				tiles[y*2][x*2] = TileWall
+				tiles[y*2][x*2+1] = TileWall
+				tiles[y*2][x*2+2] = TileWall
+			}
+		}
+	}
+	for y := 0; y < edges.Height; y++ {
+		for x := 0; x <= edges.Width; x++ {
+			if edges.West[y][x] {
+				tiles[y*2][x*2] = TileWall
+				tiles[y*2+1][x*2] = TileWall
+				tiles[y*2+2][x*2] = TileWall
+			}
+		}
+	}
+	return tiles
+}
+
+// Segments returns the walls as the fewest straight segments, horizontal segments first, for exporting to engines
+// which build walls from lines
+func (edges *EdgeWalls) Segments() []EdgeSegment {
+	segments := make([]EdgeSegment, 0)
+	for y := 0; y <= edges.Height; y++ {
+		for x := 0; x < edges.Width; x++ {
+			if !edges.North[y][x] {
+				continue
+			}
+			start := x
+			for x+1 < edges.Width && edges.North[y][x+1] {
+				x++
+			}
+			segments = append(segments, EdgeSegment{From: Point{X: start, Y: y}, To: Point{X: x + 1, Y: y}})
+		}
+	}
+	for x := 0; x <= edges.Width; x++ {
+		for y := 0; y < edges.Height; y++ {
+			if !edges.West[y][x] {
+				continue
+			}
+			start := y
+			for y+1 < edges.Height && edges.West[y+1][x] {
+				y++
+			}
+			segments = append(segments, EdgeSegment{From: Point{X: x, Y: start}, To: Point{X: x, Y: y + 1}})
+		}
+	}
+	return segments
+}
@@ -0,0 +1,107 @@
+package generate
+
+// CarveRect digs out rect as TileFloor, for changing the map while a game runs such as with explosions or digging,
+// and repairs the walls around it the way AddWalls would. The floor has FloorKindNone. Floor can't be carved inside
+// world.Border, ErrOutOfBounds is returned and nothing is changed
+func (world *World) CarveRect(rect Rect) error {
+	b := world.Border
+	if rect.W < 1 || rect.H < 1 || rect.X < b || rect.Y < b ||
+		rect.X+rect.W > world.Width-b || rect.Y+rect.H > world.Height-b {
+		return ErrOutOfBounds
+	}
+	world.repairWalls(rect, func() {
+		for y := rect.Y; y < rect.Y+rect.H; y++ {
+			for x := rect.X; x < rect.X+rect.W; x++ {
+				world.SetTile(x, y, TileFloor)
+			}
+		}
+	})
+	return nil
+}
+
+// FillRectWall fills rect with TileWall, for changing the map while a game runs such as with cave-ins or building,
+// and repairs the walls around it the way AddWalls would: walls which were only there for floor that's now filled
+// become TileVoid, so filling a whole room leaves it as if it was never dug. Doors, ledges and door tags overlapping
+// rect are removed. Rooms aren't changed, use ShrinkRoom to make a room smaller.
+// ErrOutOfBounds is returned if rect is entirely outside the map
+func (world *World) FillRectWall(rect Rect) error {
+	x0, y0 := maxInt(rect.X, 0), maxInt(rect.Y, 0)
+	x1, y1 := minInt(rect.X+rect.W, world.Width), minInt(rect.Y+rect.H, world.Height)
+	if x1 <= x0 || y1 <= y0 {
+		return ErrOutOfBounds
+	}
+	rect = Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+	world.repairWalls(rect, func() {
+		for y := rect.Y; y < rect.Y+rect.H; y++ {
+			for x := rect.X; x < rect.X+rect.W; x++ {
+				world.SetTile(x, y, TileWall)
+			}
+		}
+	})
+	for door := range world.Doors {
+		if door.overlaps(rect) {
+			delete(world.Doors, door)
+			delete(world.DoorTags, door)
+			delete(world.Ledges, door)
+		}
+	}
+	return nil
+}
+
+// repairWalls runs edit, which changes the tiles in area, then redoes AddWalls around area: void within wall
+// thickness of a floor becomes TileWall, and walls which were next to floor before the edit but aren't anymore become
+// TileVoid. Walls which never had floor next to them, such as solid rock and the map border, are left alone. Tiles
+// are changed with SetTile so the room placement index stays up to date
+func (world *World) repairWalls(area Rect, edit func()) {
+	t := wallGap(world.maxWallThickness())
+	reach := area.Expand(t)
+	x0, y0 := maxInt(reach.X, 0), maxInt(reach.Y, 0)
+	x1, y1 := minInt(reach.X+reach.W, world.Width), minInt(reach.Y+reach.H, world.Height)
+
+	// walled returns which tiles of reach are floor or within wall thickness of one
+	walled := func() [][]bool {
+		near := make([][]bool, y1-y0)
+		for y := y0; y < y1; y++ {
+			near[y-y0] = make([]bool, x1-x0)
+			for x := x0; x < x1; x++ {
+				near[y-y0][x-x0] = world.nearFloor(x, y, t)
+			}
+		}
+		return near
+	}
+	before := walled()
+	edit()
+	after := walled()
+
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			switch tile := world.Tiles[y][x]; {
+			case tile == TileVoid && after[y-y0][x-x0]:
+				world.SetTile(x, y, TileWall)
+			case tile == TileWall && before[y-y0][x-x0] && !after[y-y0][x-x0]:
+				world.SetTile(x, y, TileVoid)
+			}
+		}
+	}
+}
+
+// nearFloor returns true if x,y is a TileFloor or is within the wall thickness of one, looking up to t tiles away
+func (world *World) nearFloor(x, y, t int) bool {
+	for fy := maxInt(y-t, 0); fy <= minInt(y+t, world.Height-1); fy++ {
+		for fx := maxInt(x-t, 0); fx <= minInt(x+t, world.Width-1); fx++ {
+			if world.Tiles[fy][fx] != TileFloor {
+				continue
+			}
+			d := maxInt(absInt(fx-x), absInt(fy-y))
+			if d <= wallGap(world.paramsAt(fx, fy).WallThickness) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlaps returns true if the rects share any tiles
+func (r Rect) overlaps(o Rect) bool {
+	return r.X < o.X+o.W && o.X < r.X+r.W && r.Y < o.Y+o.H && o.Y < r.Y+r.H
+}
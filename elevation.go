@@ -0,0 +1,251 @@
+package generate
+
+import "errors"
+
+// ErrImpossibleClimb is returned when a path can only be walked by climbing more than one level, or one level without
+// stairs
+var ErrImpossibleClimb = errors.New("Path needs an impossible climb")
+
+// elevationAttempts is how many times AddElevation picks new levels before giving up
+const elevationAttempts = 10
+
+// SetElevationRect sets the elevation of every tile inside of r, making the world's elevation if it's flat
+func (world *World) SetElevationRect(r Rect, level int) {
+	if world.Elevation == nil {
+		world.Elevation = make([][]int, world.Height)
+		for y := range world.Elevation {
+			world.Elevation[y] = make([]int, world.Width)
+		}
+	}
+	for y := maxInt(r.Y, 0); y < minInt(r.Y+r.H, world.Height); y++ {
+		for x := maxInt(r.X, 0); x < minInt(r.X+r.W, world.Width); x++ {
+			world.Elevation[y][x] = level
+		}
+	}
+}
+
+// AddElevation raises the rooms to levels from 0 to levels-1 and places TileStairs where they meet, so a level can
+// have raised and sunken areas. Rooms are levelled outwards from the start of CriticalPath, each at most one level
+// from the rooms it's connected to where that's possible, and corridors take the level of the nearest room.
+// The critical path is checked with CheckClimb, the levels are picked again if it needs an impossible climb and
+// ErrImpossibleClimb is returned if none of the attempts work, leaving the world flat. Call it after AddWalls
+func (world *World) AddElevation(levels int) error {
+	rooms := world.RoomsOrdered()
+	if len(rooms) == 0 {
+		return ErrNoRooms
+	}
+	path := world.CriticalPath()
+	if len(path) == 0 {
+		return ErrNoPath
+	}
+	from, to := path[0], path[len(path)-1]
+	start, ok := world.RoomAt(from.X, from.Y)
+	if !ok {
+		start = rooms[0]
+	}
+
+	adj := make(map[Rect][]Rect)
+	for _, e := range world.BuildGraph().Edges {
+		if !e.Portal {
+			adj[e.From] = append(adj[e.From], e.To)
+			adj[e.To] = append(adj[e.To], e.From)
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt < elevationAttempts; attempt++ {
+		level := world.pickLevels(start, adj, levels)
+		world.SetElevationRect(Rect{W: world.Width, H: world.Height}, 0)
+		for room, l := range level {
+			world.SetElevationRect(room, l)
+		}
+		world.spreadElevation(rooms)
+
+		stairs := world.placeStairs()
+		if err = world.CheckClimb(from, to); err == nil {
+			return nil
+		}
+		for p, t := range stairs {
+			world.SetTile(p.X, p.Y, t)
+		}
+		if err != ErrImpossibleClimb {
+			break
+		}
+	}
+	world.Elevation = nil
+	return err
+}
+
+// pickLevels gives every room reachable from start a random level from 0 to levels-1, keeping each room within a
+// level of its neighbours where it can. Rooms are levelled breadth first, each from a level next to the room it was
+// reached from
+func (world *World) pickLevels(start Rect, adj map[Rect][]Rect, levels int) map[Rect]int {
+	levels = maxInt(levels, 1)
+	level := map[Rect]int{start: world.rng.Intn(levels)}
+	queue := []Rect{start}
+	for len(queue) > 0 {
+		room := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[room] {
+			if _, ok := level[next]; ok {
+				continue
+			}
+			options := make([]int, 0, 3)
+			for l := maxInt(level[room]-1, 0); l <= minInt(level[room]+1, levels-1); l++ {
+				fits := true
+				for _, n := range adj[next] {
+					if nl, ok := level[n]; ok && absInt(nl-l) > 1 {
+						fits = false
+						break
+					}
+				}
+				if fits {
+					options = append(options, l)
+				}
+			}
+			level[next] = level[room]
+			if len(options) > 0 {
+				level[next] = options[world.rng.Intn(len(options))]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return level
+}
+
+// spreadElevation gives every tile outside of rooms the elevation of the nearest room, walking along walkable tiles
+// first so corridors are split between the rooms at either end, then across everything else
+func (world *World) spreadElevation(rooms []Rect) {
+	done := make([][]bool, world.Height)
+	for y := range done {
+		done[y] = make([]bool, world.Width)
+	}
+	queue := make([]Point, 0)
+	for _, room := range rooms {
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				done[y][x] = true
+				queue = append(queue, Point{X: x, Y: y})
+			}
+		}
+	}
+	spread := func(queue []Point, passable func(x, y int) bool) {
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, o := range polarOffsets {
+				x, y := p.X+o.X, p.Y+o.Y
+				if x < 0 || y < 0 || x >= world.Width || y >= world.Height || done[y][x] || !passable(x, y) {
+					continue
+				}
+				done[y][x] = true
+				world.Elevation[y][x] = world.Elevation[p.Y][p.X]
+				queue = append(queue, Point{X: x, Y: y})
+			}
+		}
+	}
+	spread(queue, world.walkable)
+
+	queue = queue[:0]
+	for y := range done {
+		for x := range done[y] {
+			if done[y][x] {
+				queue = append(queue, Point{X: x, Y: y})
+			}
+		}
+	}
+	spread(queue, func(x, y int) bool { return true })
+}
+
+// PlaceRamps puts TileStairs between walkable tiles which are one level apart, so they can be climbed. The stairs go
+// on the lower tile, or the higher one if the lower one isn't a TileFloor. The number of stairs placed is returned
+func (world *World) PlaceRamps() int {
+	return len(world.placeStairs())
+}
+
+// placeStairs is PlaceRamps, returning the tiles which were replaced by stairs
+func (world *World) placeStairs() map[Point]Tile {
+	replaced := make(map[Point]Tile)
+	if world.Elevation == nil {
+		return replaced
+	}
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if !world.walkable(x, y) {
+				continue
+			}
+			for _, o := range [2]Point{{X: 1}, {Y: 1}} {
+				nx, ny := x+o.X, y+o.Y
+				if !world.walkable(nx, ny) || absInt(world.Elevation[ny][nx]-world.Elevation[y][x]) != 1 {
+					continue
+				}
+				if world.Tiles[y][x] == TileStairs || world.Tiles[ny][nx] == TileStairs {
+					continue
+				}
+				low, high := Point{X: x, Y: y}, Point{X: nx, Y: ny}
+				if world.Elevation[ny][nx] < world.Elevation[y][x] {
+					low, high = high, low
+				}
+				for _, p := range [2]Point{low, high} {
+					if world.Tiles[p.Y][p.X] == TileFloor {
+						replaced[p] = TileFloor
+						world.SetTile(p.X, p.Y, TileStairs)
+						break
+					}
+				}
+			}
+		}
+	}
+	return replaced
+}
+
+// CanStep returns true if a walkable tile can be walked onto from the next to it. Tiles on the same level can always
+// be stepped between, tiles a level apart only if either of them is TileStairs
+func (world *World) CanStep(from, to Point) bool {
+	if !world.walkable(from.X, from.Y) || !world.walkable(to.X, to.Y) {
+		return false
+	}
+	if world.Elevation == nil {
+		return true
+	}
+	switch absInt(world.Elevation[from.Y][from.X] - world.Elevation[to.Y][to.X]) {
+	case 0:
+		return true
+	case 1:
+		return world.Tiles[from.Y][from.X] == TileStairs || world.Tiles[to.Y][to.X] == TileStairs
+	}
+	return false
+}
+
+// CheckClimb returns nil if to can be walked to from from with CanStep or through portals, ErrImpossibleClimb if it can
+// only be reached by ignoring elevation, or ErrNoPath if it can't be reached at all
+func (world *World) CheckClimb(from, to Point) error {
+	if !world.walkable(from.X, from.Y) {
+		return ErrNoPath
+	}
+	links := world.portalLinks()
+	seen := map[Point]bool{from: true}
+	queue := []Point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == to {
+			return nil
+		}
+		for _, o := range polarOffsets {
+			n := Point{X: p.X + o.X, Y: p.Y + o.Y}
+			if !seen[n] && world.CanStep(p, n) {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+		if n, ok := links[p]; ok && !seen[n] && world.walkable(n.X, n.Y) {
+			seen[n] = true
+			queue = append(queue, n)
+		}
+	}
+	if pathFromDistances(world.DistanceField(from), to, nil) != nil {
+		return ErrImpossibleClimb
+	}
+	return ErrNoPath
+}
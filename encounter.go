@@ -0,0 +1,64 @@
+package generate
+
+// EncounterOptions controls how EncounterDensity weighs each tile
+type EncounterOptions struct {
+	Base           float64           // density of a tile next to the start
+	DistanceWeight float64           // how much the density grows towards the tile furthest from the start
+	CorridorWeight float64           // multiplier for corridor tiles
+	TagWeights     map[Tag]float64   // multiplier for tiles in rooms with a tag
+	BiomeWeights   map[Biome]float64 // multiplier for tiles in a biome
+}
+
+// DefaultEncounterOptions returns options where encounters are rarer near the start, in safe rooms and in shops
+func DefaultEncounterOptions() EncounterOptions {
+	return EncounterOptions{
+		Base:           0.02,
+		DistanceWeight: 2,
+		CorridorWeight: 0.5,
+		TagWeights: map[Tag]float64{
+			TagStart: 0,
+			TagSafe:  0,
+			TagShop:  0,
+			TagBoss:  0,
+		},
+		BiomeWeights: map[Biome]float64{},
+	}
+}
+
+// EncounterDensity returns the chance of a random encounter on each walkable tile, indexed [y][x], growing with the
+// walking distance from start and weighted by room tags, biome and floor kind. Tiles which can't be reached from
+// start have a density of 0. Densities are clamped to 0-1
+func (world *World) EncounterDensity(start Point, opts EncounterOptions) FloatLayer {
+	dist := world.DistanceField(start)
+	maxDist := 1
+	for y := range dist {
+		for x := range dist[y] {
+			maxDist = maxInt(maxDist, dist[y][x])
+		}
+	}
+
+	density := NewFloatLayer(world.Width, world.Height)
+	for y := range dist {
+		for x := range dist[y] {
+			if dist[y][x] < 0 {
+				continue
+			}
+			d := opts.Base * (1 + opts.DistanceWeight*float64(dist[y][x])/float64(maxDist))
+			if world.FloorKinds[y][x] == FloorKindCorridor {
+				d *= opts.CorridorWeight
+			}
+			if w, ok := opts.BiomeWeights[world.Biomes[y][x]]; ok {
+				d *= w
+			}
+			if room, ok := world.RoomAt(x, y); ok {
+				for _, tag := range world.RoomTags[room] {
+					if w, ok := opts.TagWeights[tag]; ok {
+						d *= w
+					}
+				}
+			}
+			density[y][x] = clampFloat(d, 0, 1)
+		}
+	}
+	return density
+}
@@ -2,8 +2,8 @@
 package main
 
 import (
-	"fmt"
 	"log"
+	"os"
 
 	gen "github.com/melonfunction/dungeon-gen"
 )
@@ -50,13 +50,13 @@ func main() {
 		world.WallThickness = 2
 		world.Border = world.WallThickness
 		world.AllowRandomCorridorOffset = false
-		err = world.GenerateDungeonGrid(10)
+		_, err = world.GenerateDungeonGrid(10)
 		world.AddWalls()
 	case Dungeon:
 		world.WallThickness = 1
 		world.Border = world.WallThickness
 		world.AllowRandomCorridorOffset = true
-		err = world.GenerateDungeon(10)
+		_, err = world.GenerateDungeon(10)
 		world.AddWalls()
 	}
 
@@ -64,23 +64,13 @@ func main() {
 		log.Println(err)
 	}
 
-	// Replace the world's tiles with some debug emojis to see door and room placement
-	for door := range world.Doors {
-		for dx := door.X; dx < door.X+door.W; dx++ {
-			for dy := door.Y; dy < door.Y+door.H; dy++ {
-				world.Tiles[dy][dx] = gen.TileDoor
-			}
-		}
-	}
+	// Mark where each room begins and ends, doors are drawn by Render
 	for room := range world.Rooms {
 		world.Tiles[room.Y][room.X] = gen.TileRoomBegin
 		world.Tiles[room.Y+room.H-1][room.X+room.W-1] = gen.TileRoomEnd
 	}
 
-	for y := 0; y < h; y++ {
-		for x := 0; x < w; x++ {
-			fmt.Print(world.Tiles[y][x])
-		}
-		fmt.Println()
+	if err := world.Render(os.Stdout, gen.RenderModeTile); err != nil {
+		log.Println(err)
 	}
 }
@@ -0,0 +1,113 @@
+//go:build js && wasm
+
+// Package main exposes the generator to JavaScript as generate(configJSON) -> mapJSON, build it with
+//
+//	GOOS=js GOARCH=wasm go build -o examples/wasm/main.wasm ./examples/wasm
+//	cp "$(go env GOROOT)/misc/wasm/wasm_exec.js" examples/wasm/
+//
+// then serve examples/wasm and open index.html
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"syscall/js"
+
+	gen "github.com/melonfunction/dungeon-gen"
+)
+
+// config is the JSON passed to generate. The world's parameters use the names of gen.WorldConfig's fields, such as
+// {"Width": 80, "Height": 60, "Seed": 4, "Generator": "dungeon", "Rooms": 10}
+type config struct {
+	gen.WorldConfig
+	Generator string // "dungeon", "grid", "walk" or "catacombs"
+	Rooms     int    // rooms for dungeon and grid, segments for catacombs
+	Tiles     int    // floor tiles for walk
+}
+
+// worldMap is the JSON returned by generate
+type worldMap struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Tiles  [][]int    `json:"tiles"` // indexed [y][x], the values of gen.Tile
+	Rooms  []gen.Rect `json:"rooms"`
+	Doors  []gen.Rect `json:"doors"`
+	Error  string     `json:"error,omitempty"`
+}
+
+func main() {
+	js.Global().Set("generate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return encode(worldMap{Error: "generate needs a config"})
+		}
+		return encode(generate(args[0].String()))
+	}))
+	// Keep the functions alive
+	select {}
+}
+
+// generate builds and generates a world from configJSON
+func generate(configJSON string) worldMap {
+	cfg := config{WorldConfig: gen.NewWorldConfig(80, 60), Generator: "dungeon", Rooms: 10}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return worldMap{Error: err.Error()}
+	}
+	cfg.Stats = nil
+	cfg.Generate = func(world *gen.World) (err error) {
+		switch cfg.Generator {
+		case "grid":
+			_, err = world.GenerateDungeonGrid(cfg.Rooms)
+		case "walk":
+			tiles := cfg.Tiles
+			if tiles <= 0 {
+				tiles = world.Width * world.Height / 4
+			}
+			err = world.GenerateRandomWalk(tiles)
+			world.CleanIslands()
+			world.CleanWalls(5)
+		case "catacombs":
+			err = world.GenerateCatacombs(cfg.Rooms, gen.DefaultCatacombOptions())
+		default:
+			_, err = world.GenerateDungeon(cfg.Rooms)
+		}
+		world.AddWalls()
+		return err
+	}
+
+	// A batch of one seeds the generator from cfg.Seed, so the same config always gives the same map
+	worlds, err := gen.GenerateBatch(cfg.WorldConfig, 1, 1)
+	world := worlds[0]
+	m := worldMap{
+		Width:  world.Width,
+		Height: world.Height,
+		Tiles:  make([][]int, world.Height),
+		Rooms:  world.RoomsOrdered(),
+		Doors:  make([]gen.Rect, 0, len(world.Doors)),
+	}
+	for y := range m.Tiles {
+		m.Tiles[y] = make([]int, world.Width)
+		for x := range m.Tiles[y] {
+			m.Tiles[y][x] = int(world.Tiles[y][x])
+		}
+	}
+	for door := range world.Doors {
+		m.Doors = append(m.Doors, door)
+	}
+	sort.Slice(m.Doors, func(i, j int) bool {
+		a, b := m.Doors[i], m.Doors[j]
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+	if err != nil {
+		m.Error = err.Error()
+	}
+	return m
+}
+
+// encode returns m as a JSON string
+func encode(m worldMap) string {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return `{"error":"` + err.Error() + `"}`
+	}
+	return string(b)
+}
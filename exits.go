@@ -0,0 +1,145 @@
+package generate
+
+// Exit is a named way in or out of the map, such as a gate on its edge or stairs down to a cellar, for hub maps which
+// connect to several other maps
+type Exit struct {
+	Name   string
+	Point  Point
+	Side   Direction // the edge of the map the exit was placed towards
+	Room   Rect      // the room the exit is in, if InRoom
+	InRoom bool
+}
+
+// ExitSpec declares an exit for PlaceExits
+type ExitSpec struct {
+	Name   string
+	Side   Direction // the exit is placed as close to this edge of the map as possible
+	Marker Tile      // drawn on the "markers" layer at the exit, TileVoid for none
+}
+
+// exitSpacing is how far apart exits on the same side are kept
+const exitSpacing = 4
+
+// PlaceExits places an exit for each spec on the walkable tile nearest its side, preferring the middle of the side.
+// Every exit is placed in the largest connected area of the map and then checked with Solve (using world.Rules())
+// to make sure each one can be reached from every other, so ErrNoPath is returned if locks or ledges get in the way.
+// The exits replace any placed before and are returned by world.Exits(), rooms they're in are tagged with TagEntrance
+func (world *World) PlaceExits(specs ...ExitSpec) ([]Exit, error) {
+	world.exits = nil
+	area := world.largestArea()
+	if len(area) == 0 {
+		return nil, ErrNotEnoughSpace
+	}
+
+	exits := make([]Exit, 0, len(specs))
+	for _, spec := range specs {
+		best, bestScore := Point{}, -1
+		for _, p := range area {
+			tooClose := false
+			for _, e := range exits {
+				if absInt(e.Point.X-p.X)+absInt(e.Point.Y-p.Y) < exitSpacing {
+					tooClose = true
+					break
+				}
+			}
+			if tooClose {
+				continue
+			}
+			// Distance from the side first, then from the middle of it
+			var score int
+			switch spec.Side {
+			case DirectionNorth:
+				score = p.Y*world.Width + absInt(p.X-world.Width/2)
+			case DirectionSouth:
+				score = (world.Height-1-p.Y)*world.Width + absInt(p.X-world.Width/2)
+			case DirectionWest:
+				score = p.X*world.Height + absInt(p.Y-world.Height/2)
+			case DirectionEast:
+				score = (world.Width-1-p.X)*world.Height + absInt(p.Y-world.Height/2)
+			}
+			if bestScore == -1 || score < bestScore {
+				best, bestScore = p, score
+			}
+		}
+		if bestScore == -1 {
+			return nil, ErrNotEnoughSpace
+		}
+		exit := Exit{Name: spec.Name, Point: best, Side: spec.Side}
+		exit.Room, exit.InRoom = world.RoomAt(best.X, best.Y)
+		exits = append(exits, exit)
+	}
+
+	// Each exit has to reach the next one and the last has to reach the first, so they can all reach each other
+	rules := world.Rules()
+	for i := range exits {
+		if len(exits) < 2 {
+			break
+		}
+		next := exits[(i+1)%len(exits)]
+		if _, err := world.Solve(exits[i].Point, next.Point, rules); err != nil {
+			return nil, err
+		}
+	}
+
+	markers := world.Layer("markers")
+	for i, exit := range exits {
+		if exit.InRoom {
+			world.TagRoom(exit.Room, TagEntrance)
+		}
+		if specs[i].Marker != TileVoid {
+			markers[exit.Point.Y][exit.Point.X] = specs[i].Marker
+		}
+	}
+	world.exits = exits
+	return world.Exits(), nil
+}
+
+// Exits returns the exits placed by PlaceExits
+func (world *World) Exits() []Exit {
+	return append([]Exit(nil), world.exits...)
+}
+
+// ExitMatrix returns the walking distance between every pair of exits, indexed [from][to] in the order of Exits.
+// Locks and ledges aren't taken into account
+func (world *World) ExitMatrix() [][]int {
+	matrix := make([][]int, len(world.exits))
+	for i, from := range world.exits {
+		dist := world.DistanceField(from.Point)
+		matrix[i] = make([]int, len(world.exits))
+		for j, to := range world.exits {
+			matrix[i][j] = dist[to.Point.Y][to.Point.X]
+		}
+	}
+	return matrix
+}
+
+// largestArea returns the walkable tiles of the largest area which can be walked between
+func (world *World) largestArea() []Point {
+	seen := make([][]bool, world.Height)
+	for y := range seen {
+		seen[y] = make([]bool, world.Width)
+	}
+	var largest []Point
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if seen[y][x] || !world.walkable(x, y) {
+				continue
+			}
+			seen[y][x] = true
+			area := []Point{{X: x, Y: y}}
+			for i := 0; i < len(area); i++ {
+				for _, o := range polarOffsets {
+					ox, oy := area[i].X+o.X, area[i].Y+o.Y
+					if world.walkable(ox, oy) && !seen[oy][ox] {
+						seen[oy][ox] = true
+						area = append(area, Point{X: ox, Y: oy})
+					}
+				}
+			}
+			if len(area) > len(largest) {
+				largest = area
+			}
+		}
+	}
+	return largest
+}
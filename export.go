@@ -0,0 +1,197 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// TileExportOptions controls ExportTiles
+type TileExportOptions struct {
+	TileSize int // width and height of each PNG in pixels
+	Scale    int // pixels per world tile at the highest zoom level
+}
+
+// DefaultTileExportOptions returns options for 256 pixel tiles with 4 pixels per world tile, the usual size for
+// web map viewers
+func DefaultTileExportOptions() TileExportOptions {
+	return TileExportOptions{
+		TileSize: 256,
+		Scale:    4,
+	}
+}
+
+// tileExportIndex is written to index.json by ExportTiles
+type tileExportIndex struct {
+	Width    int               `json:"width"`  // of the world in tiles
+	Height   int               `json:"height"` // of the world in tiles
+	Scale    int               `json:"scale"`
+	TileSize int               `json:"tileSize"`
+	MinZoom  int               `json:"minZoom"`
+	MaxZoom  int               `json:"maxZoom"`
+	URL      string            `json:"url"`
+	Legend   map[string]string `json:"legend"` // tile names to their colors
+}
+
+// ExportTiles writes the world to dir as a pyramid of PNG tiles laid out as {z}/{x}/{y}.png, the layout map viewers
+// such as Leaflet load, plus an index.json describing the size, zoom levels and colors. The highest zoom level has
+// opts.Scale pixels per world tile and each level below halves it, down to zoom 0 where the world fits in one tile.
+// Only a tile at a time is drawn, so worlds far too big for a single image can be exported.
+// Tiles are colored the same way as Render, with doors and decoration layers drawn on top
+func (world *World) ExportTiles(dir string, opts TileExportOptions) error {
+	if opts.TileSize < 1 || opts.Scale < 1 {
+		return ErrInvalidFactor
+	}
+	colors, legend := world.tileColors()
+
+	size := maxInt(world.Width, world.Height) * opts.Scale
+	maxZoom := 0
+	for opts.TileSize<<uint(maxZoom) < size {
+		maxZoom++
+	}
+
+	for z := 0; z <= maxZoom; z++ {
+		// World tiles per pixel at this zoom
+		perPixel := float64(uint(1)<<uint(maxZoom-z)) / float64(opts.Scale)
+		cols := int(float64(world.Width)/perPixel+float64(opts.TileSize)-1) / opts.TileSize
+		rows := int(float64(world.Height)/perPixel+float64(opts.TileSize)-1) / opts.TileSize
+		for tx := 0; tx < maxInt(cols, 1); tx++ {
+			column := filepath.Join(dir, fmt.Sprint(z), fmt.Sprint(tx))
+			if err := os.MkdirAll(column, 0755); err != nil {
+				return err
+			}
+			for ty := 0; ty < maxInt(rows, 1); ty++ {
+				img := image.NewRGBA(image.Rect(0, 0, opts.TileSize, opts.TileSize))
+				for py := 0; py < opts.TileSize; py++ {
+					y := int(float64(ty*opts.TileSize+py) * perPixel)
+					if y >= world.Height {
+						break
+					}
+					for px := 0; px < opts.TileSize; px++ {
+						x := int(float64(tx*opts.TileSize+px) * perPixel)
+						if x >= world.Width {
+							break
+						}
+						img.SetRGBA(px, py, colors[y][x])
+					}
+				}
+				if err := writePNG(filepath.Join(column, fmt.Sprintf("%d.png", ty)), img); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(tileExportIndex{
+		Width:    world.Width,
+		Height:   world.Height,
+		Scale:    opts.Scale,
+		TileSize: opts.TileSize,
+		MaxZoom:  maxZoom,
+		URL:      "{z}/{x}/{y}.png",
+		Legend:   legend,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// tileColors returns the color of every tile, indexed [y][x], and the hex color of each tile name used. Tiles are
+// drawn with their background color, doors and layers on top with their foreground color
+func (world *World) tileColors() ([][]color.RGBA, map[string]string) {
+	legend := make(map[string]string)
+	styleOf := func(t Tile) tileStyle {
+		if style, ok := tileStyles[t]; ok {
+			return style
+		}
+		return tileStyle{name: fmt.Sprintf("user tile %d", t-TileUser), fg: 226, bg: 226}
+	}
+	use := func(name string, c color.RGBA) color.RGBA {
+		legend[name] = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+		return c
+	}
+
+	colors := make([][]color.RGBA, world.Height)
+	for y := range colors {
+		colors[y] = make([]color.RGBA, world.Width)
+		for x, t := range world.Tiles[y] {
+			style := styleOf(t)
+			colors[y][x] = use(style.name, ansiColor(style.bg))
+		}
+	}
+	door := tileStyles[TileDoor]
+	for d := range world.Doors {
+		for y := d.Y; y < d.Y+d.H; y++ {
+			for x := d.X; x < d.X+d.W; x++ {
+				colors[y][x] = use(door.name, ansiColor(door.bg))
+			}
+		}
+	}
+	names := make([]string, 0, len(world.Layers))
+	for name := range world.Layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for y, row := range world.Layers[name] {
+			for x, t := range row {
+				if t != TileVoid {
+					style := styleOf(t)
+					colors[y][x] = use(style.name, ansiColor(style.fg))
+				}
+			}
+		}
+	}
+	return colors, legend
+}
+
+// ansiColor converts an ANSI 256 color code to RGB
+func ansiColor(code int) color.RGBA {
+	switch {
+	case code < 16:
+		// The standard colors, as xterm draws them
+		standard := [16][3]uint8{
+			{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205},
+			{229, 229, 229}, {127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255},
+			{255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+		}
+		c := standard[maxInt(code, 0)]
+		return color.RGBA{R: c[0], G: c[1], B: c[2], A: 255}
+	case code < 232:
+		level := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		code -= 16
+		return color.RGBA{R: level(code / 36), G: level(code / 6 % 6), B: level(code % 6), A: 255}
+	}
+	v := uint8(8 + (minInt(code, 255)-232)*10)
+	return color.RGBA{R: v, G: v, B: v, A: 255}
+}
+
+// writePNG writes img to a new file at path
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
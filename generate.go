@@ -3,14 +3,22 @@ package generate
 
 import (
 	"errors"
-	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"time"
 )
 
-// Tile represents the type of tile being used
-type Tile int8
+// Tile represents the type of tile being used. Built-in tiles keep their values between versions, new ones are only
+// added to the end of the list, so saved maps stay valid. Values from TileUser to TileUserMax are reserved for tiles
+// defined by users and are never used by the package
+type Tile int16
+
+// Reserved tile range for users, define custom tiles as TileUser, TileUser+1 and so on
+const (
+	TileUser    Tile = 1 << 12
+	TileUserMax Tile = math.MaxInt16
+)
 
 // Tiles
 const (
@@ -22,6 +30,41 @@ const (
 	TileDoor
 	TileRoomBegin
 	TileRoomEnd
+
+	TileWater
+	TileChasm
+	TileBridge
+
+	TileChest
+
+	TileGrass
+	TileTree
+	TileRoad
+	TileSand
+	TileBoat
+
+	TilePillar
+	TileLowWall
+
+	TileCounter
+	TileShelf
+	TileNPC
+
+	TilePortal
+	TileBush
+	TileStairs
+
+	TileGas // hazards, see HazardDressing
+	TileCold
+	TileDark
+
+	TileBlock // puzzles, see PuzzleDressing
+	TilePlate
+	TileLever
+	TilePortcullis
+
+	TileTrack // see TrackDressing
+	TileJunction
 )
 
 // Tiles aliases for creating neat maps manually
@@ -32,6 +75,18 @@ const (
 	F = TileFloor
 )
 
+// FloorKind specifies what a TileFloor was generated as, so rooms and corridors can be told apart
+type FloorKind int8
+
+// Floor kinds
+const (
+	FloorKindNone FloorKind = iota // not a floor, or placed manually
+	FloorKindRoom
+	FloorKindCorridor
+	FloorKindCave // RandomWalk and the cleaning passes
+	FloorKindAlcove
+)
+
 // DoorDirection specifies the direction of a Door
 type DoorDirection int8
 
@@ -41,6 +96,11 @@ const (
 	DoorDirectionVertical
 )
 
+// IsUser returns true if the tile is in the range reserved for users
+func (t Tile) IsUser() bool {
+	return t >= TileUser && t <= TileUserMax
+}
+
 func (t Tile) String() string {
 	switch t {
 	case TileVoid:
@@ -57,6 +117,58 @@ func (t Tile) String() string {
 		return "🟢"
 	case TileRoomEnd:
 		return "🔴"
+	case TileWater:
+		return "🟦"
+	case TileChasm:
+		return "🟪"
+	case TileBridge:
+		return "🟫"
+	case TileChest:
+		return "🎁"
+	case TileGrass:
+		return "🟩"
+	case TileTree:
+		return "🌲"
+	case TileRoad:
+		return "🟨"
+	case TileSand:
+		return "🟧"
+	case TileBoat:
+		return "⛵"
+	case TilePillar:
+		return "🗿"
+	case TileLowWall:
+		return "🧱"
+	case TileCounter:
+		return "🔲"
+	case TileShelf:
+		return "📚"
+	case TileNPC:
+		return "🧙"
+	case TilePortal:
+		return "🌀"
+	case TileBush:
+		return "🌿"
+	case TileStairs:
+		return "🪜"
+	case TileGas:
+		return "💨"
+	case TileCold:
+		return "❄️"
+	case TileDark:
+		return "🌑"
+	case TileBlock:
+		return "🪨"
+	case TilePlate:
+		return "🔘"
+	case TileLever:
+		return "🕹️"
+	case TilePortcullis:
+		return "⛓️"
+	case TileTrack:
+		return "🛤️"
+	case TileJunction:
+		return "🔀"
 	}
 
 	return "🚧"
@@ -67,31 +179,81 @@ func (t Tile) String() string {
 type World struct {
 	Width, Height int
 
-	Tiles [][]Tile // indexed [y][x]
-	Rooms map[Rect]struct{}
-	Doors map[Rect]DoorDirection
-
-	ShowErrorMessages bool
+	Tiles      [][]Tile      // indexed [y][x]
+	FloorKinds [][]FloorKind // indexed [y][x], what each TileFloor was generated as
+	Biomes     [][]Biome     // indexed [y][x]
+	Elevation  [][]int       // indexed [y][x], nil while the world is flat, see AddElevation
+	Rooms      map[Rect]struct{}
+	RoomParts  map[Rect][]Rect        // rooms merged into compound rooms, keyed by the first room, see RoomOverlap
+	Doors      map[Rect]DoorDirection // doors span the width of their corridor
+	Corridors  []Corridor
+	Ledges     map[Rect]Rect // doors which can only be passed one way, mapped to the room they drop into
+	RoomTags   map[Rect][]Tag
+	RoomNames  map[Rect]string // see NameRooms
+	RoomThemes map[Rect]Theme  // see AssignThemes
+	themeRules *ThemeRules
+	LevelName  string
+	DoorTags   map[Rect][]Tag
+	Sectors    []Sector
+	Gates      []Gate
+	Portals    []Portal
+	Puzzles    []Puzzle   // see PuzzleDressing
+	Landmarks  []Landmark // see PlaceLandmarks
+	exits      []Exit
+	roomOrder  []Rect
+
+	Layers   map[string]Layer    // decoration, kept separate from the structure above
+	Facing   map[Point]Direction // which way markers placed on the layers face
+	dressing []DressingPass
+	budgets  map[Rect]*budget
+
+	setPieces []SetPiece
+
+	RecordHeatmaps bool                  // record statistics about generation to Heatmaps
+	Heatmaps       map[string]FloatLayer // see HeatmapVisits and HeatmapRetries
+
+	Report      GenReport     // what happened during the last generation
+	Stats       StatsRecorder // optional, receives counters and timings after every generation
+	reportDepth int
+	reportStart time.Time
+
+	index *spatialIndex // only kept while rooms are being placed
+
+	Logf              func(format string, args ...interface{}) // receives messages about retries, log.Printf if nil
+	ShowErrorMessages bool                                     // send the messages to Logf
+
+	Seed int64 // what the world's random source was seeded with, see NewWorldWithSeed
+	rng  *rand.Rand
 
 	startTime           time.Time // for generation retry
 	DurationBeforeRetry time.Duration
 	genStartTime        time.Time // for error
 	DurationBeforeError time.Duration
 
-	Border                    int // don't place tiles in this area
-	WallThickness             int // how many tiles thick the walls are
+	Border                    int         // don't place tiles in this area
+	BorderStyle               BorderStyle // how AddWalls finishes the edge of the map
+	WallThickness             int         // how many tiles thick the walls are, 0 for single walls from AddWalls only
 	MinCorridorSize           int
 	MaxCorridorSize           int
 	AllowRandomCorridorOffset bool
+	NarrowDoorways            bool // corridors wider than a tile are narrowed to a 1 tile doorway where they meet rooms
 	MaxRoomWidth              int
 	MaxRoomHeight             int
 	MinRoomWidth              int
 	MinRoomHeight             int
-	MinIslandSize             int // RandomWalk only; any TileVoid islands < this are filled with TileFloor
+	MinIslandSize             int         // RandomWalk only; any TileVoid islands < this are filled with TileFloor
+	MinRoomSeparation         int         // Dungeon only; rooms are at least this far apart, if it's more than WallThickness
+	FlushRooms                bool        // rooms and maze passages go right up to the walls around the map
+	RoomOverlap               float64     // Dungeon only; chance of a room overlapping the last, see RoomParts
+	GridCellHeight            int         // DungeonGrid only; height of the cells, MaxRoomWidth for square cells if 0
+	Zones                     []Zone      // parameters which are overridden in parts of the world
+	RouteCost                 CostFunc    // multiplies the cost of routing corridors and roads through each tile
+	WeatherCheck              WeatherFunc // approves each hole Weather knocks through a wall, every hole if nil
+	Directions                []Direction // directions generators grow rooms and corridors in, all of them if empty
+	DoorSides                 [4]float64  // weight of corridors meeting rooms on each side, by Direction; evenly if all 0
 }
 
 var (
-	rng *rand.Rand
 	// ErrOutOfBounds is returned when a tile is attempted to be placed out of bounds
 	ErrOutOfBounds = errors.New("Coordinate out of bounds")
 	// ErrNotEnoughSpace is returned when there isn't enough space to generate the dungeon
@@ -100,6 +262,12 @@ var (
 	ErrGenerationTimeout = errors.New("Took too long to generate dungeon")
 	// ErrFloorAlreadyPlaced is returned when a floor tile is already placed
 	ErrFloorAlreadyPlaced = errors.New("Floor tile already placed")
+	// ErrNoRooms is returned when an operation needs rooms but the world has none
+	ErrNoRooms = errors.New("World has no rooms")
+	// ErrNoPath is returned when two points can't be connected
+	ErrNoPath = errors.New("No path between points")
+	// ErrCorridorTooWide is returned when corridors are wider than the rooms they connect or the map itself
+	ErrCorridorTooWide = errors.New("Corridors are too wide to fit")
 )
 
 // ResetWorld clears the tiles from the world
@@ -110,15 +278,59 @@ func (world *World) ResetWorld(width, height int) {
 	}
 	world.Tiles = tiles
 
+	kinds := make([][]FloorKind, height)
+	for i := range kinds {
+		kinds[i] = make([]FloorKind, width)
+	}
+	world.FloorKinds = kinds
+
+	biomes := make([][]Biome, height)
+	for i := range biomes {
+		biomes[i] = make([]Biome, width)
+	}
+	world.Biomes = biomes
+	world.Elevation = nil
+
 	world.Rooms = make(map[Rect]struct{})
+	world.RoomParts = make(map[Rect][]Rect)
+	world.roomOrder = nil
 	world.Doors = make(map[Rect]DoorDirection)
+	world.Corridors = nil
+	world.Ledges = make(map[Rect]Rect)
+	world.RoomTags = make(map[Rect][]Tag)
+	world.RoomNames = make(map[Rect]string)
+	world.RoomThemes = make(map[Rect]Theme)
+	world.themeRules = nil
+	world.LevelName = ""
+	world.DoorTags = make(map[Rect][]Tag)
+	world.Sectors = nil
+	world.Gates = nil
+	world.Portals = nil
+	world.exits = nil
+	world.Layers = make(map[string]Layer)
+	world.Facing = make(map[Point]Direction)
+	world.Puzzles = nil
+	world.Landmarks = nil
+	world.budgets = nil
+	world.index = nil
 }
 
-// NewWorld returns a new World instance
+// NewWorld returns a new World instance, seeded from the clock. The seed is kept in world.Seed so the map can be made
+// again with NewWorldWithSeed
 func NewWorld(width, height int) *World {
-	s1 := rand.NewSource(time.Now().UnixNano())
-	rng = rand.New(s1)
+	return NewWorldWithSeed(width, height, time.Now().UnixNano())
+}
 
+// NewWorldWithSeed returns a new World instance with its random source seeded from seed, for maps which can be
+// reproduced exactly from save files or bug reports. The same seed, settings and calls always make the same map
+func NewWorldWithSeed(width, height int, seed int64) *World {
+	world := newWorld(width, height)
+	world.reseed(seed)
+	return world
+}
+
+// newWorld returns a new World instance with the default config and a random source seeded from the clock
+func newWorld(width, height int) *World {
 	world := &World{
 		Width:  width,
 		Height: height,
@@ -140,6 +352,7 @@ func NewWorld(width, height int) *World {
 		MinRoomHeight:             4,
 		MinIslandSize:             26,
 	}
+	world.reseed(time.Now().UnixNano())
 	world.ResetWorld(width, height)
 	return world
 }
@@ -162,10 +375,91 @@ func absInt(a int) int {
 	}
 	return a
 }
-func randInt(a, b int) int {
-	return rng.Int()%(b+1-a) + a
+func clampFloat(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// centered returns where something size long starts to be centered on a span length long starting at start. When they
+// can't be centered exactly, like a 2 wide corridor on a 5 wide room, the extra tile goes after it
+func centered(start, length, size int) int {
+	return start + (length-size)/2
+}
+
+// centerLine returns where a corridor size wide starts along the side two rooms share, which is length long on one
+// room and longer on the other, both starting at 0. It's centered on the side, but when an odd sized corridor can't
+// be centered exactly and the other room has a middle tile within reach, it's lined up with that instead, so the
+// corridor and its door are on the center line of a room rather than a tile off
+func centerLine(length, longer, size int) int {
+	if size%2 == 1 && length%2 == 0 && longer%2 == 1 {
+		if start := (longer-1)/2 - (size-1)/2; start >= 0 && start+size <= length {
+			return start
+		}
+	}
+	return centered(0, length, size)
+}
+
+// randInt returns a random int from a to b inclusive
+func (world *World) randInt(a, b int) int {
+	if b <= a {
+		return a
+	}
+	return world.rng.Int()%(b+1-a) + a
+}
+
+// logf sends a message to world.Logf if world.ShowErrorMessages is set
+func (world *World) logf(format string, args ...interface{}) {
+	if !world.ShowErrorMessages {
+		return
+	}
+	if world.Logf != nil {
+		world.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// reseed seeds the world's random source, recording the seed in world.Seed. Every World has its own source so worlds
+// can be generated concurrently without changing each other's maps
+func (world *World) reseed(seed int64) {
+	world.Seed = seed
+	world.rng = rand.New(rand.NewSource(seed))
+}
+
+// RandSource is a source of random numbers which can be plugged into a World with SetRandSource, such as crypto
+// randomness, a recorded stream for replays or a PCG generator. Intn returns a number from 0 to n-1
+type RandSource interface {
+	Intn(n int) int
+}
+
+// SetRandSource makes the world draw every random number from src instead of the math/rand source it was made with.
+// Sources which are also a rand.Source, such as *rand.Rand, are used directly, others are asked for 21 bits at a
+// time. world.Seed is set to 0 as it no longer says how the world was made
+func (world *World) SetRandSource(src RandSource) {
+	world.Seed = 0
+	if s, ok := src.(rand.Source); ok {
+		world.rng = rand.New(s)
+		return
+	}
+	world.rng = rand.New(intnSource{src: src})
+}
+
+// intnSource turns a RandSource into a rand.Source
+type intnSource struct {
+	src RandSource
 }
 
+func (s intnSource) Int63() int64 {
+	return int64(s.src.Intn(1<<21))<<42 | int64(s.src.Intn(1<<21))<<21 | int64(s.src.Intn(1<<21))
+}
+
+func (s intnSource) Seed(seed int64) {}
+
 // GetTile returns a tile
 func (world *World) GetTile(x, y int) (Tile, error) {
 	w, h, b := world.Width, world.Height, world.Border
@@ -182,13 +476,32 @@ func (world *World) SetTile(x, y int, t Tile) error {
 		return ErrOutOfBounds
 	}
 
+	if world.index != nil {
+		world.index.setTile(x, y, world.Tiles[y][x], t)
+	}
 	world.Tiles[y][x] = t
+	if world.FloorKinds != nil && t != TileFloor {
+		world.FloorKinds[y][x] = FloorKindNone
+	}
+	return nil
+}
+
+// setFloor sets a TileFloor and records which kind of floor it is
+func (world *World) setFloor(x, y int, kind FloorKind) error {
+	if err := world.SetTile(x, y, TileFloor); err != nil {
+		return err
+	}
+	if world.FloorKinds != nil {
+		world.FloorKinds[y][x] = kind
+	}
 	return nil
 }
 
-// AddWalls adds a TileWall around every TileFloor
-func (world *World) AddWalls() {
-	w, h, t := world.Width, world.Height, world.WallThickness
+// AddWalls adds a TileWall around every TileFloor, WallThickness thick or 1 thick if it's 0, then finishes the edge of
+// the map according to world.BorderStyle
+func (world *World) AddWalls(overrides ...Option) {
+	defer world.override(overrides)()
+	w, h := world.Width, world.Height
 	b := world.Border
 	world.Border = 0
 	for y := 0; y < h; y++ {
@@ -196,6 +509,7 @@ func (world *World) AddWalls() {
 			if tile, err := world.GetTile(x, y); err == nil {
 				switch tile {
 				case TileFloor:
+					t := wallGap(world.paramsAt(x, y).WallThickness)
 					for dx := -t; dx <= t; dx++ {
 						for dy := -t; dy <= t; dy++ {
 							if tile, err := world.GetTile(x+dx, y+dy); err == nil && tile == TileVoid {
@@ -209,6 +523,7 @@ func (world *World) AddWalls() {
 			}
 		}
 	}
+	world.applyBorderStyle()
 	world.Border = b
 }
 
@@ -274,7 +589,7 @@ func (world *World) CleanWalls(mustSurroundCount int) {
 		for x := 0; x < w; x++ {
 			if tile, err := world.GetTile(x, y); err == nil && tile == TileWall {
 				if world.countSurrounding(x, y, TileFloor) >= mustSurroundCount {
-					world.SetTile(x, y, TileFloor)
+					world.setFloor(x, y, FloorKindCave)
 				}
 			}
 		}
@@ -300,7 +615,7 @@ func (world *World) CleanIslands() {
 				// Remove island
 				if c < world.MinIslandSize {
 					for co := range m {
-						world.SetTile(co.X, co.Y, TileFloor)
+						world.setFloor(co.X, co.Y, FloorKindCave)
 					}
 				}
 			}
@@ -312,14 +627,27 @@ func (world *World) CleanIslands() {
 // The world will look chaotic yet natural and all tiles will be touching each other
 // world.Convexity, world.WallThickness and world.CorridorSize is used
 // Ensure that tileCount isn't too high or else world generation can take a while
-func (world *World) GenerateRandomWalk(tileCount int) error {
+// The open chambers of the cave are recorded in world.Rooms, see InferRooms
+func (world *World) GenerateRandomWalk(tileCount int, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("RandomWalk", 0)
+	defer func() { world.endReport(err) }()
+
 	world.genStartTime = time.Now()
 
 	w, h := world.Width, world.Height
+	// The walk can't reach the border or leave the map, and it needs room to turn
+	if space := (w - world.Border*2 - 2) * (h - world.Border*2 - 2); space < 4 || tileCount > space {
+		return ErrNotEnoughSpace
+	}
+	if world.MinCorridorSize > minInt(w, h)-world.Border*2-2 {
+		return ErrCorridorTooWide
+	}
 
 	var g func() error
 	g = func() error {
 		world.ResetWorld(world.Width, world.Height)
+		world.startTime = time.Now()
 		x, y := w/2, h/2
 		minX, maxX, minY, maxY := w, 0, h, 0
 		var dx, dy int
@@ -328,38 +656,29 @@ func (world *World) GenerateRandomWalk(tileCount int) error {
 			if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
 				return ErrGenerationTimeout
 			} else if time.Now().Sub(world.startTime) > world.DurationBeforeRetry {
-				if world.ShowErrorMessages {
-					log.Println("Timeout, retrying gen")
-				}
+				world.logf("Timeout, retrying gen")
+				world.Report.Retries++
 				return g()
 			}
 
-			switch rng.Int() % 8 {
-			case 0:
-				dx = -1
-				dy = 0
-			case 1:
-				dx = 1
-				dy = 0
-			case 2:
-				dx = 0
-				dy = -1
-			case 3:
-				dx = 0
-				dy = 1
-			default:
-				// use the same direction as last time
+			// Half of the time, use the same direction as last time
+			if r := world.rng.Int() % 8; r < 4 {
+				dirs := world.directions()
+				dir := dirs[r%len(dirs)]
+				dx, dy = dir.Dx(), dir.Dy()
 			}
 			x += dx
 			y += dy
+			world.heat(HeatmapVisits, x, y, 1)
 
-			cs := randInt(world.MinCorridorSize, world.MaxCorridorSize)
-			for tx := x - cs/2; tx < x+cs/2; tx++ {
-				for ty := y - cs/2; ty < y+cs/2; ty++ {
+			p := world.paramsAt(x, y)
+			cs := maxInt(world.randInt(p.MinCorridorSize, p.MaxCorridorSize), 1)
+			for tx := x - cs/2; tx < x-cs/2+cs; tx++ {
+				for ty := y - cs/2; ty < y-cs/2+cs; ty++ {
 					tc++
 					if tile, err := world.GetTile(tx, ty); err == nil && tile != TileVoid {
 						tc--
-					} else if world.SetTile(tx, ty, TileFloor) == ErrOutOfBounds {
+					} else if world.setFloor(tx, ty, FloorKindCave) == ErrOutOfBounds {
 						x = w / 2
 						y = h / 2
 						tc--
@@ -396,17 +715,22 @@ func (world *World) GenerateRandomWalk(tileCount int) error {
 			}
 		}
 	done:
-		if !convX {
-			if world.ShowErrorMessages {
-				log.Println("no convexity, retrying gen")
-			}
+		// Walks which are only a few dozen brushes long can't wander far enough to be convex, and would retry until
+		// they time out
+		if !convX && tileCount >= 32*world.MaxCorridorSize*world.MaxCorridorSize {
+			world.logf("no convexity, retrying gen")
+			world.Report.Retries++
 			return g()
 		}
 
 		return nil
 	}
 
-	return g()
+	if err = g(); err != nil {
+		return err
+	}
+	world.InferRooms()
+	return nil
 }
 
 // Rect is used for storing the x,y,w,h of a room or corridor
@@ -416,28 +740,86 @@ type Rect struct {
 }
 
 // GenerateDungeonGrid generates the world using the dungeon grid function
-// The world will look neat, with rooms aligned perfectly in a grid. world.MaxRoomWidth is used for both the width and
-// the height of the rooms as all rooms are the same size and shape.
-// world.WallThickness, world.MaxRoomWidth and world.CorridorSize and world.AllowRandomCorridorOffset are used
-func (world *World) GenerateDungeonGrid(roomCount int) error {
+// The world will look neat, with rooms aligned perfectly in a grid. Every room is the same size and shape, filling a
+// grid cell world.MaxRoomWidth wide and world.GridCellHeight tall, or square if GridCellHeight is 0.
+// world.WallThickness, world.MaxRoomWidth, world.GridCellHeight, world.CorridorSize and
+// world.AllowRandomCorridorOffset are used.
+// The number of rooms placed is returned, which can be less than roomCount as the walk can pass through a room twice
+// or box itself in.
+// ErrNotEnoughSpace is returned if not even one grid cell fits
+func (world *World) GenerateDungeonGrid(roomCount int, overrides ...Option) (placed int, err error) {
+	return world.generateDungeonGrid("DungeonGrid", roomCount, 0, overrides)
+}
+
+// GenerateDungeonGridFill generates the world like GenerateDungeonGrid, but keeps placing rooms until utilization
+// (0-1) of the grid's cells have a room instead of taking a fixed number of steps, for filling the map to a density.
+// The number of rooms placed is returned. ErrNotEnoughSpace is returned if utilization is out of range, not even one
+// grid cell fits or the walk boxes itself in before reaching it
+func (world *World) GenerateDungeonGridFill(utilization float64, overrides ...Option) (placed int, err error) {
+	if utilization <= 0 || utilization > 1 {
+		return 0, ErrNotEnoughSpace
+	}
+	return world.generateDungeonGrid("DungeonGridFill", 0, utilization, overrides)
+}
+
+// generateDungeonGrid walks the grid for roomCount steps and then on until fill (0-1) of the cells have rooms
+func (world *World) generateDungeonGrid(name string, roomCount int, fill float64, opts []Option) (placed int, err error) {
+	defer world.override(opts)()
+	world.beginReport(name, roomCount)
+	defer func() {
+		placed = len(world.Rooms)
+		world.endReport(err)
+	}()
+
 	world.genStartTime = time.Now()
 
-	s := world.MaxRoomWidth
-	mw := (world.Width-world.Border*2)/(s+world.WallThickness) + 1
-	mh := (world.Height-world.Border*2)/(s+world.WallThickness) + 1
+	sw, sh := world.MaxRoomWidth, world.GridCellHeight
+	if sh < 1 {
+		sh = sw
+	}
+	if sw < 1 {
+		return 0, ErrNotEnoughSpace
+	} else if world.MinCorridorSize > minInt(sw, sh) {
+		return 0, ErrCorridorTooWide
+	}
 
-	if world.ShowErrorMessages {
-		fmt.Printf("Max grid size is %d x %d, so max roomCount is %d. Use fewer rooms for a better result.\n", mw-1, mh-1, (mw-1)*(mh-1))
+	// The rooms are kept m in from the edge of the map, with a wall between each of them. Cells start at 1
+	wt := wallGap(world.WallThickness)
+	m := world.Border + wt
+	if world.FlushRooms {
+		m = maxInt(world.Border, wt)
+	}
+	mw := (world.Width-m*2+wt)/(sw+wt) + 1
+	mh := (world.Height-m*2+wt)/(sh+wt) + 1
+	if mw < 2 || mh < 2 {
+		return 0, ErrNotEnoughSpace
 	}
 
+	world.logf("Max grid size is %d x %d, so max roomCount is %d. Use fewer rooms for a better result.", mw-1, mh-1,
+		(mw-1)*(mh-1))
+
 	// if roomCount > (mw-2)*(mh-2) {
 	// 	return ErrNotEnoughSpace
 	// }
 
+	// cellRoom returns the room of a grid cell
+	cellRoom := func(cell Rect) Rect {
+		return Rect{
+			X: m + (cell.X-1)*(sw+wt),
+			Y: m + (cell.Y-1)*(sh+wt),
+			W: sw,
+			H: sh,
+		}
+	}
+
+	// target is how many cells need rooms to reach fill
+	target := int(math.Ceil(fill * float64((mw-1)*(mh-1))))
+
 	var g func() error
 	g = func() error {
 		world.ResetWorld(world.Width, world.Height)
 		sx, sy := int(mw/2), int(mh/2)
+		filled := 0
 		world.startTime = time.Now()
 		// Create rooms layout data structure
 		rooms := make([][]bool, mh)
@@ -446,25 +828,16 @@ func (world *World) GenerateDungeonGrid(roomCount int) error {
 		}
 
 		previousRooms := make([][]Rect, 1)
-		for rc := roomCount; rc > 0; rc-- {
+	walk:
+		for rc := roomCount; rc > 0 || filled < target; rc-- {
 			if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
 				return ErrGenerationTimeout
 			} else if time.Now().Sub(world.startTime) > world.DurationBeforeRetry {
-				if world.ShowErrorMessages {
-					log.Println("Timeout, retrying gen")
-				}
+				world.logf("Timeout, retrying gen")
+				world.Report.Retries++
 				return g()
 			}
-			switch rng.Int() % 4 {
-			case 0:
-				sx--
-			case 1:
-				sx++
-			case 2:
-				sy--
-			case 3:
-				sy++
-			}
+			sx, sy = world.Neighbor(sx, sy, world.randomDirection())
 
 			countAdj := func(iy, ix int) int {
 				var count int
@@ -483,13 +856,25 @@ func (world *World) GenerateDungeonGrid(roomCount int) error {
 				return count
 			}
 
-			if sx >= mw || sx <= 0 || sy >= mh || sy <= 0 || (countAdj(sy, sx) >= 2 && rooms[sy][sx]) {
+			// open returns whether the walk can step into a cell: it's on the grid and empty, or a room with fewer
+			// than 2 rooms beside it
+			open := func(x, y int) bool {
+				return x > 0 && x < mw && y > 0 && y < mh && (!rooms[y][x] || countAdj(y, x) < 2)
+			}
+
+			if !open(sx, sy) {
+				// Center of the cell which was rejected
+				rejected := cellRoom(Rect{X: sx, Y: sy})
+				world.heat(HeatmapRetries, rejected.X+sw/2, rejected.Y+sh/2, 1)
+				world.Report.Rollbacks++
 				rc++
+				// Rewind to a previous room which can be stepped out of and start a new chain from it. The room is the
+				// first entry of the new chain so the chain's next room is connected back to it
 				for l := 0; l < len(previousRooms); l++ {
 					for i := 0; i < len(previousRooms[l]); i++ { // start from beginning
 						roomCoord := previousRooms[l][i]
-						rc := countAdj(roomCoord.Y, roomCoord.X)
-						if rc >= 0 && rc <= 2 {
+						x, y := roomCoord.X, roomCoord.Y
+						if countAdj(y, x) <= 2 && (open(x-1, y) || open(x+1, y) || open(x, y-1) || open(x, y+1)) {
 							sx = roomCoord.X
 							sy = roomCoord.Y
 							previousRooms = append(previousRooms, make([]Rect, 0))
@@ -497,10 +882,17 @@ func (world *World) GenerateDungeonGrid(roomCount int) error {
 						}
 					}
 				}
+				// Boxed in, keep the rooms so far unless they're short of the fill
+				if filled >= target {
+					break walk
+				}
 				return ErrNotEnoughSpace
 			}
 		good:
 			// Append room coord for rewinding purposes
+			if !rooms[sy][sx] {
+				filled++
+			}
 			rooms[sy][sx] = true
 			previousRooms[len(previousRooms)-1] = append(previousRooms[len(previousRooms)-1], Rect{X: sx, Y: sy})
 		}
@@ -508,19 +900,13 @@ func (world *World) GenerateDungeonGrid(roomCount int) error {
 		for pr := 0; pr < len(previousRooms); pr++ {
 			// log.Println(previousRooms[pr])
 			for i, cur := range previousRooms[pr] {
-				sy, sx = cur.Y, cur.X
-				room := Rect{
-					X: sx*s + sx*world.WallThickness - world.MaxRoomWidth,
-					Y: sy*s + sy*world.WallThickness - world.MaxRoomWidth,
-					W: world.MaxRoomWidth,
-					H: world.MaxRoomWidth,
-				}
-				world.Rooms[room] = struct{}{}
+				room := cellRoom(cur)
+				world.addRoom(room)
 
 				// Fill in the world's tiles with the room
 				for dx := room.X; dx < room.X+room.W; dx++ {
 					for dy := room.Y; dy < room.Y+room.H; dy++ {
-						world.SetTile(dx, dy, TileFloor)
+						world.setFloor(dx, dy, FloorKindRoom)
 					}
 				}
 
@@ -528,248 +914,348 @@ func (world *World) GenerateDungeonGrid(roomCount int) error {
 					continue
 				}
 
-				// Corridors
+				// Corridors are centered on the side the rooms share, the wall between them is bridged. Corridors
+				// between rooms side by side run along the height of the cell, and the width between rooms above and
+				// below each other
 				prev := previousRooms[pr][i-1]
-				dx, dy := cur.X-prev.X, cur.Y-prev.Y
-				x1 := prev.X*s - world.MaxRoomWidth/2
-				x2 := cur.X*s - world.MaxRoomWidth/2
-				y1 := prev.Y*s - world.MaxRoomWidth/2
-				y2 := cur.Y*s - world.MaxRoomWidth/2
+				prevRoom := cellRoom(prev)
+				cs := world.randInt(world.MinCorridorSize, world.MaxCorridorSize)
+				var corridor Rect
 				cd := DoorDirectionHorizontal
-				cs := randInt(world.MinCorridorSize, world.MaxCorridorSize)
-				var offsetCy, offsetCx int
-				if world.AllowRandomCorridorOffset {
-					offsetCy = (world.MaxRoomWidth - cs)
-					offsetCy = randInt(-offsetCy/2, offsetCy/2)
-					offsetCx = (world.MaxRoomWidth - cs)
-					offsetCx = randInt(-offsetCx/2, offsetCx/2)
-				}
-				switch {
-				case dx == -1: // left
-					x1 = x2 + world.MaxRoomWidth/2 + world.MaxRoomWidth%2
-					x2 = x1 + world.WallThickness
-					y1 = y1 - cs/2 - offsetCy
-					y2 = y2 + cs/2 + cs%2 - offsetCy
-					cd = DoorDirectionVertical
-				case dx == 1:
-					x1 = x2 - world.MaxRoomWidth/2 - world.WallThickness
-					x2 = x1 + world.WallThickness
-					y1 = y1 - cs/2 - offsetCy
-					y2 = y2 + cs/2 + cs%2 - offsetCy
+				switch dx, dy := cur.X-prev.X, cur.Y-prev.Y; {
+				case dx == -1 || dx == 1:
+					cs = minInt(cs, sh)
+					var offset int
+					if world.AllowRandomCorridorOffset {
+						offset = world.randInt(-(sh-cs)/2, (sh-cs)/2)
+					}
+					left := minInt(room.X, prevRoom.X)
+					corridor = Rect{X: left + sw, Y: centered(room.Y, sh, cs) - offset, W: wt, H: cs}
 					cd = DoorDirectionVertical
-				case dy == -1:
-					y1 = y2 + world.MaxRoomWidth/2 + world.MaxRoomWidth%2
-					y2 = y1 + world.WallThickness
-					x1 = x1 - cs/2 - offsetCx
-					x2 = x2 + cs/2 + cs%2 - offsetCx
-				case dy == 1:
-					y1 = y2 - world.MaxRoomWidth/2 - world.WallThickness
-					y2 = y1 + world.WallThickness
-					x1 = x1 - cs/2 - offsetCx
-					x2 = x2 + cs/2 + cs%2 - offsetCx
-				default:
-					if world.ShowErrorMessages {
-						log.Println("somehow, dx,dy > abs 1", cur, prev, dx, dy)
+				case dy == -1 || dy == 1:
+					cs = minInt(cs, sw)
+					var offset int
+					if world.AllowRandomCorridorOffset {
+						offset = world.randInt(-(sw-cs)/2, (sw-cs)/2)
 					}
+					top := minInt(room.Y, prevRoom.Y)
+					corridor = Rect{X: centered(room.X, sw, cs) - offset, Y: top + sh, W: cs, H: wt}
+				default:
+					world.logf("somehow, dx,dy > abs 1 %v %v %d %d", cur, prev, dx, dy)
+					continue
 				}
 
-				cx := Rect{
-					X: x1 + sx*world.WallThickness,
-					Y: y1 + sy*world.WallThickness,
-					W: x2 - x1,
-					H: y2 - y1,
-				}
-				if world.WallThickness > 1 {
-					switch cd {
-					case DoorDirectionHorizontal:
-						cx.H = 1
-						cx.Y += (world.WallThickness/2 + world.WallThickness%2) - 1
-					case DoorDirectionVertical:
-						cx.W = 1
-						cx.X += (world.WallThickness/2 + world.WallThickness%2) - 1
+				for x := corridor.X; x < corridor.X+corridor.W; x++ {
+					for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+						world.setFloor(x, y, FloorKindCorridor)
 					}
 				}
-				world.Doors[cx] = cd
-				for x := x1; x < x2; x++ {
-					for y := y1; y < y2; y++ {
-						world.SetTile(x+sx*world.WallThickness, y+sy*world.WallThickness, TileFloor)
+				world.addDoorway(corridor, cd)
+			}
+		}
+		return nil
+	}
+	return 0, g()
+}
+
+// roomSeparation returns how far apart rooms with walls wt thick are placed
+func (world *World) roomSeparation(wt int) int {
+	return maxInt(wallGap(wt), world.MinRoomSeparation)
+}
+
+// wallGap returns how far apart floors with walls wt thick are kept. Walls 0 thick still leave a gap of 1 for a single
+// wall shared by the floors either side, which AddWalls fills in
+func wallGap(wt int) int {
+	return maxInt(wt, 1)
+}
+
+// edgeMargin returns how close the floor of a room with walls wt thick can be to the edge of the map: world.Border and
+// the room separation inside of it, or if world.FlushRooms is set just far enough in for its walls, which can be in
+// the border
+func (world *World) edgeMargin(wt int) int {
+	if world.FlushRooms {
+		return maxInt(world.Border, wallGap(wt))
+	}
+	return world.Border + world.roomSeparation(wt)
+}
+
+// checkRoom returns an error if a room (plus its walls wt thick and separation) can't be placed at x,y
+func (world *World) checkRoom(x, y, w, h, wt int) error {
+	sep := world.roomSeparation(wt)
+	m := world.edgeMargin(wt)
+	if x < m || y < m || x+w > world.Width-m || y+h > world.Height-m {
+		return ErrOutOfBounds
+	}
+	if world.index != nil {
+		if world.index.hasFloor(world, Rect{X: x - sep, Y: y - sep, W: w + sep*2, H: h + sep*2}) {
+			return ErrFloorAlreadyPlaced
+		}
+		return nil
+	}
+	for dx := maxInt(x-sep, 0); dx < minInt(x+w+sep, world.Width); dx++ {
+		for dy := maxInt(y-sep, 0); dy < minInt(y+h+sep, world.Height); dy++ {
+			if world.Tiles[dy][dx] == TileFloor {
+				return ErrFloorAlreadyPlaced
+			}
+		}
+	}
+	return nil
+}
+
+// placeRoom places a room surrounded by TilePreWall wt thick and adds it to world.Rooms
+func (world *World) placeRoom(x, y, w, h, wt int) error {
+	// Check area
+	if err := world.checkRoom(x, y, w, h, wt); err != nil {
+		return err
+	}
+	if err := world.digRoom(x, y, w, h, wt); err != nil {
+		return err
+	}
+	// Set world.Rooms
+	room := Rect{
+		X: x,
+		Y: y,
+		W: w,
+		H: h,
+	}
+	world.addRoom(room)
+	return nil
+}
+
+// digRoom places the floor of a room surrounded by TilePreWall wt thick, the walls only replace TileVoid
+func (world *World) digRoom(x, y, w, h, wt int) error {
+	for dx := x - wt; dx < x+w+wt; dx++ {
+		for dy := y - wt; dy < y+h+wt; dy++ {
+			if dx < x || dx > x+w-1 || dy < y || dy > y+h-1 {
+				// Temp wall
+				if tile, err := world.GetTile(dx, dy); err == nil && tile == TileVoid {
+					if err := world.SetTile(dx, dy, TilePreWall); err != nil {
+						return err
 					}
 				}
+			} else {
+				// Floor
+				if err := world.setFloor(dx, dy, FloorKindRoom); err != nil {
+					return err
+				}
 			}
 		}
-		return nil
 	}
-	return g()
+	return nil
 }
 
 // GenerateDungeon generates the world using a more fluid algorithm
 // The world will have randomly sized rooms
-// world.WallThickness, world.MinRoomWidth|Height, world.MaxRoomWidth|Height, world.CorridorSize and
-// world.AllowRandomCorridorOffset are used
-func (world *World) GenerateDungeon(roomCount int) error {
+// world.WallThickness, world.MinRoomWidth|Height, world.MaxRoomWidth|Height, world.CorridorSize,
+// world.AllowRandomCorridorOffset, world.MinRoomSeparation and world.RoomOverlap are used.
+// With world.RoomOverlap, some rooms are merged into the room before them instead of being joined by a corridor,
+// making compound rooms for messier interiors. Only the first room of each compound is in world.Rooms, the others are
+// in world.RoomParts.
+// The number of rooms placed is returned, it's only less than roomCount if an error is returned too or rooms were
+// merged
+func (world *World) GenerateDungeon(roomCount int, overrides ...Option) (placed int, err error) {
+	defer world.override(overrides)()
+	world.beginReport("Dungeon", roomCount)
+	defer func() {
+		placed = len(world.Rooms)
+		world.endReport(err)
+	}()
+
 	world.genStartTime = time.Now()
 
+	if world.MaxRoomWidth < 1 || world.MinRoomWidth < 1 || world.MinRoomHeight < 1 {
+		return 0, ErrNotEnoughSpace
+	} else if world.MinCorridorSize > minInt(world.MinRoomWidth, world.MinRoomHeight) {
+		return 0, ErrCorridorTooWide
+	}
+
 	s := world.MaxRoomWidth
 	mw := (world.Width - world.Border*2) / s
 	mh := (world.Height - world.Border*2) / s
 
-	if roomCount > (mw-2)*(mh-2) {
-		return ErrNotEnoughSpace
+	if mw < 3 || mh < 3 || roomCount > (mw-2)*(mh-2) {
+		return 0, ErrNotEnoughSpace
 	}
 
 	var g func() error
 	g = func() error {
 		world.ResetWorld(world.Width, world.Height)
 		world.startTime = time.Now()
-		// Helper func to place rooms
-		placeRoom := func(x, y, w, h int) error {
-			// Check area
-			for dx := x - world.WallThickness; dx < x+w+world.WallThickness; dx++ {
-				for dy := y - world.WallThickness; dy < y+h+world.WallThickness; dy++ {
-					if tile, err := world.GetTile(dx, dy); err == nil && tile == TileFloor {
-						return ErrFloorAlreadyPlaced
-					} else if err != nil {
-						return err
-					}
-				}
-			}
-			// Place
-			for dx := x - world.WallThickness; dx < x+w+world.WallThickness; dx++ {
-				for dy := y - world.WallThickness; dy < y+h+world.WallThickness; dy++ {
-					if dx < x || dx > x+w-1 || dy < y || dy > y+h-1 {
-						// Temp wall
-						if tile, err := world.GetTile(dx, dy); err == nil && tile == TileVoid {
-							if err := world.SetTile(dx, dy, TilePreWall); err != nil {
-								return err
-							}
-						}
-					} else {
-						// Floor
-						if err := world.SetTile(dx, dy, TileFloor); err != nil {
-							return err
-						}
-					}
-				}
-			}
-			// Set world.Rooms
-			world.Rooms[Rect{
-				X: x,
-				Y: y,
-				W: w,
-				H: h,
-			}] = struct{}{}
-			return nil
-		}
 
 		// Random first room size
 		sx, sy := world.Width/2, world.Height/2
-		rw := randInt(world.MinRoomWidth, world.MaxRoomWidth)
-		rh := randInt(world.MinRoomHeight, world.MaxRoomHeight)
+		p := world.paramsAt(sx, sy)
+		rw := world.randInt(p.MinRoomWidth, p.MaxRoomWidth)
+		rh := world.randInt(p.MinRoomHeight, p.MaxRoomHeight)
 
 		// Place the first room into the world
-		placeRoom(sx, sy, rw, rh)
+		world.placeRoom(sx, sy, rw, rh, p.WallThickness)
 
 		previousRooms := make([]Rect, 0)
 		previousRooms = append(previousRooms, Rect{X: sx, Y: sy, W: rw, H: rh})
 
-		for rc := roomCount - 1; rc > 0; rc-- {
-			if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
-				return ErrGenerationTimeout
-			} else if time.Now().Sub(world.startTime) > world.DurationBeforeRetry {
-				if world.ShowErrorMessages {
-					log.Println("Timeout, retrying gen")
-				}
-				return g()
-			}
+		return world.growDungeon(previousRooms, roomCount-1, g)
+	}
+	return 0, g()
+}
+
+// Expand continues GenerateDungeon from the existing layout, attaching roomCount new rooms to the frontier rooms
+// (rooms which still have space next to them). It can be called multiple times to grow the dungeon while the player
+// explores it. Rooms placed before a timeout are kept, call AddWalls afterwards to wall in the new rooms.
+// The number of new rooms is returned, even if there's an error
+func (world *World) Expand(roomCount int, overrides ...Option) (placed int, err error) {
+	defer world.override(overrides)()
+	world.beginReport("Expand", roomCount)
+	before := len(world.Rooms)
+	defer func() {
+		placed = len(world.Rooms) - before
+		world.endReport(err)
+	}()
+
+	world.genStartTime = time.Now()
+	world.startTime = world.genStartTime
+
+	if len(world.Rooms) == 0 {
+		return 0, ErrNoRooms
+	}
+	if world.MinCorridorSize > minInt(world.MinRoomWidth, world.MinRoomHeight) {
+		return 0, ErrCorridorTooWide
+	}
+
+	frontier := world.frontierRooms()
+	if len(frontier) == 0 {
+		return 0, ErrNotEnoughSpace
+	}
+
+	return 0, world.growDungeon(frontier, roomCount, nil)
+}
+
+// frontierRooms returns the rooms which have enough space on at least one side for another room
+func (world *World) frontierRooms() []Rect {
+	frontier := make([]Rect, 0)
+	for _, room := range world.RoomsOrdered() {
+		p := world.paramsAt(room.X+room.W/2, room.Y+room.H/2)
+		t := world.roomSeparation(p.WallThickness)
+		rw, rh, wt := p.MinRoomWidth, p.MinRoomHeight, p.WallThickness
+		// The room's own walls count as floor-free, so probe just outside of them
+		if world.checkRoom(room.X-t-rw, room.Y, rw, rh, wt) == nil ||
+			world.checkRoom(room.X+room.W+t, room.Y, rw, rh, wt) == nil ||
+			world.checkRoom(room.X, room.Y-t-rh, rw, rh, wt) == nil ||
+			world.checkRoom(room.X, room.Y+room.H+t, rw, rh, wt) == nil {
+			frontier = append(frontier, room)
+		}
+	}
+	return frontier
+}
 
-			// Offset position by last room
-			osx := sx
-			osy := sy
-			orw := rw
-			orh := rh
-			rw = randInt(world.MinRoomWidth, world.MaxRoomWidth)
-			rh = randInt(world.MinRoomHeight, world.MaxRoomHeight)
-			cx, cy := osx, osy // corridor position
-			cs := randInt(world.MinCorridorSize, world.MaxCorridorSize)
-			var cw, ch int
-			var offsetCy, offsetCx int
+// growDungeon attaches roomCount rooms to previousRooms, starting from a random room in previousRooms
+// retry is called when world.DurationBeforeRetry is exceeded, if it's nil generation continues until
+// world.DurationBeforeError is exceeded
+func (world *World) growDungeon(previousRooms []Rect, roomCount int, retry func() error) error {
+	if world.index == nil {
+		world.index = newSpatialIndex(world)
+		defer func() { world.index = nil }()
+	}
+
+	c := previousRooms[world.rng.Int()%len(previousRooms)]
+	sx, sy, rw, rh := c.X, c.Y, c.W, c.H
+	// rollback starts again from a random room after a room couldn't be placed
+	rollback := func(err error) {
+		world.logf("rollback: %v %d %d %d %d", err, sx, sy, rw, rh)
+		world.heat(HeatmapRetries, sx+rw/2, sy+rh/2, 1)
+		world.Report.Rollbacks++
+		c := previousRooms[world.rng.Int()%len(previousRooms)]
+		sx, sy, rw, rh = c.X, c.Y, c.W, c.H
+	}
+
+	for rc := roomCount; rc > 0; rc-- {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		} else if retry != nil && time.Now().Sub(world.startTime) > world.DurationBeforeRetry {
+			world.logf("Timeout, retrying gen")
+			world.Report.Retries++
+			return retry()
+		}
+
+		// Offset position by last room
+		osx := sx
+		osy := sy
+		orw := rw
+		orh := rh
+		// The new room is generated with the parameters of the zone the last room is in
+		p := world.paramsAt(osx+orw/2, osy+orh/2)
+		sep := world.roomSeparation(p.WallThickness)
+		rw = world.randInt(p.MinRoomWidth, p.MaxRoomWidth)
+		rh = world.randInt(p.MinRoomHeight, p.MaxRoomHeight)
+		cx, cy := osx, osy // corridor position
+		cs := world.randInt(p.MinCorridorSize, p.MaxCorridorSize)
+		var cw, ch int
+		// offset returns where the corridor starts along the side the rooms share, which is shared long on one room
+		// and longer on the other. The corridor is centered on it unless world.AllowRandomCorridorOffset is set
+		offset := func(shared, longer int) int {
+			cs = minInt(cs, shared)
 			if world.AllowRandomCorridorOffset {
-				offsetCy = (minInt(rh, orh) - ch)
-				offsetCy = randInt(-cs/2, offsetCy/2-cs/2)
-				offsetCx = (minInt(rw, orw) - cw)
-				offsetCx = randInt(-cs/2, offsetCx/2-cs/2)
+				return world.randInt(0, shared-cs)
 			}
-			cd := DoorDirectionHorizontal
-			switch rng.Int() % 4 {
-			case 0: // left
-				cw = world.WallThickness
-				ch = cs
-				sx = sx - world.WallThickness - rw
-				cx = sx + rw
-				cy = cy + (ch / 2) + offsetCy
-				cd = DoorDirectionVertical
-			case 1: // right
-				cw = world.WallThickness
-				ch = cs
-				sx = sx + orw + world.WallThickness
-				cx = sx - world.WallThickness
-				cy = cy + (ch / 2) + offsetCy
-				cd = DoorDirectionVertical
-			case 2: // up
-				cw = cs
-				ch = world.WallThickness
-				sy = sy - world.WallThickness - rh
-				cy = sy + rh
-				cx = cx + (cw / 2) + offsetCx
-			case 3: // down
-				cw = cs
-				ch = world.WallThickness
-				sy = sy + orh + world.WallThickness
-				cy = sy - world.WallThickness
-				cx = cx + (cw / 2) + offsetCx
-			}
-
-			if err := placeRoom(sx, sy, rw, rh); err != nil {
-				if world.ShowErrorMessages {
-					log.Println("rollback:", err, sx, sy, rw, rh)
-				}
-				c := previousRooms[rng.Int()%len(previousRooms)]
-				sx = c.X
-				sy = c.Y
-				rw = c.W
-				rh = c.H
+			return centerLine(shared, longer, cs)
+		}
+		cd := DoorDirectionHorizontal
+		dir := world.corridorDirection()
+
+		// Merge the new room into the last one to make a compound room
+		if world.RoomOverlap > 0 && world.rng.Float64() < world.RoomOverlap && minInt(rw, orw) > 1 && minInt(rh, orh) > 1 {
+			part := world.overlappingRoom(Rect{X: osx, Y: osy, W: orw, H: orh}, rw, rh, dir)
+			sx, sy = part.X, part.Y
+			if err := world.placeRoomPart(part, p.WallThickness); err != nil {
+				rollback(err)
 				rc++
 				continue
 			}
+			previousRooms = append(previousRooms, part)
+			continue
+		}
 
-			// Corridors
-			door := Rect{
-				X: cx,
-				Y: cy,
-				W: cw,
-				H: ch,
-			}
-			if world.WallThickness > 1 {
-				switch cd {
-				case DoorDirectionHorizontal:
-					door.H = 1
-					door.Y += (world.WallThickness/2 + world.WallThickness%2) - 1
-				case DoorDirectionVertical:
-					door.W = 1
-					door.X += (world.WallThickness/2 + world.WallThickness%2) - 1
-				}
-			}
-			world.Doors[door] = cd
-			for x := cx; x < cx+cw; x++ {
-				for y := cy; y < cy+ch; y++ {
-					world.SetTile(x, y, TileFloor)
-				}
-			}
+		switch dir {
+		case DirectionWest:
+			sx = sx - sep - rw
+			cx = sx + rw
+			cy = cy + offset(minInt(rh, orh), maxInt(rh, orh))
+			cw, ch = sep, cs
+			cd = DoorDirectionVertical
+		case DirectionEast:
+			sx = sx + orw + sep
+			cx = sx - sep
+			cy = cy + offset(minInt(rh, orh), maxInt(rh, orh))
+			cw, ch = sep, cs
+			cd = DoorDirectionVertical
+		case DirectionNorth:
+			sy = sy - sep - rh
+			cy = sy + rh
+			cx = cx + offset(minInt(rw, orw), maxInt(rw, orw))
+			cw, ch = cs, sep
+		case DirectionSouth:
+			sy = sy + orh + sep
+			cy = sy - sep
+			cx = cx + offset(minInt(rw, orw), maxInt(rw, orw))
+			cw, ch = cs, sep
+		}
 
-			previousRooms = append(previousRooms, Rect{X: sx, Y: sy, W: rw, H: rh})
+		if err := world.placeRoom(sx, sy, rw, rh, p.WallThickness); err != nil {
+			rollback(err)
+			rc++
+			continue
 		}
 
-		return nil
+		// Corridors
+		for x := cx; x < cx+cw; x++ {
+			for y := cy; y < cy+ch; y++ {
+				world.setFloor(x, y, FloorKindCorridor)
+			}
+		}
+		world.addDoorway(Rect{X: cx, Y: cy, W: cw, H: ch}, cd)
+
+		previousRooms = append(previousRooms, Rect{X: sx, Y: sy, W: rw, H: rh})
 	}
-	return g()
+
+	return nil
 }
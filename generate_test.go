@@ -0,0 +1,693 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// connectedGenerators each generate a map whose floor can all be walked to once its walls are added. The grid
+// generators rewind to earlier rooms when the walk gets stuck, so they also check the rewound chains are joined on
+var connectedGenerators = []struct {
+	name string
+	gen  func(world *World) error
+}{
+	{"DungeonGrid", func(world *World) error {
+		_, err := world.GenerateDungeonGrid(12)
+		return err
+	}},
+	{"DungeonGridFill", func(world *World) error {
+		_, err := world.GenerateDungeonGridFill(0.9)
+		return err
+	}},
+	{"Dungeon", func(world *World) error {
+		_, err := world.GenerateDungeon(12)
+		return err
+	}},
+	{"BSP", func(world *World) error {
+		return world.GenerateBSP(4)
+	}},
+	{"RoomGrowth", func(world *World) error {
+		return world.GenerateRoomGrowth(8, 0.2)
+	}},
+	{"RandomWalk", func(world *World) error {
+		return world.GenerateRandomWalk(600)
+	}},
+	{"Maze", func(world *World) error {
+		return world.GenerateMaze()
+	}},
+	{"MazePrim", func(world *World) error {
+		return world.GenerateMazePrim()
+	}},
+	{"MazeWilson", func(world *World) error {
+		return world.GenerateMazeWilson()
+	}},
+	{"Catacombs", func(world *World) error {
+		return world.GenerateCatacombs(6, DefaultCatacombOptions())
+	}},
+	{"SurfaceEntrance", func(world *World) error {
+		return world.GenerateSurfaceEntrance(Direction(world.Seed%4), 8, 600)
+	}},
+}
+
+// TestGeneratorsConnected generates a range of seeds with each generator and checks every map passes Validate
+func TestGeneratorsConnected(t *testing.T) {
+	const seeds = 30
+	for _, g := range connectedGenerators {
+		g := g
+		t.Run(g.name, func(t *testing.T) {
+			for seed := int64(1); seed <= seeds; seed++ {
+				world := NewWorldWithSeed(64, 48, seed)
+				if err := g.gen(world); err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				world.AddWalls()
+				if err := world.Validate(); err != nil {
+					t.Errorf("seed %d: %v", seed, err)
+				}
+			}
+		})
+	}
+}
+
+// TestFixDoorsCompletable checks FixDoors never cuts a room off from the rest, whether or not it can move the doors
+func TestFixDoorsCompletable(t *testing.T) {
+	for _, g := range connectedGenerators {
+		g := g
+		t.Run(g.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 10; seed++ {
+				world := NewWorldWithSeed(64, 48, seed)
+				if err := g.gen(world); err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				world.AddWalls()
+				if len(world.Rooms) == 0 {
+					continue
+				}
+				start := world.RoomsOrdered()[0]
+				if !world.Completable(start) {
+					t.Fatalf("seed %d: not completable before FixDoors", seed)
+				}
+				world.FixDoors()
+				if !world.Completable(start) {
+					t.Errorf("seed %d: not completable after FixDoors", seed)
+				}
+			}
+		})
+	}
+}
+
+// TestLinkDoors checks the doors of corridors routed between rooms are clear on both sides and held between walls
+func TestLinkDoors(t *testing.T) {
+	g := RoomGraph{Nodes: make([]RoomNode, 8)}
+	for i := 1; i < len(g.Nodes); i++ {
+		g.Links = append(g.Links, RoomLink{From: (i - 1) / 2, To: i})
+	}
+	g.Links = append(g.Links, RoomLink{From: 3, To: 6}, RoomLink{From: 7, To: 0})
+	generators := map[string]func(world *World) error{
+		"BSP": func(world *World) error {
+			return world.GenerateBSP(4)
+		},
+		"FromGraph": func(world *World) error {
+			_, err := world.GenerateFromGraph(g)
+			return err
+		},
+	}
+	for name, gen := range generators {
+		for seed := int64(1); seed <= 20; seed++ {
+			world := NewWorldWithSeed(64, 48, seed)
+			if err := gen(world); err != nil {
+				t.Fatalf("%s seed %d: %v", name, seed, err)
+			}
+			world.AddWalls()
+			for _, v := range world.CheckDoors() {
+				t.Errorf("%s seed %d: door %v blocked %v exposed %v", name, seed, v.Door, v.Blocked, v.Exposed)
+			}
+		}
+	}
+}
+
+// TestScale checks scaled copies of generated maps are still valid, with narrow corridors kept connected when scaling
+// down and rooms left whole when smoothing
+func TestScale(t *testing.T) {
+	for _, g := range connectedGenerators {
+		g := g
+		t.Run(g.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				world := NewWorldWithSeed(64, 48, seed)
+				if err := g.gen(world); err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				world.AddWalls()
+				for _, factor := range []int{2, 3} {
+					down, err := world.Downscale(factor)
+					if err != nil {
+						t.Fatalf("seed %d: Downscale(%d): %v", seed, factor, err)
+					}
+					if err := down.Validate(); err != nil {
+						t.Errorf("seed %d: Downscale(%d): %v", seed, factor, err)
+					}
+					for _, smooth := range []bool{false, true} {
+						up, err := world.Upscale(factor, smooth)
+						if err != nil {
+							t.Fatalf("seed %d: Upscale(%d, %v): %v", seed, factor, smooth, err)
+						}
+						if err := up.Validate(); err != nil {
+							t.Errorf("seed %d: Upscale(%d, %v): %v", seed, factor, smooth, err)
+						}
+						if len(up.Rooms) != len(world.Rooms) {
+							t.Errorf("seed %d: Upscale(%d, %v) has %d rooms, want %d", seed, factor, smooth,
+								len(up.Rooms), len(world.Rooms))
+						}
+					}
+				}
+			}
+		})
+	}
+
+	world := NewWorldWithSeed(16, 16, 1)
+	if _, err := world.Downscale(0); !errors.Is(err, ErrInvalidFactor) {
+		t.Errorf("Downscale(0): got error %v, want %v", err, ErrInvalidFactor)
+	}
+	if _, err := world.Upscale(0, false); !errors.Is(err, ErrInvalidFactor) {
+		t.Errorf("Upscale(0): got error %v, want %v", err, ErrInvalidFactor)
+	}
+}
+
+// TestTinyWorlds generates worlds too small for most generators, which should return ErrNotEnoughSpace rather than
+// panic, spin until they time out or leave the map empty. Worlds which are only small for some generators may succeed
+// as long as there's somewhere to walk
+func TestTinyWorlds(t *testing.T) {
+	generators := append(connectedGenerators[:len(connectedGenerators):len(connectedGenerators)], []struct {
+		name string
+		gen  func(world *World) error
+	}{
+		{"Arena", func(world *World) error {
+			return world.GenerateArena(DefaultArenaOptions())
+		}},
+		{"Archipelago", func(world *World) error {
+			return world.GenerateArchipelago(DefaultArchipelagoOptions())
+		}},
+		{"Tower", func(world *World) error {
+			_, err := world.GenerateTower(DefaultTowerOptions())
+			return err
+		}},
+	}...)
+
+	tests := []struct {
+		name          string
+		width, height int
+		opts          []Option
+		want          error // nil if the world may fit
+	}{
+		{"1x1", 1, 1, nil, ErrNotEnoughSpace},
+		{"3x3", 3, 3, nil, ErrNotEnoughSpace},
+		{"6x6", 6, 6, nil, ErrNotEnoughSpace},
+		{"40x1", 40, 1, nil, ErrNotEnoughSpace},
+		{"1x40", 1, 40, nil, ErrNotEnoughSpace},
+		{"10x10", 10, 10, nil, nil},
+		{"corridors as wide as rooms", 40, 40, []Option{WithCorridorSize(8), WithRoomSize(4, 4, 8, 8)}, nil},
+		{"corridors wider than rooms", 40, 40, []Option{WithCorridorSize(9), WithRoomSize(4, 4, 8, 8)}, nil},
+	}
+	for _, tt := range tests {
+		for _, g := range generators {
+			tt, g := tt, g
+			t.Run(tt.name+"/"+g.name, func(t *testing.T) {
+				world := NewWorldWithSeed(tt.width, tt.height, 1)
+				cfg := world.config()
+				for _, opt := range tt.opts {
+					opt(&cfg)
+				}
+				cfg.apply(world)
+				err := g.gen(world)
+				if world.Report.Retries > 0 {
+					t.Errorf("retried %d times after timing out", world.Report.Retries)
+				}
+				switch {
+				case tt.want != nil && !errors.Is(err, tt.want):
+					t.Errorf("got error %v, want %v", err, tt.want)
+				case err == nil && len(world.largestArea()) == 0:
+					t.Errorf("no error but nothing to walk on")
+				}
+			})
+		}
+	}
+}
+
+// TestCorridorsTooWide checks the generators which fit corridors between rooms refuse corridors wider than the rooms
+func TestCorridorsTooWide(t *testing.T) {
+	opts := []Option{WithCorridorSize(9), WithRoomSize(4, 4, 8, 8)}
+	generators := map[string]func(world *World) error{
+		"DungeonGrid": func(world *World) error {
+			_, err := world.GenerateDungeonGrid(12, opts...)
+			return err
+		},
+		"DungeonGridFill": func(world *World) error {
+			_, err := world.GenerateDungeonGridFill(0.5, opts...)
+			return err
+		},
+		"Dungeon": func(world *World) error {
+			_, err := world.GenerateDungeon(12, opts...)
+			return err
+		},
+	}
+	for name, gen := range generators {
+		if err := gen(NewWorldWithSeed(40, 40, 1)); !errors.Is(err, ErrCorridorTooWide) {
+			t.Errorf("%s: got error %v, want %v", name, err, ErrCorridorTooWide)
+		}
+	}
+}
+
+// TestCentered checks every parity of span and size, with the extra tile going after when they can't be centered
+func TestCentered(t *testing.T) {
+	tests := []struct {
+		start, length, size int
+		want                int
+	}{
+		{0, 5, 1, 2}, // odd span, odd size
+		{0, 5, 3, 1},
+		{0, 5, 2, 1}, // odd span, even size
+		{0, 5, 4, 0},
+		{0, 6, 2, 2}, // even span, even size
+		{0, 6, 4, 1},
+		{0, 6, 1, 2}, // even span, odd size
+		{0, 6, 3, 1},
+		{3, 5, 1, 5}, // offset
+		{3, 6, 3, 4},
+		{0, 4, 4, 0}, // as long as the span
+		{0, 3, 3, 0},
+	}
+	for _, tt := range tests {
+		if got := centered(tt.start, tt.length, tt.size); got != tt.want {
+			t.Errorf("centered(%d, %d, %d) = %d, want %d", tt.start, tt.length, tt.size, got, tt.want)
+		}
+	}
+
+	for length := 1; length <= 9; length++ {
+		for size := 1; size <= length; size++ {
+			before := centered(0, length, size)
+			if after := length - size - before; after != before && after != before+1 {
+				t.Errorf("centered(0, %d, %d) leaves %d before and %d after", length, size, before, after)
+			}
+		}
+	}
+}
+
+// TestCenterLine checks corridors on a side which is even on one room and odd on the other line up with the middle
+// of the odd room when they're odd too, and are centered on the shared side otherwise
+func TestCenterLine(t *testing.T) {
+	tests := []struct {
+		length, longer, size int
+		want                 int
+	}{
+		{6, 7, 1, 3}, // odd corridor lines up with the 7 long room's middle tile
+		{6, 7, 3, 2},
+		{6, 9, 3, 3},
+		{6, 11, 3, 1}, // the middle of the longer room is out of reach, so it falls back to centering
+		{6, 7, 2, 2},  // even corridors can't line up with a middle tile
+		{6, 8, 1, 2},  // both rooms even
+		{5, 7, 1, 2},  // shared side odd, already centered
+		{5, 6, 3, 1},
+	}
+	for _, tt := range tests {
+		got := centerLine(tt.length, tt.longer, tt.size)
+		if got != tt.want {
+			t.Errorf("centerLine(%d, %d, %d) = %d, want %d", tt.length, tt.longer, tt.size, got, tt.want)
+		}
+		if got < 0 || got+tt.size > tt.length {
+			t.Errorf("centerLine(%d, %d, %d) = %d is outside the shared side", tt.length, tt.longer, tt.size, got)
+		}
+	}
+}
+
+// TestGridCorridorParity generates grids with odd and even cells and corridors, checking every corridor is as wide
+// as asked and centered on the side of the rooms it joins
+func TestGridCorridorParity(t *testing.T) {
+	for _, sw := range []int{5, 6} {
+		for _, sh := range []int{5, 6} {
+			for cs := 1; cs <= 4; cs++ {
+				world := NewWorldWithSeed(60, 60, 1)
+				world.GridCellHeight = sh
+				if _, err := world.GenerateDungeonGrid(10, WithRoomSize(1, 1, sw, sw), WithCorridorSize(cs)); err != nil {
+					t.Fatalf("%dx%d cells, corridors %d: %v", sw, sh, cs, err)
+				}
+				for door, dir := range world.Doors {
+					long, before, span := door.W, door.X, sw
+					if dir == DoorDirectionVertical {
+						long, before, span = door.H, door.Y, sh
+					}
+					if long != cs {
+						t.Errorf("%dx%d cells: door %v is %d wide, want %d", sw, sh, door, long, cs)
+					}
+					room, ok := doorRoom(world, door, dir)
+					if !ok {
+						t.Errorf("%dx%d cells: door %v isn't beside a room", sw, sh, door)
+						continue
+					}
+					if dir == DoorDirectionVertical {
+						before -= room.Y
+					} else {
+						before -= room.X
+					}
+					if after := span - cs - before; after != before && after != before+1 {
+						t.Errorf("%dx%d cells, corridors %d: door %v leaves %d before and %d after", sw, sh, cs, door,
+							before, after)
+					}
+				}
+			}
+		}
+	}
+}
+
+// doorRoom returns the room the door opens out of, to its left for vertical doors and above for horizontal ones
+func doorRoom(world *World, door Rect, dir DoorDirection) (Rect, bool) {
+	for room := range world.Rooms {
+		if dir == DoorDirectionVertical && room.X+room.W <= door.X && door.Y >= room.Y && door.Y < room.Y+room.H {
+			if door.X-(room.X+room.W) <= world.WallThickness {
+				return room, true
+			}
+		}
+		if dir == DoorDirectionHorizontal && room.Y+room.H <= door.Y && door.X >= room.X && door.X < room.X+room.W {
+			if door.Y-(room.Y+room.H) <= world.WallThickness {
+				return room, true
+			}
+		}
+	}
+	return Rect{}, false
+}
+
+// TestDeltaRoundTrip changes tiles, decoration and doors of a generated map and checks applying its delta to a freshly
+// generated base gives the same map back
+func TestDeltaRoundTrip(t *testing.T) {
+	newWorld := func() *World {
+		world := NewWorldWithSeed(64, 48, 7)
+		if _, err := world.GenerateDungeon(10); err != nil {
+			t.Fatal(err)
+		}
+		world.AddWalls()
+		world.Layer("props")[10][10] = TileChest
+		return world
+	}
+	base, world := newWorld(), newWorld()
+
+	world.SetTile(3, 4, TileWater)
+	world.SetTile(5, 6, TileFloor)
+	world.Layer("props")[10][10] = TileVoid
+	world.Layer("props")[20][21] = TileShelf
+	world.Layer("marks")[1][2] = TileNPC
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		doors = append(doors, door)
+	}
+	sortRects(doors)
+	delete(world.Doors, doors[0])
+	if world.Doors[doors[1]] == DoorDirectionVertical {
+		world.Doors[doors[1]] = DoorDirectionHorizontal
+	} else {
+		world.Doors[doors[1]] = DoorDirectionVertical
+	}
+	world.Doors[Rect{X: 30, Y: 30, W: 1, H: 2}] = DoorDirectionVertical
+
+	var buf bytes.Buffer
+	if err := world.EncodeDelta(base, &buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := newWorld()
+	if err := restored.ApplyDelta(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Tiles, world.Tiles) {
+		t.Error("tiles differ")
+	}
+	if !reflect.DeepEqual(restored.Doors, world.Doors) {
+		t.Errorf("doors differ:\n got %v\nwant %v", restored.Doors, world.Doors)
+	}
+	for _, name := range []string{"props", "marks"} {
+		if !reflect.DeepEqual(restored.Layers[name], world.Layers[name]) {
+			t.Errorf("layer %s differs", name)
+		}
+	}
+
+	var empty bytes.Buffer
+	if err := base.EncodeDelta(newWorld(), &empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWorldWithSeed(32, 32, 1).ApplyDelta(&empty); !errors.Is(err, ErrDeltaMismatch) {
+		t.Errorf("applying to a different size: got error %v, want %v", err, ErrDeltaMismatch)
+	}
+}
+
+// TestLDtkRoundTrip writes a named and tagged map to LDtk and checks reading it back gives the same tiles, rooms, doors,
+// names, tags and landmarks
+func TestLDtkRoundTrip(t *testing.T) {
+	world := NewWorldWithSeed(64, 48, 3)
+	if _, err := world.GenerateDungeon(10); err != nil {
+		t.Fatal(err)
+	}
+	world.AddWalls()
+	world.NameRooms(NamingStyleDungeon)
+	rooms := world.RoomsOrdered()
+	world.TagRoom(rooms[0], TagStart)
+	world.TagRoom(rooms[len(rooms)-1], TagBoss, TagTreasure)
+	for door := range world.Doors {
+		world.TagDoor(door, TagLocked)
+		break
+	}
+	if _, err := world.PlaceLandmarks(3); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := world.WriteLDtk(&buf, DefaultLDtkOptions()); err != nil {
+		t.Fatal(err)
+	}
+	read := NewWorldWithSeed(1, 1, 1)
+	if err := read.ReadLDtk(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Width != world.Width || read.Height != world.Height || !reflect.DeepEqual(read.Tiles, world.Tiles) {
+		t.Error("tiles differ")
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"rooms", read.Rooms, world.Rooms},
+		{"doors", read.Doors, world.Doors},
+		{"room names", read.RoomNames, world.RoomNames},
+		{"room tags", read.RoomTags, world.RoomTags},
+		{"door tags", read.DoorTags, world.DoorTags},
+		{"landmarks", read.Landmarks, world.Landmarks},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s differ:\n got %v\nwant %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+// TestPuzzlesSolvable dresses every room of some maps with puzzles of each kind and checks each one can be solved in
+// the number of moves it records, from the room's first entrance
+func TestPuzzlesSolvable(t *testing.T) {
+	for _, kind := range []PuzzleKind{PuzzleBlocks, PuzzleSwitches} {
+		puzzles := 0
+		for seed := int64(1); seed <= 3; seed++ {
+			world := NewWorldWithSeed(64, 48, seed)
+			if _, err := world.GenerateDungeon(10); err != nil {
+				t.Fatal(err)
+			}
+			world.AddWalls()
+			for room := range world.Rooms {
+				world.TagRoom(room, TagPuzzle)
+			}
+			world.AddDressing(PuzzleDressing("puzzles", PuzzleOptions{Kinds: []PuzzleKind{kind}, Difficulty: 0.5}))
+			world.Redecorate(seed)
+			layer := world.Layers["puzzles"]
+
+			for _, p := range world.Puzzles {
+				puzzles++
+				entrances := world.roomEntrances(p.Room)
+				switch kind {
+				case PuzzleBlocks:
+					area := make(map[Point]bool)
+					for y := p.Room.Y; y < p.Room.Y+p.Room.H; y++ {
+						for x := p.Room.X; x < p.Room.X+p.Room.W; x++ {
+							area[Point{X: x, Y: y}] = world.walkable(x, y)
+						}
+					}
+					movable := func(q Point) bool {
+						for _, e := range entrances {
+							if absInt(e.X-q.X) <= 1 && absInt(e.Y-q.Y) <= 1 {
+								return false
+							}
+						}
+						return area[q]
+					}
+					for _, b := range p.Blocks {
+						if layer[b.Y][b.X] != TileBlock && layer[b.Y][b.X] != TilePlate {
+							t.Errorf("seed %d: no block at %v", seed, b)
+						}
+					}
+					if moves := solveBlocks(area, movable, p.Blocks, p.Plates, entrances[0]); moves < 1 || moves != p.Moves {
+						t.Errorf("seed %d: block puzzle in %v takes %d pushes, recorded %d", seed, p.Room, moves, p.Moves)
+					}
+				case PuzzleSwitches:
+					// Every lever is reachable with the portcullises closed
+					closed := make(map[Point]bool)
+					for _, g := range p.Gates {
+						closed[g] = true
+					}
+					for _, r := range p.Rewards {
+						closed[r] = true
+					}
+					reach := floodPoints(entrances[0], func(q Point) bool {
+						return p.Room.contains(q.X, q.Y) && world.walkable(q.X, q.Y) && !closed[q]
+					})
+					for _, l := range p.Levers {
+						if !reach[l] || layer[l.Y][l.X] != TileLever {
+							t.Errorf("seed %d: lever at %v can't be pulled", seed, l)
+						}
+					}
+					// The fewest levers whose toggles open every portcullis
+					fewest := -1
+					for set := 0; set < 1<<uint(len(p.Levers)); set++ {
+						open := make([]bool, len(p.Gates))
+						pulled := 0
+						for i, toggles := range p.Toggles {
+							if set&(1<<uint(i)) == 0 {
+								continue
+							}
+							pulled++
+							for _, g := range toggles {
+								open[g] = !open[g]
+							}
+						}
+						all := true
+						for _, o := range open {
+							all = all && o
+						}
+						if all && (fewest == -1 || pulled < fewest) {
+							fewest = pulled
+						}
+					}
+					if fewest < 1 || fewest != p.Moves {
+						t.Errorf("seed %d: switch puzzle in %v takes %d pulls, recorded %d", seed, p.Room, fewest, p.Moves)
+					}
+				}
+			}
+		}
+		if puzzles == 0 {
+			t.Errorf("no puzzles of kind %d were made", kind)
+		}
+	}
+}
+
+// TestSolve partitions generated maps into locked sectors and checks Solve finds an unbroken walk from the first room
+// into the last sector, picking up the keys on the way, and finds none without the keys
+func TestSolve(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		world := NewWorldWithSeed(64, 48, seed)
+		if _, err := world.GenerateDungeon(12); err != nil {
+			t.Fatal(err)
+		}
+		world.AddWalls()
+		first := world.RoomsOrdered()[0]
+		if err := world.PartitionSectors(first, 3); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		last := world.Sectors[len(world.Sectors)-1].Rooms[0]
+		start := Point{X: first.X + first.W/2, Y: first.Y + first.H/2}
+		goal := Point{X: last.X + last.W/2, Y: last.Y + last.H/2}
+
+		rules := world.Rules()
+		solution, err := world.Solve(start, goal, rules)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		path := solution.Path
+		if path[0] != start || path[len(path)-1] != goal {
+			t.Errorf("seed %d: path goes from %v to %v, want %v to %v", seed, path[0], path[len(path)-1], start, goal)
+		}
+		for i, p := range path {
+			if !world.walkable(p.X, p.Y) {
+				t.Errorf("seed %d: path crosses %v which can't be walked on", seed, p)
+			}
+			if i > 0 && absInt(p.X-path[i-1].X)+absInt(p.Y-path[i-1].Y) != 1 {
+				t.Errorf("seed %d: path jumps from %v to %v", seed, path[i-1], p)
+			}
+		}
+		if len(solution.Items) == 0 {
+			t.Errorf("seed %d: reached the last sector without picking up a key", seed)
+		}
+
+		if _, err := world.Solve(start, goal, Rules{Locks: rules.Locks}); !errors.Is(err, ErrNoPath) {
+			t.Errorf("seed %d: without keys got error %v, want %v", seed, err, ErrNoPath)
+		}
+	}
+}
+
+// TestWFCPatterns generates maps from a sample and checks every pattern sized square of them appears in the sample,
+// turned and flipped too when Symmetry is set
+func TestWFCPatterns(t *testing.T) {
+	sample := [][]Tile{
+		{V, V, V, V, V, V, V, V, V, V},
+		{V, W, W, W, W, V, V, V, V, V},
+		{V, W, F, F, W, W, W, W, W, V},
+		{V, W, F, F, F, F, F, F, W, V},
+		{V, W, F, F, W, W, W, F, W, V},
+		{V, W, W, W, W, V, W, F, W, V},
+		{V, V, V, V, V, V, W, F, W, V},
+		{V, V, W, W, W, W, W, F, W, V},
+		{V, V, W, F, F, F, F, F, W, V},
+		{V, V, W, W, W, W, W, W, W, V},
+		{V, V, V, V, V, V, V, V, V, V},
+	}
+	key := func(tiles [][]Tile) string {
+		return fmt.Sprint(tiles)
+	}
+	for _, symmetry := range []bool{false, true} {
+		for _, n := range []int{2, 3} {
+			allowed := make(map[string]bool)
+			for y := 0; y+n <= len(sample); y++ {
+				for x := 0; x+n <= len(sample[0]); x++ {
+					tiles := make([][]Tile, n)
+					for dy := range tiles {
+						tiles[dy] = sample[y+dy][x : x+n]
+					}
+					allowed[key(tiles)] = true
+					for r := 0; symmetry && r < 4; r++ {
+						tiles = wfcRotate(tiles)
+						allowed[key(tiles)] = true
+						allowed[key(wfcReflect(tiles))] = true
+					}
+				}
+			}
+
+			world := NewWorldWithSeed(32, 24, 1)
+			world.DurationBeforeError = time.Minute // contradictions restart the map, slowly under the race detector
+			if err := world.GenerateWFC(sample, WFCOptions{PatternSize: n, Symmetry: symmetry}); err != nil {
+				t.Fatalf("size %d, symmetry %v: %v", n, symmetry, err)
+			}
+			b := world.Border
+			for y := b; y+n <= world.Height-b; y++ {
+				for x := b; x+n <= world.Width-b; x++ {
+					tiles := make([][]Tile, n)
+					for dy := range tiles {
+						tiles[dy] = world.Tiles[y+dy][x : x+n]
+					}
+					if !allowed[key(tiles)] {
+						t.Fatalf("size %d, symmetry %v: square at %d,%d isn't in the sample: %v", n, symmetry, x, y, tiles)
+					}
+				}
+			}
+		}
+	}
+
+	if err := NewWorldWithSeed(32, 24, 1).GenerateWFC(sample[:2], DefaultWFCOptions()); !errors.Is(err, ErrSampleTooSmall) {
+		t.Errorf("tiny sample: got error %v, want %v", err, ErrSampleTooSmall)
+	}
+}
@@ -0,0 +1,83 @@
+// Package generatetest provides helpers for snapshot testing maps made with the generate package. Maps are generated
+// from a fixed seed so they're the same every run and compared against golden strings of their Art:
+//
+//	func TestDungeon(t *testing.T) {
+//		world := generatetest.World(t, 48, 40, 1, func(world *generate.World) error {
+//			_, err := world.GenerateDungeon(4)
+//			world.AddWalls()
+//			return err
+//		})
+//		generatetest.Golden(t, world, `
+//	      ############
+//	      #..........#
+//	...
+//	`)
+//	}
+//
+// Golden strings are raw string literals starting on the line after the backtick, with no indentation, since leading
+// spaces are void tiles. Trailing spaces are ignored so editors which strip them don't break the tests. When the
+// map changes the test fails with the new art, which can be pasted in without go test's indentation if the change
+// was meant
+package generatetest
+
+import (
+	"strings"
+	"testing"
+
+	generate "github.com/melonfunction/dungeon-gen"
+)
+
+// World returns a new width x height world seeded with seed after running gen on it, failing the test if gen returns an
+// error. Keep maps small so the golden strings stay readable
+func World(t testing.TB, width, height int, seed int64, gen func(world *generate.World) error) *generate.World {
+	t.Helper()
+	world := generate.NewWorldWithSeed(width, height, seed)
+	if err := gen(world); err != nil {
+		t.Fatalf("generating %dx%d world with seed %d: %v", width, height, seed, err)
+	}
+	return world
+}
+
+// Golden fails the test if the world's Art doesn't match want, reporting the first row which differs and the whole art
+func Golden(t testing.TB, world *generate.World, want string) {
+	t.Helper()
+	GoldenArt(t, world.Art(), want)
+}
+
+// GoldenArt fails the test if got doesn't match want, for art which has been edited before comparing, such as by
+// drawing extra markers on it. Both are normalized with Normalize first
+func GoldenArt(t testing.TB, got, want string) {
+	t.Helper()
+	g, w := Normalize(got), Normalize(want)
+	if g == w {
+		return
+	}
+	gotLines, wantLines := strings.Split(g, "\n"), strings.Split(w, "\n")
+	row := 0
+	for row < len(gotLines) && row < len(wantLines) && gotLines[row] == wantLines[row] {
+		row++
+	}
+	line := func(lines []string) string {
+		if row < len(lines) {
+			return lines[row]
+		}
+		return "(no row)"
+	}
+	t.Errorf("art differs at row %d:\n got: %q\nwant: %q\n\ngot:\n%s", row, line(gotLines), line(wantLines), g)
+}
+
+// Normalize returns art without trailing spaces on each row or blank rows at the start and end, which is what Golden
+// compares
+func Normalize(art string) string {
+	lines := strings.Split(art, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,150 @@
+package generatetest_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	generate "github.com/melonfunction/dungeon-gen"
+	"github.com/melonfunction/dungeon-gen/generatetest"
+)
+
+// recorder is a testing.TB which records failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+	runtime.Goexit()
+}
+
+// record runs f with a recorder on its own goroutine so Fatalf can stop it
+func record(t *testing.T, f func(tb testing.TB)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r
+}
+
+func dungeon(world *generate.World) error {
+	_, err := world.GenerateDungeon(4)
+	world.AddWalls()
+	return err
+}
+
+const dungeonArt = `
+      ########################
+      ########################
+      ##.......##.....##....##
+      ##.......##.....##....##
+      ##.......##.....[.....##
+      ##.......[......##....##
+      ##.......##.....##....##
+      ##.......##.....########
+      ##.......###############
+      ####[#############
+      ####.######
+      ##.....##
+      ##.....##
+      ##.....##
+      ##.....##
+      #########
+      #########
+`
+
+func TestGolden(t *testing.T) {
+	world := generatetest.World(t, 48, 40, 1, dungeon)
+	generatetest.Golden(t, world, dungeonArt)
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	world := generatetest.World(t, 48, 40, 1, dungeon)
+	want := strings.Replace(dungeonArt, "####[####", "#########", 1)
+	r := record(t, func(tb testing.TB) {
+		generatetest.Golden(tb, world, want)
+	})
+	if len(r.errors) != 1 {
+		t.Fatalf("got %d errors, want 1", len(r.errors))
+	}
+	if !strings.Contains(r.errors[0], "art differs at row 9") {
+		t.Errorf("error doesn't give the row which differs:\n%s", r.errors[0])
+	}
+	if !strings.Contains(r.errors[0], generatetest.Normalize(world.Art())) {
+		t.Errorf("error doesn't include the new art:\n%s", r.errors[0])
+	}
+}
+
+func TestGoldenArt(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want string
+		fails     bool
+	}{
+		{"same", "#.#\n", "#.#", false},
+		{"trailing spaces", "#.#  \n # \n", "#.#\n #", false},
+		{"blank rows around", "\n\n#.#\n\n", "#.#", false},
+		{"leading spaces kept", "  #", "#", true},
+		{"different tile", "#.#", "###", true},
+		{"extra row", "#.#\n###", "#.#", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := record(t, func(tb testing.TB) {
+				generatetest.GoldenArt(tb, tt.got, tt.want)
+			})
+			if failed := len(r.errors) > 0; failed != tt.fails {
+				t.Errorf("failed = %v, want %v: %v", failed, tt.fails, r.errors)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		art, want string
+	}{
+		{"", ""},
+		{"\n\n", ""},
+		{"#.#\n", "#.#"},
+		{"#.# \t\r\n", "#.#"},
+		{"\n  #\n\n #.\n\n", "  #\n\n #."},
+		{"#\r\n.\r\n", "#\n."},
+	}
+	for _, tt := range tests {
+		if got := generatetest.Normalize(tt.art); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.art, got, tt.want)
+		}
+	}
+}
+
+func TestWorldError(t *testing.T) {
+	r := record(t, func(tb testing.TB) {
+		generatetest.World(tb, 8, 8, 1, func(world *generate.World) error {
+			return errors.New("no room")
+		})
+		tb.Errorf("World returned after gen failed")
+	})
+	if !r.fatal || len(r.errors) != 1 {
+		t.Fatalf("got fatal %v and errors %v, want one fatal error", r.fatal, r.errors)
+	}
+	if want := "8x8 world with seed 1: no room"; !strings.Contains(r.errors[0], want) {
+		t.Errorf("error %q doesn't contain %q", r.errors[0], want)
+	}
+}
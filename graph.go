@@ -0,0 +1,188 @@
+package generate
+
+// Edge connects two rooms through a door. OneWay edges can only be travelled From -> To
+type Edge struct {
+	From, To Rect
+	Door     Rect
+	OneWay   bool
+	Portal   bool // the rooms are linked by a portal, Door is the portal's tile in From
+}
+
+// Graph is the connectivity of the world's rooms, built from world.Rooms and world.Doors
+type Graph struct {
+	Rooms []Rect // in the order they were placed, see RoomsOrdered
+	Edges []Edge
+}
+
+// RoomAt returns the room containing x,y, tiles in the parts of a compound room return the room in world.Rooms
+func (world *World) RoomAt(x, y int) (Rect, bool) {
+	if world.index != nil && x >= 0 && y >= 0 && x < world.Width && y < world.Height {
+		if room, ok := world.index.roomAt(x, y); ok {
+			return room, true
+		}
+		return world.roomPartAt(x, y)
+	}
+	for room := range world.Rooms {
+		if room.contains(x, y) {
+			return room, true
+		}
+	}
+	return world.roomPartAt(x, y)
+}
+
+// doorRooms returns the two rooms on either side of a door, looking through up to the widest room separation+1 tiles
+// of corridor in each direction
+func (world *World) doorRooms(door Rect, dir DoorDirection) (Rect, Rect, bool) {
+	return world.doorRoomsWith(door, dir, world.RoomAt)
+}
+
+// doorRoomsWith is doorRooms with roomAt finding the room containing each tile
+func (world *World) doorRoomsWith(door Rect, dir DoorDirection, roomAt func(x, y int) (Rect, bool)) (Rect, Rect, bool) {
+	var a, b Rect
+	var okA, okB bool
+	sep := world.roomSeparation(world.maxWallThickness())
+	for d := 1; d <= sep+1 && !(okA && okB); d++ {
+		switch dir {
+		case DoorDirectionVertical:
+			y := door.Y + door.H/2
+			if !okA {
+				a, okA = roomAt(door.X-d, y)
+			}
+			if !okB {
+				b, okB = roomAt(door.X+door.W-1+d, y)
+			}
+		case DoorDirectionHorizontal:
+			x := door.X + door.W/2
+			if !okA {
+				a, okA = roomAt(x, door.Y-d)
+			}
+			if !okB {
+				b, okB = roomAt(x, door.Y+door.H-1+d)
+			}
+		}
+	}
+	return a, b, okA && okB && a != b
+}
+
+// BuildGraph returns the room graph of the world. Doors which are ledges become OneWay edges and portals between rooms
+// become Portal edges. Edges are in the row order of their doors, so the graph is the same every time
+func (world *World) BuildGraph() *Graph {
+	g := &Graph{
+		Rooms: world.RoomsOrdered(),
+		Edges: make([]Edge, 0, len(world.Doors)),
+	}
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		doors = append(doors, door)
+	}
+	sortRects(doors)
+	for _, door := range doors {
+		a, b, ok := world.doorRooms(door, world.Doors[door])
+		if !ok {
+			// Doors which aren't in a straight line between rooms, such as inferred cave passages
+			if a, b, ok = world.corridorRooms(door); !ok {
+				continue
+			}
+		}
+		edge := Edge{From: a, To: b, Door: door}
+		if to, ok := world.Ledges[door]; ok {
+			edge.OneWay = true
+			if to == a {
+				edge.From, edge.To = b, a
+			}
+		}
+		g.Edges = append(g.Edges, edge)
+	}
+	for _, portal := range world.Portals {
+		a, okA := world.RoomAt(portal.A.X, portal.A.Y)
+		b, okB := world.RoomAt(portal.B.X, portal.B.Y)
+		if okA && okB && a != b {
+			g.Edges = append(g.Edges, Edge{From: a, To: b, Door: Rect{X: portal.A.X, Y: portal.A.Y, W: 1, H: 1}, Portal: true})
+		}
+	}
+	return g
+}
+
+// degrees returns how many edges each room has
+func (g *Graph) degrees() map[Rect]int {
+	degree := make(map[Rect]int, len(g.Rooms))
+	for _, e := range g.Edges {
+		degree[e.From]++
+		degree[e.To]++
+	}
+	return degree
+}
+
+// Reachable returns every room which can be reached from the room from, respecting OneWay edges
+func (g *Graph) Reachable(from Rect) map[Rect]struct{} {
+	adj := make(map[Rect][]Rect)
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+		if !e.OneWay {
+			adj[e.To] = append(adj[e.To], e.From)
+		}
+	}
+
+	seen := map[Rect]struct{}{from: {}}
+	queue := []Rect{from}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		for _, n := range adj[r] {
+			if _, ok := seen[n]; !ok {
+				seen[n] = struct{}{}
+				queue = append(queue, n)
+			}
+		}
+	}
+	return seen
+}
+
+// Completable returns true if every room can be reached from start, taking ledges into account
+func (world *World) Completable(start Rect) bool {
+	return len(world.BuildGraph().Reachable(start)) == len(world.Rooms)
+}
+
+// AddLedges turns up to count doors into ledges: one-way drops from one room into another. A ledge is only kept if
+// every room can still be reached from start. The number of ledges placed is returned
+func (world *World) AddLedges(start Rect, count int) int {
+	if !world.Completable(start) {
+		return 0
+	}
+
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		if _, ok := world.Ledges[door]; !ok {
+			doors = append(doors, door)
+		}
+	}
+	sortRects(doors)
+	world.rng.Shuffle(len(doors), func(i, j int) {
+		doors[i], doors[j] = doors[j], doors[i]
+	})
+
+	var placed int
+	for _, door := range doors {
+		if placed >= count {
+			break
+		}
+		a, b, ok := world.doorRooms(door, world.Doors[door])
+		if !ok {
+			continue
+		}
+		if world.rng.Int()%2 == 0 {
+			a, b = b, a
+		}
+		// Try dropping a -> b, then b -> a
+		world.Ledges[door] = b
+		if !world.Completable(start) {
+			world.Ledges[door] = a
+			if !world.Completable(start) {
+				delete(world.Ledges, door)
+				continue
+			}
+		}
+		placed++
+	}
+	return placed
+}
@@ -0,0 +1,193 @@
+package generate
+
+import "time"
+
+// GenerateRoomGrowth generates the world by scattering seeds room seeds and growing each of them a row or column at a
+// time until they collide, giving tightly packed rooms of every size separated by world.WallThickness walls, or
+// world.MinRoomSeparation if it's more. Touching rooms are joined by doors through their shared wall, enough to connect
+// every room plus extra doors with loopChance (0-1) to make loops. Rooms which can't be connected to the rest are
+// removed.
+// world.WallThickness, world.MinRoomSeparation, world.FlushRooms and world.MinCorridorSize|MaxCorridorSize are used
+func (world *World) GenerateRoomGrowth(seeds int, loopChance float64, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("RoomGrowth", seeds)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	wt := wallGap(world.WallThickness)
+	// Rooms are kept exactly gap apart so placeRoom accepts them and doors can be carved straight through
+	gap := world.roomSeparation(wt)
+	m := world.edgeMargin(wt)
+	bounds := Rect{X: m, Y: m, W: world.Width - m*2, H: world.Height - m*2}
+	if bounds.W < 1 || bounds.H < 1 || seeds < 1 {
+		return ErrNotEnoughSpace
+	}
+
+	// fits returns true if r is inside bounds and its walls don't touch any room other than rooms[skip]
+	rooms := make([]Rect, 0, seeds)
+	fits := func(r Rect, skip int) bool {
+		if r.X < bounds.X || r.Y < bounds.Y || r.X+r.W > bounds.X+bounds.W || r.Y+r.H > bounds.Y+bounds.H {
+			return false
+		}
+		for i, o := range rooms {
+			if i != skip && r.X < o.X+o.W+gap && o.X < r.X+r.W+gap && r.Y < o.Y+o.H+gap && o.Y < r.Y+r.H+gap {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Seeds
+	for attempts := 0; len(rooms) < seeds && attempts < seeds*20; attempts++ {
+		seed := Rect{
+			X: world.randInt(bounds.X, bounds.X+bounds.W-1),
+			Y: world.randInt(bounds.Y, bounds.Y+bounds.H-1),
+			W: 1,
+			H: 1,
+		}
+		if fits(seed, -1) {
+			rooms = append(rooms, seed)
+		}
+	}
+
+	// Grow every room by a row or column in a random direction until none of them can grow
+	growing := make([]int, len(rooms))
+	for i := range growing {
+		growing[i] = i
+	}
+	for len(growing) > 0 {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		}
+		world.rng.Shuffle(len(growing), func(i, j int) { growing[i], growing[j] = growing[j], growing[i] })
+		for gi := 0; gi < len(growing); gi++ {
+			i := growing[gi]
+			r := rooms[i]
+			grown := false
+			for _, side := range world.rng.Perm(4) {
+				next := r
+				switch side {
+				case 0: // left
+					next.X--
+					next.W++
+				case 1: // right
+					next.W++
+				case 2: // up
+					next.Y--
+					next.H++
+				case 3: // down
+					next.H++
+				}
+				if fits(next, i) {
+					rooms[i] = next
+					grown = true
+					break
+				}
+			}
+			if !grown {
+				growing[gi] = growing[len(growing)-1]
+				growing = growing[:len(growing)-1]
+				gi--
+			}
+		}
+	}
+
+	// Find the rooms touching through a single wall
+	type link struct {
+		a, b int
+	}
+	links := make([]link, 0)
+	for i, a := range rooms {
+		for j := i + 1; j < len(rooms); j++ {
+			b := rooms[j]
+			xOverlap := minInt(a.X+a.W, b.X+b.W) - maxInt(a.X, b.X)
+			yOverlap := minInt(a.Y+a.H, b.Y+b.H) - maxInt(a.Y, b.Y)
+			if (yOverlap > 0 && (a.X+a.W+gap == b.X || b.X+b.W+gap == a.X)) ||
+				(xOverlap > 0 && (a.Y+a.H+gap == b.Y || b.Y+b.H+gap == a.Y)) {
+				links = append(links, link{a: i, b: j})
+			}
+		}
+	}
+	world.rng.Shuffle(len(links), func(i, j int) { links[i], links[j] = links[j], links[i] })
+
+	// Random spanning tree over the links, plus loops
+	parent := make([]int, len(rooms))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	doors := make([]link, 0, len(links))
+	for _, l := range links {
+		if ra, rb := find(l.a), find(l.b); ra != rb {
+			parent[ra] = rb
+			doors = append(doors, l)
+		} else if world.rng.Float64() < loopChance {
+			doors = append(doors, l)
+		}
+	}
+
+	// Keep the largest connected group of rooms
+	size := make(map[int]int)
+	largest := 0
+	for i := range rooms {
+		root := find(i)
+		size[root]++
+		if size[root] > size[largest] {
+			largest = root
+		}
+	}
+	for i, room := range rooms {
+		if find(i) != largest {
+			continue
+		}
+		if err := world.placeRoom(room.X, room.Y, room.W, room.H, wt); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range doors {
+		if find(l.a) != largest {
+			continue
+		}
+		world.carveWallDoor(rooms[l.a], rooms[l.b], gap)
+	}
+
+	return nil
+}
+
+// carveWallDoor carves a corridor of a random width through the wall between two rooms which are gap apart and adds
+// its doorway
+func (world *World) carveWallDoor(a, b Rect, gap int) {
+	if b.X+b.W+gap == a.X || b.Y+b.H+gap == a.Y {
+		a, b = b, a
+	}
+
+	var corridor Rect
+	cd := DoorDirectionHorizontal
+	if a.X+a.W+gap == b.X { // left to right
+		lo, hi := maxInt(a.Y, b.Y), minInt(a.Y+a.H, b.Y+b.H)
+		cs := minInt(world.randInt(world.MinCorridorSize, world.MaxCorridorSize), hi-lo)
+		corridor = Rect{X: a.X + a.W, Y: world.randInt(lo, hi-cs), W: gap, H: cs}
+		cd = DoorDirectionVertical
+	} else { // top to bottom
+		lo, hi := maxInt(a.X, b.X), minInt(a.X+a.W, b.X+b.W)
+		cs := minInt(world.randInt(world.MinCorridorSize, world.MaxCorridorSize), hi-lo)
+		corridor = Rect{X: world.randInt(lo, hi-cs), Y: a.Y + a.H, W: cs, H: gap}
+	}
+
+	for x := corridor.X; x < corridor.X+corridor.W; x++ {
+		for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+			world.setFloor(x, y, FloorKindCorridor)
+		}
+	}
+
+	world.addDoorway(corridor, cd)
+}
@@ -0,0 +1,269 @@
+package generate
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// hasher feeds ints into a 64 bit FNV-1a hash
+type hasher struct {
+	h   hash.Hash64
+	buf [8]byte
+}
+
+func newHasher() *hasher {
+	return &hasher{h: fnv.New64a()}
+}
+
+func (h *hasher) int(v int) {
+	binary.LittleEndian.PutUint64(h.buf[:], uint64(v))
+	h.h.Write(h.buf[:])
+}
+
+func (h *hasher) string(s string) {
+	h.int(len(s))
+	h.h.Write([]byte(s))
+}
+
+func (h *hasher) point(p Point) {
+	h.int(p.X)
+	h.int(p.Y)
+}
+
+func (h *hasher) points(ps []Point) {
+	h.int(len(ps))
+	for _, p := range ps {
+		h.point(p)
+	}
+}
+
+func (h *hasher) bool(b bool) {
+	if b {
+		h.int(1)
+	} else {
+		h.int(0)
+	}
+}
+
+func (h *hasher) float(f float64) {
+	h.int(int(math.Float64bits(f)))
+}
+
+func (h *hasher) tags(tags []Tag) {
+	h.int(len(tags))
+	for _, tag := range tags {
+		h.string(string(tag))
+	}
+}
+
+func (h *hasher) rect(r Rect) {
+	h.int(r.X)
+	h.int(r.Y)
+	h.int(r.W)
+	h.int(r.H)
+}
+
+// sortRects sorts rects top to bottom, left to right, then by size
+func sortRects(rects []Rect) {
+	sort.Slice(rects, func(i, j int) bool {
+		a, b := rects[i], rects[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.W != b.W {
+			return a.W < b.W
+		}
+		return a.H < b.H
+	})
+}
+
+// HashStructure returns a hash of the world's size, tiles and elevation only
+// Two worlds with the same layout hash the same even if their rooms, tags or decorations differ
+func (world *World) HashStructure() uint64 {
+	h := newHasher()
+	world.hashTiles(h)
+	return h.h.Sum64()
+}
+
+// Hash returns a hash of everything the world holds: tiles, elevation, floor kinds, biomes, rooms and their parts,
+// names and themes, doors, tags, ledges, corridors, decoration layers, facings, sectors, gates, portals, puzzles,
+// landmarks and exits
+func (world *World) Hash() uint64 {
+	h := newHasher()
+	world.hashTiles(h)
+
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			h.int(int(world.FloorKinds[y][x]))
+			h.int(int(world.Biomes[y][x]))
+		}
+	}
+
+	rooms := world.sortedRooms()
+	h.int(len(rooms))
+	for _, room := range rooms {
+		h.rect(room)
+		h.tags(world.RoomTags[room])
+		h.string(world.RoomNames[room])
+		theme := world.RoomThemes[room]
+		h.string(theme.FloorMaterial)
+		h.float(theme.CeilingHeight)
+		h.string(theme.WallSet)
+		parts := world.RoomParts[room]
+		h.int(len(parts))
+		for _, part := range parts {
+			h.rect(part)
+		}
+	}
+
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		doors = append(doors, door)
+	}
+	sortRects(doors)
+	h.int(len(doors))
+	for _, door := range doors {
+		h.rect(door)
+		h.int(int(world.Doors[door]))
+		h.tags(world.DoorTags[door])
+		if to, ok := world.Ledges[door]; ok {
+			h.int(1)
+			h.rect(to)
+		} else {
+			h.int(0)
+		}
+	}
+
+	h.int(len(world.Corridors))
+	for _, c := range world.Corridors {
+		h.int(len(c.Path))
+		for _, p := range c.Path {
+			h.int(p.X)
+			h.int(p.Y)
+		}
+		h.int(c.Width)
+		h.int(len(c.Rooms))
+		for _, room := range c.Rooms {
+			h.rect(room)
+		}
+		h.rect(c.Door)
+	}
+
+	names := make([]string, 0, len(world.Layers))
+	for name := range world.Layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.string(name)
+		for _, row := range world.Layers[name] {
+			for _, t := range row {
+				h.int(int(t))
+			}
+		}
+	}
+
+	facing := make([]Point, 0, len(world.Facing))
+	for p := range world.Facing {
+		facing = append(facing, p)
+	}
+	sort.Slice(facing, func(i, j int) bool {
+		if facing[i].Y != facing[j].Y {
+			return facing[i].Y < facing[j].Y
+		}
+		return facing[i].X < facing[j].X
+	})
+	h.int(len(facing))
+	for _, p := range facing {
+		h.int(p.X)
+		h.int(p.Y)
+		h.int(int(world.Facing[p]))
+	}
+
+	h.int(len(world.Sectors))
+	for _, sector := range world.Sectors {
+		h.int(len(sector.Rooms))
+		for _, room := range sector.Rooms {
+			h.rect(room)
+		}
+		h.int(int(sector.Biome))
+		h.int(sector.Parent)
+		h.int(sector.Key.X)
+		h.int(sector.Key.Y)
+	}
+	h.int(len(world.Gates))
+	for _, gate := range world.Gates {
+		h.rect(gate.Door)
+		h.int(gate.From)
+		h.int(gate.To)
+	}
+	h.int(len(world.Portals))
+	for _, portal := range world.Portals {
+		h.int(portal.A.X)
+		h.int(portal.A.Y)
+		h.int(portal.B.X)
+		h.int(portal.B.Y)
+	}
+
+	h.int(len(world.Puzzles))
+	for _, puzzle := range world.Puzzles {
+		h.rect(puzzle.Room)
+		h.int(int(puzzle.Kind))
+		h.points(puzzle.Blocks)
+		h.points(puzzle.Plates)
+		h.points(puzzle.Levers)
+		h.points(puzzle.Gates)
+		h.int(len(puzzle.Toggles))
+		for _, toggle := range puzzle.Toggles {
+			h.int(len(toggle))
+			for _, i := range toggle {
+				h.int(i)
+			}
+		}
+		h.points(puzzle.Rewards)
+		h.int(puzzle.Moves)
+	}
+	h.int(len(world.Landmarks))
+	for _, landmark := range world.Landmarks {
+		h.point(landmark.Point)
+		h.rect(landmark.Room)
+		h.bool(landmark.InRoom)
+		h.tags(landmark.Tags)
+	}
+	h.int(len(world.exits))
+	for _, exit := range world.exits {
+		h.string(exit.Name)
+		h.point(exit.Point)
+		h.int(int(exit.Side))
+		h.rect(exit.Room)
+		h.bool(exit.InRoom)
+	}
+
+	return h.h.Sum64()
+}
+
+func (world *World) hashTiles(h *hasher) {
+	h.int(world.Width)
+	h.int(world.Height)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			h.int(int(world.Tiles[y][x]))
+		}
+	}
+	if world.Elevation == nil {
+		h.int(0)
+		return
+	}
+	h.int(1)
+	for _, row := range world.Elevation {
+		for _, e := range row {
+			h.int(e)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+)
+
+// HazardRule marks a kind of hazard where its noise is above Threshold, only in its biomes
+type HazardRule struct {
+	Tile      Tile    // marked on the layer, such as TileGas
+	Biomes    []Biome // biomes the hazard can be in, any biome if it's empty
+	Scale     float64 // size of the noise features in tiles, bigger for larger patches
+	Threshold float64 // noise (0-1) above which the hazard is marked, higher for rarer hazards
+}
+
+// HazardOptions controls how HazardDressing marks hazards
+type HazardOptions struct {
+	Rules            []HazardRule // the first rule to match a tile wins
+	SafeCriticalPath bool         // keep CriticalPath clear so the level can be finished without walking through any
+}
+
+// DefaultHazardOptions returns gas in caves and crypts, cold in caves and by the sea and darkness underground, with
+// the critical path kept clear. Tiles without a biome count as dungeon
+func DefaultHazardOptions() HazardOptions {
+	return HazardOptions{
+		Rules: []HazardRule{
+			{Tile: TileGas, Biomes: []Biome{BiomeNone, BiomeCave, BiomeCrypt}, Scale: 6, Threshold: 0.7},
+			{Tile: TileCold, Biomes: []Biome{BiomeCave, BiomeBeach, BiomeOcean}, Scale: 10, Threshold: 0.68},
+			{Tile: TileDark, Biomes: []Biome{BiomeNone, BiomeDungeon, BiomeCave, BiomeCrypt}, Scale: 8, Threshold: 0.65},
+		},
+		SafeCriticalPath: true,
+	}
+}
+
+// HazardDressing returns a pass which marks environmental hazards on the named layer: patches of walkable tiles
+// picked by noise, each rule limited to its biomes
+func HazardDressing(name string, opts HazardOptions) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		noises := make([]*Noise, len(opts.Rules))
+		for i := range noises {
+			noises[i] = newNoise(rng)
+		}
+		safe := make(map[Point]bool)
+		if opts.SafeCriticalPath {
+			for _, p := range world.CriticalPath() {
+				safe[p] = true
+			}
+		}
+
+		layer := world.Layer(name)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if !world.walkable(x, y) || safe[Point{X: x, Y: y}] {
+					continue
+				}
+				biome := world.Biomes[y][x]
+				for i, rule := range opts.Rules {
+					if len(rule.Biomes) > 0 && !hasBiome(rule.Biomes, biome) {
+						continue
+					}
+					scale := math.Max(rule.Scale, 1)
+					if noises[i].Fractal(float64(x)/scale, float64(y)/scale, 3) > rule.Threshold {
+						layer[y][x] = rule.Tile
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// hasBiome returns true if biomes contains biome
+func hasBiome(biomes []Biome, biome Biome) bool {
+	for _, b := range biomes {
+		if b == biome {
+			return true
+		}
+	}
+	return false
+}
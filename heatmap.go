@@ -0,0 +1,96 @@
+package generate
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Heatmaps recorded while generating when world.RecordHeatmaps is set
+const (
+	HeatmapVisits  = "visits"  // how many times GenerateRandomWalk's walker stepped on each tile
+	HeatmapRetries = "retries" // where rooms were rejected or the grid was rewound
+)
+
+// heat adds amount to the named heatmap at x,y if world.RecordHeatmaps is set
+func (world *World) heat(name string, x, y int, amount float64) {
+	if !world.RecordHeatmaps || x < 0 || y < 0 || x >= world.Width || y >= world.Height {
+		return
+	}
+	if world.Heatmaps == nil {
+		world.Heatmaps = make(map[string]FloatLayer)
+	}
+	layer, ok := world.Heatmaps[name]
+	if !ok || len(layer) != world.Height || len(layer[0]) != world.Width {
+		layer = NewFloatLayer(world.Width, world.Height)
+		world.Heatmaps[name] = layer
+	}
+	layer[y][x] += amount
+}
+
+// ClearHeatmaps removes every recorded heatmap. Heatmaps aren't cleared when generating so they can be collected over
+// many generations
+func (world *World) ClearHeatmaps() {
+	world.Heatmaps = nil
+}
+
+// DistanceHeatmap returns how far each tile is from from, unreachable tiles are 0
+func (world *World) DistanceHeatmap(from Point) FloatLayer {
+	layer := NewFloatLayer(world.Width, world.Height)
+	for y, row := range world.DistanceField(from) {
+		for x, d := range row {
+			if d > 0 {
+				layer[y][x] = float64(d)
+			}
+		}
+	}
+	return layer
+}
+
+// Image returns the layer as an image, scale pixels per value, colored from black through red and yellow to white
+// between 0 and the layer's highest value
+func (layer FloatLayer) Image(scale int) *image.RGBA {
+	scale = maxInt(scale, 1)
+	h := len(layer)
+	w := 0
+	if h > 0 {
+		w = len(layer[0])
+	}
+
+	max := 0.0
+	for _, row := range layer {
+		for _, v := range row {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w*scale, h*scale))
+	for y, row := range layer {
+		for x, v := range row {
+			t := 0.0
+			if max > 0 {
+				t = clampFloat(v/max, 0, 1)
+			}
+			c := color.RGBA{
+				R: uint8(clampFloat(t*3, 0, 1) * 255),
+				G: uint8(clampFloat(t*3-1, 0, 1) * 255),
+				B: uint8(clampFloat(t*3-2, 0, 1) * 255),
+				A: 255,
+			}
+			for py := y * scale; py < (y+1)*scale; py++ {
+				for px := x * scale; px < (x+1)*scale; px++ {
+					img.SetRGBA(px, py, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the layer to w as a PNG, see Image
+func (layer FloatLayer) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, layer.Image(scale))
+}
@@ -0,0 +1,122 @@
+package generate
+
+// indexCellSize is the width and height of each cell of a spatialIndex in tiles
+const indexCellSize = 16
+
+// spatialIndex buckets the floor tiles and rooms of a world into cells so room placement doesn't need to scan every
+// tile or room. It's only kept while growDungeon runs, when every change goes through SetTile and placeRoom, so it
+// can't go stale when world.Tiles or world.Rooms are edited directly
+type spatialIndex struct {
+	width      int // size of the world in tiles, areas are clipped to it
+	height     int
+	cols, rows int
+	floors     []int    // floor tiles in each cell
+	rooms      [][]Rect // rooms overlapping each cell
+}
+
+// newSpatialIndex returns an index of the world's current floor tiles and rooms
+func newSpatialIndex(world *World) *spatialIndex {
+	cols := (world.Width + indexCellSize - 1) / indexCellSize
+	rows := (world.Height + indexCellSize - 1) / indexCellSize
+	index := &spatialIndex{
+		width:  world.Width,
+		height: world.Height,
+		cols:   cols,
+		rows:   rows,
+		floors: make([]int, cols*rows),
+		rooms:  make([][]Rect, cols*rows),
+	}
+	for y := range world.Tiles {
+		for x, t := range world.Tiles[y] {
+			if t == TileFloor {
+				index.floors[index.cell(x, y)]++
+			}
+		}
+	}
+	for room := range world.Rooms {
+		index.addRoom(room)
+	}
+	return index
+}
+
+// cell returns the cell containing x,y, which must be in bounds
+func (index *spatialIndex) cell(x, y int) int {
+	return y/indexCellSize*index.cols + x/indexCellSize
+}
+
+// cells calls f with every cell overlapping area, and the part of area inside that cell, until it returns false. The
+// parts of area outside the world are left out
+func (index *spatialIndex) cells(area Rect, f func(cell int, part Rect) bool) {
+	x, y := maxInt(area.X, 0), maxInt(area.Y, 0)
+	area.W = minInt(area.X+area.W, index.width) - x
+	area.H = minInt(area.Y+area.H, index.height) - y
+	area.X, area.Y = x, y
+	if area.W <= 0 || area.H <= 0 {
+		return
+	}
+	x0, y0 := area.X/indexCellSize, area.Y/indexCellSize
+	x1 := (area.X + area.W - 1) / indexCellSize
+	y1 := (area.Y + area.H - 1) / indexCellSize
+	for cy := y0; cy <= y1; cy++ {
+		for cx := x0; cx <= x1; cx++ {
+			part := Rect{X: maxInt(area.X, cx*indexCellSize), Y: maxInt(area.Y, cy*indexCellSize)}
+			part.W = minInt(area.X+area.W, (cx+1)*indexCellSize) - part.X
+			part.H = minInt(area.Y+area.H, (cy+1)*indexCellSize) - part.Y
+			if !f(cy*index.cols+cx, part) {
+				return
+			}
+		}
+	}
+}
+
+// setTile updates the floor counts for the tile at x,y changing from old to t
+func (index *spatialIndex) setTile(x, y int, old, t Tile) {
+	switch {
+	case old != TileFloor && t == TileFloor:
+		index.floors[index.cell(x, y)]++
+	case old == TileFloor && t != TileFloor:
+		index.floors[index.cell(x, y)]--
+	}
+}
+
+// addRoom adds a room to every cell it overlaps
+func (index *spatialIndex) addRoom(room Rect) {
+	index.cells(room, func(cell int, _ Rect) bool {
+		index.rooms[cell] = append(index.rooms[cell], room)
+		return true
+	})
+}
+
+// hasFloor returns true if there's a TileFloor anywhere in area, only scanning the tiles of cells which have floor
+func (index *spatialIndex) hasFloor(world *World, area Rect) bool {
+	found := false
+	index.cells(area, func(cell int, part Rect) bool {
+		if index.floors[cell] == 0 {
+			return true
+		}
+		if part.W == indexCellSize && part.H == indexCellSize {
+			found = true
+			return false
+		}
+		for y := part.Y; y < part.Y+part.H; y++ {
+			for x := part.X; x < part.X+part.W; x++ {
+				if world.Tiles[y][x] == TileFloor {
+					found = true
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// roomAt returns the room containing x,y, which must be in bounds
+func (index *spatialIndex) roomAt(x, y int) (Rect, bool) {
+	for _, room := range index.rooms[index.cell(x, y)] {
+		if room.contains(x, y) {
+			return room, true
+		}
+	}
+	return Rect{}, false
+}
@@ -0,0 +1,286 @@
+package generate
+
+// InferRooms finds the open chambers of a world which was generated without rooms, such as caves and tunnels, and
+// records them in world.Rooms. Chambers are rectangles of walkable tiles at least world.MinRoomWidth by
+// world.MinRoomHeight with a tile between each of them. Open ground on the surface, grass, sand and roads, can be
+// walked through but isn't a chamber, so overworld maps have no rooms. The passages between chambers are recorded in
+// world.Corridors and world.Doors, with the door at the start of the passage, so BuildGraph works the same as it does
+// for dungeons. Passages are found with a single flood fill spreading out from every chamber at once, each pair of
+// chambers whose fill meets is joined by the shortest passage where they meet.
+// Any rooms the world already has are replaced, along with their doors and corridors. It's called by the generators
+// which don't place rooms, call it again after changing their floor, such as with CleanIslands
+func (world *World) InferRooms() {
+	// Clear the old rooms, keeping corridors which don't lead to any, like catacomb corridors and tunnels
+	corridors := world.Corridors[:0]
+	for _, c := range world.Corridors {
+		if len(c.Rooms) == 0 {
+			corridors = append(corridors, c)
+			continue
+		}
+		delete(world.Doors, c.Door)
+		delete(world.DoorTags, c.Door)
+	}
+	world.Corridors = corridors
+	world.Rooms = make(map[Rect]struct{})
+	world.RoomParts = make(map[Rect][]Rect)
+	world.roomOrder = nil
+	world.RoomTags = make(map[Rect][]Tag)
+	world.RoomNames = make(map[Rect]string)
+	world.RoomThemes = make(map[Rect]Theme)
+
+	// Claim the largest chambers first, keeping a tile between each
+	claimed := make([][]bool, world.Height)
+	for y := range claimed {
+		claimed[y] = make([]bool, world.Width)
+	}
+	free := func(x, y int) bool {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				ox, oy := x+dx, y+dy
+				if ox >= 0 && oy >= 0 && ox < world.Width && oy < world.Height && claimed[oy][ox] {
+					return false
+				}
+			}
+		}
+		return world.walkable(x, y) && !world.Tiles[y][x].surface()
+	}
+	rooms := make([]Rect, 0)
+	minW, minH := maxInt(world.MinRoomWidth, 1), maxInt(world.MinRoomHeight, 1)
+	finder := newRectFinder(world.Width, world.Height, free, minW, minH)
+	for {
+		r, ok := finder.largest()
+		if !ok {
+			break
+		}
+		for y := r.Y; y < r.Y+r.H; y++ {
+			for x := r.X; x < r.X+r.W; x++ {
+				claimed[y][x] = true
+			}
+		}
+		finder.update(r.Expand(1))
+		world.addRoom(r)
+		rooms = append(rooms, r)
+	}
+	sortRects(rooms)
+
+	for _, p := range world.passages(rooms) {
+		a, b := rooms[p.a], rooms[p.b]
+		door := Rect{X: p.path[0].X, Y: p.path[0].Y, W: 1, H: 1}
+		dir := DoorDirectionHorizontal
+		if p.from.Y == p.path[0].Y {
+			dir = DoorDirectionVertical
+		}
+		world.Doors[door] = dir
+		world.Corridors = append(world.Corridors, Corridor{
+			Path:  p.path,
+			Width: 1,
+			From:  p.path[0],
+			To:    p.path[len(p.path)-1],
+			Rooms: []Rect{a, b},
+			Door:  door,
+		})
+	}
+}
+
+// surface returns true for the open ground of overworld maps
+func (t Tile) surface() bool {
+	return t == TileGrass || t == TileSand || t == TileRoad
+}
+
+// passage is the shortest way found between chambers a and b, a < b, through tiles which aren't in a chamber. from is
+// the chamber tile the path leaves a from
+type passage struct {
+	a, b int
+	path []Point
+	from Point
+}
+
+// passages floods out from every chamber at once through walkable tiles which aren't part of a chamber, returning a
+// passage for each pair of chambers whose floods meet, in the order they're first met
+func (world *World) passages(chambers []Rect) []passage {
+	owner := make([][]int, world.Height)
+	dist := make([][]int, world.Height)
+	from := make([][]Point, world.Height)
+	for y := range owner {
+		owner[y] = make([]int, world.Width)
+		dist[y] = make([]int, world.Width)
+		from[y] = make([]Point, world.Width)
+		for x := range owner[y] {
+			owner[y][x] = -1
+		}
+	}
+	queue := make([]Point, 0)
+	for i, c := range chambers {
+		for y := c.Y; y < c.Y+c.H; y++ {
+			for x := c.X; x < c.X+c.W; x++ {
+				owner[y][x] = i
+				queue = append(queue, Point{X: x, Y: y})
+			}
+		}
+	}
+
+	// trace returns the tiles from t back to its chamber, not counting the chamber's own tiles
+	trace := func(t Point) []Point {
+		path := make([]Point, 0, dist[t.Y][t.X])
+		for ; dist[t.Y][t.X] > 0; t = from[t.Y][t.X] {
+			path = append(path, t)
+		}
+		return path
+	}
+
+	type pair struct{ a, b int }
+	type meeting struct{ p, n Point }
+	best := make(map[pair]meeting)
+	order := make([]pair, 0)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, o := range polarOffsets {
+			n := Point{X: p.X + o.X, Y: p.Y + o.Y}
+			if !world.walkable(n.X, n.Y) {
+				continue
+			}
+			if owner[n.Y][n.X] == -1 {
+				owner[n.Y][n.X] = owner[p.Y][p.X]
+				dist[n.Y][n.X] = dist[p.Y][p.X] + 1
+				from[n.Y][n.X] = p
+				queue = append(queue, n)
+				continue
+			}
+			a, b := owner[p.Y][p.X], owner[n.Y][n.X]
+			if a == b || dist[p.Y][p.X]+dist[n.Y][n.X] == 0 {
+				continue
+			}
+			m := meeting{p: p, n: n}
+			if a > b {
+				a, b = b, a
+				m = meeting{p: n, n: p}
+			}
+			k := pair{a: a, b: b}
+			old, ok := best[k]
+			if !ok {
+				order = append(order, k)
+			} else if dist[old.p.Y][old.p.X]+dist[old.n.Y][old.n.X] <= dist[m.p.Y][m.p.X]+dist[m.n.Y][m.n.X] {
+				continue
+			}
+			best[k] = m
+		}
+	}
+
+	found := make([]passage, 0, len(order))
+	for _, k := range order {
+		m := best[k]
+		side := trace(m.p)
+		path := make([]Point, 0, len(side)+dist[m.n.Y][m.n.X])
+		for i := len(side) - 1; i >= 0; i-- {
+			path = append(path, side[i])
+		}
+		path = append(path, trace(m.n)...)
+		start := m.p
+		if len(side) > 0 {
+			start = from[path[0].Y][path[0].X]
+		}
+		found = append(found, passage{a: k.a, b: k.b, path: path, from: start})
+	}
+	return found
+}
+
+// rectFinder finds the largest rectangle of free tiles over and over as tiles stop being free, only measuring again
+// the rows which changed. Each row remembers the largest rectangle with its bottom edge on that row
+type rectFinder struct {
+	w, h       int
+	free       func(x, y int) bool
+	minW, minH int
+	heights    [][]int // free tiles in a column ending at each tile, with an extra 0 column
+	rowBest    []Rect
+	rowFound   []bool
+}
+
+// newRectFinder measures every row of a w x h map
+func newRectFinder(w, h int, free func(x, y int) bool, minW, minH int) *rectFinder {
+	f := &rectFinder{
+		w:        w,
+		h:        h,
+		free:     free,
+		minW:     minW,
+		minH:     minH,
+		heights:  make([][]int, h),
+		rowBest:  make([]Rect, h),
+		rowFound: make([]bool, h),
+	}
+	for y := range f.heights {
+		f.heights[y] = make([]int, w+1)
+	}
+	f.update(Rect{W: w, H: h})
+	return f
+}
+
+// update measures the columns of area again, and the rows whose columns changed
+func (f *rectFinder) update(area Rect) {
+	x0, x1 := maxInt(area.X, 0), minInt(area.X+area.W, f.w)
+	y0 := maxInt(area.Y, 0)
+	last := y0 - 1
+	for x := x0; x < x1; x++ {
+		for y := y0; y < f.h; y++ {
+			h := 0
+			if f.free(x, y) {
+				h = 1
+				if y > 0 {
+					h += f.heights[y-1][x]
+				}
+			}
+			if h == f.heights[y][x] {
+				// Below area nothing else changes the column
+				if y >= area.Y+area.H {
+					break
+				}
+				continue
+			}
+			f.heights[y][x] = h
+			last = maxInt(last, y)
+		}
+	}
+	for y := y0; y <= last; y++ {
+		f.measureRow(y)
+	}
+}
+
+// measureRow finds the largest rectangle with its bottom edge on row y
+func (f *rectFinder) measureRow(y int) {
+	type bar struct {
+		x, h int
+	}
+	var best Rect
+	found := false
+	stack := make([]bar, 0)
+	for x := 0; x <= f.w; x++ {
+		h := f.heights[y][x]
+		if h < f.minH {
+			h = 0
+		}
+		start := x
+		for len(stack) > 0 && stack[len(stack)-1].h >= h {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if w := x - top.x; top.h > 0 && w >= f.minW && w*top.h > best.W*best.H {
+				best = Rect{X: top.x, Y: y - top.h + 1, W: w, H: top.h}
+				found = true
+			}
+			start = top.x
+		}
+		stack = append(stack, bar{x: start, h: h})
+	}
+	f.rowBest[y], f.rowFound[y] = best, found
+}
+
+// largest returns the largest rectangle of free tiles which is at least minW by minH, the topmost if there's a tie
+func (f *rectFinder) largest() (Rect, bool) {
+	var best Rect
+	found := false
+	for y := 0; y < f.h; y++ {
+		if r := f.rowBest[y]; f.rowFound[y] && r.W*r.H > best.W*best.H {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,102 @@
+package generate
+
+import (
+	"math"
+	"sort"
+)
+
+// Landmark is a spot for something the player should notice from afar, such as a statue, shrine or fast travel point
+type Landmark struct {
+	Point  Point
+	Room   Rect // the room the landmark is in, if InRoom
+	InRoom bool
+	Tags   []Tag // TagLandmark, add more to say what goes there
+}
+
+// landmarkMinSpacing is the closest two landmarks are ever placed
+const landmarkMinSpacing = 2
+
+// PlaceLandmarks picks n floor tiles spread evenly over the map with blue noise sampling, keeping away from doors and
+// corridors. Every tile is given a fixed random priority and the tiles are taken in that order, skipping any too
+// close to one already taken, with the spacing shrinking until n fit. Priorities don't depend on the rest of the map,
+// so small edits elsewhere leave the landmarks where they were. The landmarks replace any placed before and are
+// stored in world.Landmarks, rooms they're in are tagged with TagLandmark. ErrNotEnoughSpace is returned along with
+// the landmarks which fit if there's no room for n of them
+func (world *World) PlaceLandmarks(n int) ([]Landmark, error) {
+	world.Landmarks = nil
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// Doorways are kept clear a tile either side so landmarks never get in the way
+	nearDoor := make(map[Point]bool)
+	for door := range world.Doors {
+		for y := door.Y - 1; y <= door.Y+door.H; y++ {
+			for x := door.X - 1; x <= door.X+door.W; x++ {
+				nearDoor[Point{X: x, Y: y}] = true
+			}
+		}
+	}
+	noise := newNoise(world.rng)
+	candidates := make([]Point, 0)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			p := Point{X: x, Y: y}
+			if world.Tiles[y][x] == TileFloor && world.FloorKinds[y][x] != FloorKindCorridor && !nearDoor[p] {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNotEnoughSpace
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return noise.hash(candidates[i].X, candidates[i].Y) < noise.hash(candidates[j].X, candidates[j].Y)
+	})
+
+	// Start from the spacing of n points packed evenly over the floor
+	spacing := math.Sqrt(float64(len(candidates)) / float64(n) * 2 / math.Sqrt(3))
+	var picked []Point
+	for ; ; spacing *= 0.9 {
+		spacing = math.Max(spacing, landmarkMinSpacing)
+		picked = blueNoise(candidates, n, spacing)
+		if len(picked) == n || spacing == landmarkMinSpacing {
+			break
+		}
+	}
+
+	landmarks := make([]Landmark, 0, len(picked))
+	for _, p := range picked {
+		room, in := world.RoomAt(p.X, p.Y)
+		landmarks = append(landmarks, Landmark{Point: p, Room: room, InRoom: in, Tags: []Tag{TagLandmark}})
+		if in {
+			world.TagRoom(room, TagLandmark)
+		}
+	}
+	world.Landmarks = landmarks
+	if len(landmarks) < n {
+		return landmarks, ErrNotEnoughSpace
+	}
+	return landmarks, nil
+}
+
+// blueNoise takes up to n of candidates in order, skipping any closer than spacing to one already taken
+func blueNoise(candidates []Point, n int, spacing float64) []Point {
+	picked := make([]Point, 0, n)
+	for _, p := range candidates {
+		clear := true
+		for _, q := range picked {
+			if p.distance(q) < spacing {
+				clear = false
+				break
+			}
+		}
+		if clear {
+			picked = append(picked, p)
+			if len(picked) == n {
+				break
+			}
+		}
+	}
+	return picked
+}
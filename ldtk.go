@@ -0,0 +1,537 @@
+package generate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrNoLDtkLevel is returned by ReadLDtk when the project has no level with the layers WriteLDtk writes
+var ErrNoLDtkLevel = errors.New("LDtk project has no level with a Tiles layer")
+
+// LDtkOptions controls WriteLDtk
+type LDtkOptions struct {
+	GridSize int    // pixels per world tile in the editor
+	Level    string // identifier of the level, it must start with a letter and only use letters, digits and _
+}
+
+// DefaultLDtkOptions returns options for 16 pixel tiles in a level called Level_0, LDtk's defaults
+func DefaultLDtkOptions() LDtkOptions {
+	return LDtkOptions{
+		GridSize: 16,
+		Level:    "Level_0",
+	}
+}
+
+// Layer and entity identifiers written by WriteLDtk and looked for by ReadLDtk
+const (
+	ldtkTilesLayer    = "Tiles"
+	ldtkEntitiesLayer = "Entities"
+	ldtkRoom          = "Room"
+	ldtkDoor          = "Door"
+	ldtkLandmark      = "Landmark"
+	ldtkVersion       = "1.5.3"
+)
+
+// ldtkProject is the part of an LDtk project file WriteLDtk writes and ReadLDtk reads
+type ldtkProject struct {
+	Header          ldtkHeader  `json:"__header__"`
+	Iid             string      `json:"iid"`
+	JSONVersion     string      `json:"jsonVersion"`
+	NextUID         int         `json:"nextUid"`
+	IdentifierStyle string      `json:"identifierStyle"`
+	WorldLayout     string      `json:"worldLayout"`
+	DefaultGridSize int         `json:"defaultGridSize"`
+	BgColor         string      `json:"bgColor"`
+	LevelBgColor    string      `json:"defaultLevelBgColor"`
+	ExternalLevels  bool        `json:"externalLevels"`
+	Flags           []string    `json:"flags"`
+	Toc             []string    `json:"toc"`
+	Worlds          []string    `json:"worlds"`
+	Defs            ldtkDefs    `json:"defs"`
+	Levels          []ldtkLevel `json:"levels"`
+}
+
+// ldtkHeader is the __header__ LDtk writes at the top of every project
+type ldtkHeader struct {
+	FileType   string `json:"fileType"`
+	App        string `json:"app"`
+	Doc        string `json:"doc"`
+	Schema     string `json:"schema"`
+	AppVersion string `json:"appVersion"`
+	URL        string `json:"url"`
+}
+
+// ldtkDefs holds the definitions of the project's layers and entities
+type ldtkDefs struct {
+	Layers        []ldtkLayerDef  `json:"layers"`
+	Entities      []ldtkEntityDef `json:"entities"`
+	Tilesets      []string        `json:"tilesets"`
+	Enums         []string        `json:"enums"`
+	ExternalEnums []string        `json:"externalEnums"`
+	LevelFields   []string        `json:"levelFields"`
+}
+
+// ldtkLayerDef defines a layer
+type ldtkLayerDef struct {
+	Type           string             `json:"__type"`
+	Identifier     string             `json:"identifier"`
+	LayerType      string             `json:"type"`
+	UID            int                `json:"uid"`
+	GridSize       int                `json:"gridSize"`
+	DisplayOpacity float64            `json:"displayOpacity"`
+	IntGridValues  []ldtkIntGridValue `json:"intGridValues"`
+	ValuesGroups   []string           `json:"intGridValuesGroups"`
+	AutoRuleGroups []string           `json:"autoRuleGroups"`
+	RequiredTags   []string           `json:"requiredTags"`
+	ExcludedTags   []string           `json:"excludedTags"`
+	TilesetDefUID  *int               `json:"tilesetDefUid"`
+}
+
+// ldtkIntGridValue is one of the values an IntGrid layer can hold
+type ldtkIntGridValue struct {
+	Value      int    `json:"value"`
+	Identifier string `json:"identifier"`
+	Color      string `json:"color"`
+	GroupUID   int    `json:"groupUid"`
+}
+
+// ldtkEntityDef defines an entity
+type ldtkEntityDef struct {
+	Identifier     string         `json:"identifier"`
+	UID            int            `json:"uid"`
+	Width          int            `json:"width"`
+	Height         int            `json:"height"`
+	ResizableX     bool           `json:"resizableX"`
+	ResizableY     bool           `json:"resizableY"`
+	Color          string         `json:"color"`
+	RenderMode     string         `json:"renderMode"`
+	TileRenderMode string         `json:"tileRenderMode"`
+	Hollow         bool           `json:"hollow"`
+	ShowName       bool           `json:"showName"`
+	FillOpacity    float64        `json:"fillOpacity"`
+	LineOpacity    float64        `json:"lineOpacity"`
+	LimitScope     string         `json:"limitScope"`
+	LimitBehavior  string         `json:"limitBehavior"`
+	Tags           []string       `json:"tags"`
+	FieldDefs      []ldtkFieldDef `json:"fieldDefs"`
+}
+
+// ldtkFieldDef defines a field of an entity
+type ldtkFieldDef struct {
+	Identifier        string `json:"identifier"`
+	Type              string `json:"__type"`
+	UID               int    `json:"uid"`
+	FieldType         string `json:"type"`
+	IsArray           bool   `json:"isArray"`
+	CanBeNull         bool   `json:"canBeNull"`
+	EditorDisplayMode string `json:"editorDisplayMode"`
+	EditorDisplayPos  string `json:"editorDisplayPos"`
+}
+
+// ldtkLevel is a level of the project
+type ldtkLevel struct {
+	Identifier     string              `json:"identifier"`
+	Iid            string              `json:"iid"`
+	UID            int                 `json:"uid"`
+	WorldX         int                 `json:"worldX"`
+	WorldY         int                 `json:"worldY"`
+	PxWid          int                 `json:"pxWid"`
+	PxHei          int                 `json:"pxHei"`
+	BgColor        string              `json:"__bgColor"`
+	FieldInstances []ldtkFieldInstance `json:"fieldInstances"`
+	LayerInstances []ldtkLayerInstance `json:"layerInstances"`
+	Neighbours     []string            `json:"__neighbours"`
+}
+
+// ldtkLayerInstance is a layer of a level
+type ldtkLayerInstance struct {
+	Identifier      string               `json:"__identifier"`
+	Type            string               `json:"__type"`
+	CWid            int                  `json:"__cWid"`
+	CHei            int                  `json:"__cHei"`
+	GridSize        int                  `json:"__gridSize"`
+	Opacity         float64              `json:"__opacity"`
+	Iid             string               `json:"iid"`
+	LevelID         int                  `json:"levelId"`
+	LayerDefUID     int                  `json:"layerDefUid"`
+	Visible         bool                 `json:"visible"`
+	IntGridCsv      []int                `json:"intGridCsv"`
+	AutoLayerTiles  []string             `json:"autoLayerTiles"`
+	GridTiles       []string             `json:"gridTiles"`
+	EntityInstances []ldtkEntityInstance `json:"entityInstances"`
+	OptionalRules   []string             `json:"optionalRules"`
+}
+
+// ldtkEntityInstance is an entity placed on an Entities layer
+type ldtkEntityInstance struct {
+	Identifier     string              `json:"__identifier"`
+	Grid           [2]int              `json:"__grid"`
+	Pivot          [2]float64          `json:"__pivot"`
+	Tags           []string            `json:"__tags"`
+	Iid            string              `json:"iid"`
+	Width          int                 `json:"width"`
+	Height         int                 `json:"height"`
+	DefUID         int                 `json:"defUid"`
+	Px             [2]int              `json:"px"`
+	FieldInstances []ldtkFieldInstance `json:"fieldInstances"`
+}
+
+// ldtkFieldInstance is the value of a field of an entity
+type ldtkFieldInstance struct {
+	Identifier       string            `json:"__identifier"`
+	Type             string            `json:"__type"`
+	Value            json.RawMessage   `json:"__value"`
+	DefUID           int               `json:"defUid"`
+	RealEditorValues []json.RawMessage `json:"realEditorValues"`
+}
+
+// ldtkFields are the fields of each entity WriteLDtk writes, tags are string arrays and everything else strings
+var ldtkFields = map[string][]string{
+	ldtkRoom:     {"Name", "Tags"},
+	ldtkDoor:     {"Direction", "Tags"},
+	ldtkLandmark: {"Tags"},
+}
+
+// ldtkDoorDirections are the values of a door's Direction field
+var ldtkDoorDirections = map[DoorDirection]string{
+	DoorDirectionHorizontal: "horizontal",
+	DoorDirectionVertical:   "vertical",
+}
+
+// WriteLDtk writes the world as an LDtk project with a single level, so designers can open generated maps in the LDtk
+// editor and touch them up by hand. The tiles are an IntGrid layer called Tiles, where each value is the Tile number
+// (void is empty), and an Entities layer holds a Room entity for each room with its name and tags, a Door entity for
+// each door with its direction and tags, and a Landmark entity for each landmark. Read the edited project back with
+// ReadLDtk. Other structure such as corridors, sectors and decoration layers isn't written. ErrInvalidFactor is
+// returned if opts.GridSize is less than 1
+func (world *World) WriteLDtk(w io.Writer, opts LDtkOptions) error {
+	if opts.GridSize < 1 {
+		return ErrInvalidFactor
+	}
+	uid := 0
+	nextUID := func() int {
+		uid++
+		return uid
+	}
+	iid := func(n int) string {
+		return fmt.Sprintf("00000000-0000-0000-0000-%012x", n)
+	}
+
+	// Every tile which has a style gets a value, plus any user tiles in the world
+	used := make(map[Tile]bool)
+	for t := range tileStyles {
+		used[t] = true
+	}
+	for y := range world.Tiles {
+		for _, t := range world.Tiles[y] {
+			used[t] = true
+		}
+	}
+	delete(used, TileVoid)
+	tiles := make([]Tile, 0, len(used))
+	for t := range used {
+		tiles = append(tiles, t)
+	}
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i] < tiles[j] })
+	values := make([]ldtkIntGridValue, 0, len(tiles))
+	for _, t := range tiles {
+		style, ok := tileStyles[t]
+		if !ok {
+			style = tileStyle{name: fmt.Sprintf("user tile %d", t-TileUser), bg: 226}
+		}
+		c := ansiColor(style.bg)
+		values = append(values, ldtkIntGridValue{
+			Value:      int(t),
+			Identifier: ldtkIdentifier(style.name),
+			Color:      fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B),
+		})
+	}
+
+	tilesDef := ldtkLayerDef{
+		Type: "IntGrid", Identifier: ldtkTilesLayer, LayerType: "IntGrid", UID: nextUID(), GridSize: opts.GridSize,
+		DisplayOpacity: 1, IntGridValues: values,
+	}
+	entitiesDef := ldtkLayerDef{
+		Type: "Entities", Identifier: ldtkEntitiesLayer, LayerType: "Entities", UID: nextUID(),
+		GridSize: opts.GridSize, DisplayOpacity: 1, IntGridValues: []ldtkIntGridValue{},
+	}
+	for _, def := range []*ldtkLayerDef{&tilesDef, &entitiesDef} {
+		def.ValuesGroups, def.AutoRuleGroups = []string{}, []string{}
+		def.RequiredTags, def.ExcludedTags = []string{}, []string{}
+	}
+
+	entityDefs := make(map[string]ldtkEntityDef)
+	for _, e := range []struct {
+		name  string
+		color string
+	}{{ldtkRoom, "#94D9B3"}, {ldtkDoor, "#FFCC00"}, {ldtkLandmark, "#FF6A6A"}} {
+		def := ldtkEntityDef{
+			Identifier: e.name, UID: nextUID(), Width: opts.GridSize, Height: opts.GridSize,
+			ResizableX: e.name != ldtkLandmark, ResizableY: e.name != ldtkLandmark, Color: e.color,
+			RenderMode: "Rectangle", TileRenderMode: "FitInside", Hollow: e.name == ldtkRoom, ShowName: true,
+			FillOpacity: 0.08, LineOpacity: 1, LimitScope: "PerLevel", LimitBehavior: "MoveLastOne",
+			Tags: []string{}, FieldDefs: []ldtkFieldDef{},
+		}
+		for _, field := range ldtkFields[e.name] {
+			fd := ldtkFieldDef{
+				Identifier: field, Type: "String", UID: nextUID(), FieldType: "F_String", CanBeNull: true,
+				EditorDisplayMode: "ValueOnly", EditorDisplayPos: "Above",
+			}
+			if field == "Tags" {
+				fd.Type, fd.IsArray = "Array<String>", true
+			}
+			def.FieldDefs = append(def.FieldDefs, fd)
+		}
+		entityDefs[e.name] = def
+	}
+
+	levelUID := nextUID()
+	entities := make([]ldtkEntityInstance, 0)
+	entity := func(name string, r Rect, fields map[string]interface{}) error {
+		def := entityDefs[name]
+		e := ldtkEntityInstance{
+			Identifier: name, Grid: [2]int{r.X, r.Y}, Tags: []string{}, Iid: iid(nextUID()),
+			Width: r.W * opts.GridSize, Height: r.H * opts.GridSize, DefUID: def.UID,
+			Px: [2]int{r.X * opts.GridSize, r.Y * opts.GridSize}, FieldInstances: []ldtkFieldInstance{},
+		}
+		for _, fd := range def.FieldDefs {
+			f, err := ldtkField(fd, fields[fd.Identifier])
+			if err != nil {
+				return err
+			}
+			e.FieldInstances = append(e.FieldInstances, f)
+		}
+		entities = append(entities, e)
+		return nil
+	}
+	tagStrings := func(tags []Tag) []string {
+		s := make([]string, len(tags))
+		for i, t := range tags {
+			s[i] = string(t)
+		}
+		return s
+	}
+
+	for _, room := range world.RoomsOrdered() {
+		err := entity(ldtkRoom, room, map[string]interface{}{
+			"Name": world.RoomNames[room],
+			"Tags": tagStrings(world.RoomTags[room]),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	doors := make([]Rect, 0, len(world.Doors))
+	for door := range world.Doors {
+		doors = append(doors, door)
+	}
+	sortRects(doors)
+	for _, door := range doors {
+		err := entity(ldtkDoor, door, map[string]interface{}{
+			"Direction": ldtkDoorDirections[world.Doors[door]],
+			"Tags":      tagStrings(world.DoorTags[door]),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, l := range world.Landmarks {
+		err := entity(ldtkLandmark, Rect{X: l.Point.X, Y: l.Point.Y, W: 1, H: 1}, map[string]interface{}{
+			"Tags": tagStrings(l.Tags),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csv := make([]int, 0, world.Width*world.Height)
+	for y := range world.Tiles {
+		for _, t := range world.Tiles[y] {
+			csv = append(csv, int(t))
+		}
+	}
+	layer := func(def ldtkLayerDef) ldtkLayerInstance {
+		return ldtkLayerInstance{
+			Identifier: def.Identifier, Type: def.Type, CWid: world.Width, CHei: world.Height,
+			GridSize: opts.GridSize, Opacity: 1, Iid: iid(nextUID()), LevelID: levelUID, LayerDefUID: def.UID,
+			Visible: true, IntGridCsv: []int{}, AutoLayerTiles: []string{}, GridTiles: []string{},
+			EntityInstances: []ldtkEntityInstance{}, OptionalRules: []string{},
+		}
+	}
+	// LDtk lists layers from the top down
+	entitiesLayer, tilesLayer := layer(entitiesDef), layer(tilesDef)
+	entitiesLayer.EntityInstances = entities
+	tilesLayer.IntGridCsv = csv
+
+	project := ldtkProject{
+		Header: ldtkHeader{
+			FileType: "LDtk Project JSON", App: "LDtk", Doc: "https://ldtk.io/json",
+			Schema: "https://ldtk.io/files/JSON_SCHEMA.json", AppVersion: ldtkVersion, URL: "https://ldtk.io",
+		},
+		Iid: iid(nextUID()), JSONVersion: ldtkVersion, IdentifierStyle: "Capitalize", WorldLayout: "Free",
+		DefaultGridSize: opts.GridSize, BgColor: "#40465B", LevelBgColor: "#696A79",
+		Flags: []string{}, Toc: []string{}, Worlds: []string{},
+		Defs: ldtkDefs{
+			Layers:   []ldtkLayerDef{entitiesDef, tilesDef},
+			Entities: []ldtkEntityDef{entityDefs[ldtkRoom], entityDefs[ldtkDoor], entityDefs[ldtkLandmark]},
+			Tilesets: []string{}, Enums: []string{}, ExternalEnums: []string{}, LevelFields: []string{},
+		},
+		Levels: []ldtkLevel{{
+			Identifier: opts.Level, Iid: iid(nextUID()), UID: levelUID,
+			PxWid: world.Width * opts.GridSize, PxHei: world.Height * opts.GridSize, BgColor: "#696A79",
+			FieldInstances: []ldtkFieldInstance{}, LayerInstances: []ldtkLayerInstance{entitiesLayer, tilesLayer},
+			Neighbours: []string{},
+		}},
+	}
+	project.NextUID = nextUID()
+
+	return json.NewEncoder(w).Encode(project)
+}
+
+// ldtkField returns the instance of the field fd holding value, which is a string or []string. Empty strings are null
+func ldtkField(fd ldtkFieldDef, value interface{}) (ldtkFieldInstance, error) {
+	f := ldtkFieldInstance{Identifier: fd.Identifier, Type: fd.Type, DefUID: fd.UID}
+	if value == "" {
+		value = nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return f, err
+	}
+	f.Value = raw
+
+	// realEditorValues holds how the editor shows each value, null for a null value
+	editorValue := func(s string) (json.RawMessage, error) {
+		return json.Marshal(map[string]interface{}{"id": "V_String", "params": []string{s}})
+	}
+	f.RealEditorValues = []json.RawMessage{}
+	switch v := value.(type) {
+	case nil:
+		f.RealEditorValues = append(f.RealEditorValues, json.RawMessage("null"))
+	case string:
+		ev, err := editorValue(v)
+		if err != nil {
+			return f, err
+		}
+		f.RealEditorValues = append(f.RealEditorValues, ev)
+	case []string:
+		for _, s := range v {
+			ev, err := editorValue(s)
+			if err != nil {
+				return f, err
+			}
+			f.RealEditorValues = append(f.RealEditorValues, ev)
+		}
+	}
+	return f, nil
+}
+
+// ldtkIdentifier turns a tile name like "low wall" into an LDtk identifier like "Low_wall"
+func ldtkIdentifier(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+	if id == "" {
+		return "Tile"
+	}
+	return strings.ToUpper(id[:1]) + id[1:]
+}
+
+// ReadLDtk replaces the world with the first level of an LDtk project which has an IntGrid layer called Tiles, such as
+// one written by WriteLDtk and edited by hand. The world is resized to the level, its tiles are set from the Tiles
+// layer and any Room, Door and Landmark entities on its Entities layer become rooms, doors and landmarks again, with
+// their tags, names and directions. Corridors aren't kept, and rooms which were made by hand don't have any. Tiles
+// without a value (0) are void. ErrNoLDtkLevel is returned if no level has a Tiles layer
+func (world *World) ReadLDtk(r io.Reader) error {
+	var project ldtkProject
+	if err := json.NewDecoder(r).Decode(&project); err != nil {
+		return err
+	}
+	var tiles *ldtkLayerInstance
+	var entities []ldtkEntityInstance
+	for _, level := range project.Levels {
+		for i, layer := range level.LayerInstances {
+			switch {
+			case layer.Identifier == ldtkTilesLayer && layer.Type == "IntGrid":
+				tiles = &level.LayerInstances[i]
+			case layer.Identifier == ldtkEntitiesLayer && layer.Type == "Entities":
+				entities = layer.EntityInstances
+			}
+		}
+		if tiles != nil {
+			break
+		}
+		entities = nil
+	}
+	if tiles == nil || tiles.CWid < 1 || tiles.CHei < 1 || len(tiles.IntGridCsv) != tiles.CWid*tiles.CHei {
+		return ErrNoLDtkLevel
+	}
+
+	world.Width, world.Height = tiles.CWid, tiles.CHei
+	world.ResetWorld(world.Width, world.Height)
+	for i, v := range tiles.IntGridCsv {
+		world.SetTile(i%world.Width, i/world.Width, Tile(v))
+	}
+
+	grid := maxInt(tiles.GridSize, 1)
+	landmarks := make([]Landmark, 0)
+	for _, e := range entities {
+		rect := Rect{X: e.Grid[0], Y: e.Grid[1], W: maxInt(e.Width/grid, 1), H: maxInt(e.Height/grid, 1)}
+		if rect.X < 0 || rect.Y < 0 || rect.X+rect.W > world.Width || rect.Y+rect.H > world.Height {
+			return ErrOutOfBounds
+		}
+		var name, direction string
+		var tags []Tag
+		for _, f := range e.FieldInstances {
+			var err error
+			switch f.Identifier {
+			case "Name":
+				err = json.Unmarshal(f.Value, &name)
+			case "Direction":
+				err = json.Unmarshal(f.Value, &direction)
+			case "Tags":
+				var s []string
+				err = json.Unmarshal(f.Value, &s)
+				for _, t := range s {
+					tags = append(tags, Tag(t))
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		switch e.Identifier {
+		case ldtkRoom:
+			world.addRoom(rect)
+			world.TagRoom(rect, tags...)
+			if name != "" {
+				world.RoomNames[rect] = name
+			}
+		case ldtkDoor:
+			dir := DoorDirectionHorizontal
+			if direction == ldtkDoorDirections[DoorDirectionVertical] {
+				dir = DoorDirectionVertical
+			}
+			world.Doors[rect] = dir
+			world.TagDoor(rect, tags...)
+		case ldtkLandmark:
+			landmarks = append(landmarks, Landmark{Point: Point{X: rect.X, Y: rect.Y}, Tags: tags})
+		}
+	}
+	// Landmarks are matched to rooms once every room is in, as they can come before their room
+	for i, l := range landmarks {
+		landmarks[i].Room, landmarks[i].InRoom = world.RoomAt(l.Point.X, l.Point.Y)
+	}
+	if len(landmarks) > 0 {
+		world.Landmarks = landmarks
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package generate
+
+import "time"
+
+// mazeGrid is the lattice of square cells a maze is carved over, with walls between neighbouring cells
+type mazeGrid struct {
+	w, h   int // cells across and down
+	margin int // tiles from the edge of the map to the first cell
+	size   int // width of each cell and the passages between them
+	step   int // tiles from one cell to the next
+}
+
+// newMazeGrid fits as many cells world.MaxCorridorSize wide as it can inside world.Border, leaving room for walls
+// world.WallThickness thick between the cells and around the outside
+func (world *World) newMazeGrid() (mazeGrid, error) {
+	wt := wallGap(world.WallThickness)
+	g := mazeGrid{size: maxInt(world.MaxCorridorSize, 1)}
+	g.margin = world.Border + wt
+	if world.FlushRooms {
+		g.margin = maxInt(world.Border, wt)
+	}
+	g.step = g.size + wt
+	g.w = (world.Width - g.margin*2 + wt) / g.step
+	g.h = (world.Height - g.margin*2 + wt) / g.step
+	if g.w < 1 || g.h < 1 {
+		return g, ErrNotEnoughSpace
+	}
+	return g, nil
+}
+
+// cell returns the tiles of the cell at c
+func (g mazeGrid) cell(c Point) Rect {
+	return Rect{X: g.margin + c.X*g.step, Y: g.margin + c.Y*g.step, W: g.size, H: g.size}
+}
+
+// neighbors returns the cells next to c in the 4 polar directions
+func (g mazeGrid) neighbors(c Point) []Point {
+	cells := make([]Point, 0, 4)
+	for _, d := range [4]Direction{DirectionNorth, DirectionEast, DirectionSouth, DirectionWest} {
+		n := Point{X: c.X + d.Dx(), Y: c.Y + d.Dy()}
+		if n.X >= 0 && n.Y >= 0 && n.X < g.w && n.Y < g.h {
+			cells = append(cells, n)
+		}
+	}
+	return cells
+}
+
+// carveMazeCell turns the cell at c into corridor floor
+func (world *World) carveMazeCell(g mazeGrid, c Point) {
+	r := g.cell(c)
+	for y := r.Y; y < r.Y+r.H; y++ {
+		for x := r.X; x < r.X+r.W; x++ {
+			world.setFloor(x, y, FloorKindCorridor)
+		}
+	}
+}
+
+// linkMazeCells carves the passage between the neighbouring cells a and b, and both cells, recording it as a corridor
+func (world *World) linkMazeCells(g mazeGrid, a, b Point) {
+	ra, rb := g.cell(a), g.cell(b)
+	area := Rect{X: minInt(ra.X, rb.X), Y: minInt(ra.Y, rb.Y)}
+	area.W = maxInt(ra.X, rb.X) + g.size - area.X
+	area.H = maxInt(ra.Y, rb.Y) + g.size - area.Y
+	for y := area.Y; y < area.Y+area.H; y++ {
+		for x := area.X; x < area.X+area.W; x++ {
+			world.setFloor(x, y, FloorKindCorridor)
+		}
+	}
+	dir := DoorDirectionHorizontal
+	if a.Y == b.Y {
+		dir = DoorDirectionVertical
+	}
+	world.addStraightCorridor(area, dir, Rect{})
+}
+
+// GenerateMaze generates the world as a perfect maze, with exactly one way between any two tiles, filling the area
+// inside world.Border. The maze is carved by a recursive backtracker: a random walk which goes on to a random cell it
+// hasn't visited yet, stepping back along its path whenever it's boxed in, which makes long winding passages with few
+// dead ends. Passages are world.MaxCorridorSize wide with walls world.WallThickness thick between them, and each
+// passage between two cells is recorded in world.Corridors. There are no rooms, use GenerateMixed to put maze sections
+// between rooms. ErrNotEnoughSpace is returned if not even one cell fits
+func (world *World) GenerateMaze(overrides ...Option) error {
+	return world.generateMaze("Maze", overrides, func(g mazeGrid, start Point, visited [][]bool) {
+		stack := []Point{start}
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			options := make([]Point, 0, 4)
+			for _, n := range g.neighbors(c) {
+				if !visited[n.Y][n.X] {
+					options = append(options, n)
+				}
+			}
+			if len(options) == 0 {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			n := options[world.rng.Intn(len(options))]
+			world.linkMazeCells(g, c, n)
+			visited[n.Y][n.X] = true
+			stack = append(stack, n)
+		}
+	})
+}
+
+// GenerateMazePrim generates the world as a perfect maze like GenerateMaze, but grown with randomized Prim's
+// algorithm: the maze spreads out from its first cell by joining a random passage from anywhere along its edge each
+// step, rather than following one long walk. This gives a maze with shorter passages and many more short dead ends
+func (world *World) GenerateMazePrim(overrides ...Option) error {
+	return world.generateMaze("MazePrim", overrides, func(g mazeGrid, start Point, visited [][]bool) {
+		type edge struct{ from, to Point }
+		frontier := make([]edge, 0)
+		grow := func(c Point) {
+			for _, n := range g.neighbors(c) {
+				if !visited[n.Y][n.X] {
+					frontier = append(frontier, edge{from: c, to: n})
+				}
+			}
+		}
+		grow(start)
+		for len(frontier) > 0 {
+			i := world.rng.Intn(len(frontier))
+			e := frontier[i]
+			frontier[i] = frontier[len(frontier)-1]
+			frontier = frontier[:len(frontier)-1]
+			if visited[e.to.Y][e.to.X] {
+				continue
+			}
+			world.linkMazeCells(g, e.from, e.to)
+			visited[e.to.Y][e.to.X] = true
+			grow(e.to)
+		}
+	})
+}
+
+// GenerateMazeWilson generates the world as a perfect maze like GenerateMaze, but with Wilson's algorithm: from each
+// cell not in the maze yet, a random walk wanders until it reaches the maze, forgetting any loops it makes, and the
+// path it ends up with is carved. Every possible maze is equally likely, so there's no bias towards long passages or
+// short dead ends like the other generators have, for maps where no layout should favour anyone. It's slower to
+// start as the first walks have to find a single cell
+func (world *World) GenerateMazeWilson(overrides ...Option) error {
+	return world.generateMaze("MazeWilson", overrides, func(g mazeGrid, start Point, visited [][]bool) {
+		// next remembers the last step the walk took from each cell, which erases loops as it's overwritten
+		next := make(map[Point]Point)
+		for y := 0; y < g.h; y++ {
+			for x := 0; x < g.w; x++ {
+				first := Point{X: x, Y: y}
+				for c := first; !visited[c.Y][c.X]; c = next[c] {
+					options := g.neighbors(c)
+					next[c] = options[world.rng.Intn(len(options))]
+				}
+				for c := first; !visited[c.Y][c.X]; c = next[c] {
+					world.linkMazeCells(g, c, next[c])
+					visited[c.Y][c.X] = true
+				}
+			}
+		}
+	})
+}
+
+// mazeCarver joins every cell of g into a maze, starting from start which is already carved and marked in visited
+type mazeCarver func(g mazeGrid, start Point, visited [][]bool)
+
+// generateMaze resets the world and runs carve over a maze grid from a random cell
+func (world *World) generateMaze(name string, overrides []Option, carve mazeCarver) (err error) {
+	defer world.override(overrides)()
+	world.beginReport(name, 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	g, err := world.newMazeGrid()
+	if err != nil {
+		return err
+	}
+
+	visited := make([][]bool, g.h)
+	for y := range visited {
+		visited[y] = make([]bool, g.w)
+	}
+	start := Point{X: world.rng.Intn(g.w), Y: world.rng.Intn(g.h)}
+	world.carveMazeCell(g, start)
+	visited[start.Y][start.X] = true
+	carve(g, start, visited)
+	return nil
+}
@@ -0,0 +1,147 @@
+package generate
+
+// MergeCorridors tidies up corridors which were carved next to each other, as happens with random corridor offsets,
+// so the map reads as clean passages. Single tiles of wall between corridor floor on both sides become floor, which
+// joins corridors running parallel a tile apart into one wider passage. Walls next to doors are kept so doorways keep
+// their frames, as are walls which would let the player past a locked door, gate or ledge. Then corridors joining the
+// same rooms whose floor now touches or overlaps are merged into the first of them: it takes the door of the other if
+// it has none and its width and path are measured again across the merged passage. The number of corridors merged
+// away is returned
+func (world *World) MergeCorridors() int {
+	world.fillCorridorGaps()
+
+	merged := 0
+	for i := 0; i < len(world.Corridors); i++ {
+		for j := i + 1; j < len(world.Corridors); j++ {
+			a, b := world.Corridors[i], world.Corridors[j]
+			if !sameRooms(a.Rooms, b.Rooms) || !world.corridorsTouch(a, b) {
+				continue
+			}
+			if _, ok := world.Doors[a.Door]; !ok {
+				a.Door = b.Door
+			}
+			world.Corridors[i] = world.remeasureCorridor(a)
+			world.Corridors = append(world.Corridors[:j], world.Corridors[j+1:]...)
+			merged++
+			j = i
+		}
+	}
+	return merged
+}
+
+// corridorFloor returns whether x,y is floor carved as part of a corridor
+func (world *World) corridorFloor(x, y int) bool {
+	if x < 0 || y < 0 || x >= world.Width || y >= world.Height {
+		return false
+	}
+	return world.Tiles[y][x] == TileFloor && world.FloorKinds[y][x] == FloorKindCorridor
+}
+
+// fillCorridorGaps turns single tiles of wall or void with corridor floor either side of them into corridor floor
+func (world *World) fillCorridorGaps() {
+	nearDoor := make(map[Point]bool)
+	for door := range world.Doors {
+		for y := door.Y - 1; y <= door.Y+door.H; y++ {
+			for x := door.X - 1; x <= door.X+door.W; x++ {
+				nearDoor[Point{X: x, Y: y}] = true
+			}
+		}
+	}
+	region := world.lockRegions()
+
+	gaps := make([]Point, 0)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if tile := world.Tiles[y][x]; tile != TileWall && tile != TileVoid && tile != TilePreWall {
+				continue
+			}
+			if _, in := world.RoomAt(x, y); in || nearDoor[Point{X: x, Y: y}] {
+				continue
+			}
+			for _, step := range [2]Point{{X: 1}, {Y: 1}} {
+				bx, by, ax, ay := x-step.X, y-step.Y, x+step.X, y+step.Y
+				if world.corridorFloor(bx, by) && world.corridorFloor(ax, ay) &&
+					region[by][bx] >= 0 && region[by][bx] == region[ay][ax] {
+					gaps = append(gaps, Point{X: x, Y: y})
+					break
+				}
+			}
+		}
+	}
+	if len(gaps) == 0 {
+		return
+	}
+	world.repairWalls(pointBounds(gaps), func() {
+		for _, p := range gaps {
+			world.setFloor(p.X, p.Y, FloorKindCorridor)
+		}
+	})
+}
+
+// sameRooms returns whether a and b hold the same rooms, in any order
+func sameRooms(a, b []Rect) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for _, r := range a {
+		found := false
+		for _, s := range b {
+			if r == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// corridorsTouch returns whether any floor of b is on or next to floor of a, or across a gap fillCorridorGaps filled
+func (world *World) corridorsTouch(a, b Corridor) bool {
+	near := make(map[Point]bool)
+	for _, t := range world.corridorTiles(a) {
+		near[t] = true
+		for _, o := range [4]Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			if world.corridorFloor(t.X+o.X, t.Y+o.Y) {
+				near[Point{X: t.X + o.X, Y: t.Y + o.Y}] = true
+			}
+		}
+	}
+	for _, t := range world.corridorTiles(b) {
+		for _, o := range [5]Point{{0, 0}, {-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			if near[Point{X: t.X + o.X, Y: t.Y + o.Y}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remeasureCorridor returns c with its width measured across the corridor floor at the middle of its path, moving the
+// path so it runs down the middle of that floor
+func (world *World) remeasureCorridor(c Corridor) Corridor {
+	across := Point{X: 1}
+	if world.Doors[c.Door] == DoorDirectionVertical {
+		across = Point{Y: 1}
+	}
+	mid := c.Path[len(c.Path)/2]
+	lo, hi := 0, 0
+	for world.corridorFloor(mid.X-across.X*(lo+1), mid.Y-across.Y*(lo+1)) {
+		lo++
+	}
+	for world.corridorFloor(mid.X+across.X*(hi+1), mid.Y+across.Y*(hi+1)) {
+		hi++
+	}
+	if width := lo + hi + 1; world.corridorFloor(mid.X, mid.Y) && width > c.Width {
+		shift := (width-1)/2 - lo
+		path := make([]Point, len(c.Path))
+		for i, p := range c.Path {
+			path[i] = Point{X: p.X + across.X*shift, Y: p.Y + across.Y*shift}
+		}
+		c.Path, c.Width = path, width
+		c.From, c.To = path[0], path[len(path)-1]
+	}
+	return c
+}
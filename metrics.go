@@ -0,0 +1,183 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metrics summarizes the shape of a generated world
+type Metrics struct {
+	FloorRatio float64 // walkable tiles / all tiles
+	Rooms      int
+	Doors      int
+	DeadEnds   int // rooms with a single connection
+	Loops      int // connections which could be removed without splitting the room graph
+	Diameter   int // walking distance between the two walkable tiles furthest apart, approximately
+}
+
+// Metrics measures the world
+func (world *World) Metrics() Metrics {
+	m := Metrics{
+		Rooms: len(world.Rooms),
+		Doors: len(world.Doors),
+	}
+
+	walkable := 0
+	var first Point
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if world.walkable(x, y) {
+				if walkable == 0 {
+					first = Point{X: x, Y: y}
+				}
+				walkable++
+			}
+		}
+	}
+	if world.Width*world.Height > 0 {
+		m.FloorRatio = float64(walkable) / float64(world.Width*world.Height)
+	}
+
+	// Two sweeps of BFS: the furthest tile from any tile is an end of a long path, the furthest from that approximates
+	// the diameter
+	if walkable > 0 {
+		far, _ := furthest(world.DistanceField(first))
+		_, m.Diameter = furthest(world.DistanceField(far))
+	}
+
+	g := world.BuildGraph()
+	degree := g.degrees()
+	for _, room := range g.Rooms {
+		if degree[room] == 1 {
+			m.DeadEnds++
+		}
+	}
+	if len(g.Rooms) > 0 {
+		m.Loops = len(g.Edges) - len(g.Rooms) + g.components()
+	}
+
+	return m
+}
+
+// furthest returns the point with the largest distance in dist and that distance
+func furthest(dist [][]int) (Point, int) {
+	var best Point
+	max := -1
+	for y, row := range dist {
+		for x, d := range row {
+			if d > max {
+				best, max = Point{X: x, Y: y}, d
+			}
+		}
+	}
+	return best, max
+}
+
+// components returns how many separate groups of rooms the graph has, ignoring OneWay
+func (g *Graph) components() int {
+	parent := make(map[Rect]Rect, len(g.Rooms))
+	var find func(r Rect) Rect
+	find = func(r Rect) Rect {
+		if p, ok := parent[r]; ok && p != r {
+			root := find(p)
+			parent[r] = root
+			return root
+		}
+		return r
+	}
+	count := len(g.Rooms)
+	for _, e := range g.Edges {
+		a, b := find(e.From), find(e.To)
+		if a != b {
+			parent[a] = b
+			count--
+		}
+	}
+	return count
+}
+
+// MetricTolerances is how far each metric can move before CompareMetrics reports it, as a fraction of the first
+// value (0.1 is 10%). Values of 0 or less aren't compared
+type MetricTolerances struct {
+	FloorRatio float64
+	Rooms      float64
+	Doors      float64
+	DeadEnds   float64
+	Loops      float64
+	Diameter   float64
+}
+
+// DefaultMetricTolerances returns tolerances which catch large changes without tripping on randomness when comparing
+// averages from AverageMetrics
+func DefaultMetricTolerances() MetricTolerances {
+	return MetricTolerances{
+		FloorRatio: 0.1,
+		Rooms:      0.1,
+		Doors:      0.15,
+		DeadEnds:   0.25,
+		Loops:      0.5,
+		Diameter:   0.2,
+	}
+}
+
+// MetricDiff is a metric which moved further than its tolerance
+type MetricDiff struct {
+	Name      string
+	A, B      float64
+	Tolerance float64
+}
+
+func (d MetricDiff) String() string {
+	return fmt.Sprintf("%s changed from %.3f to %.3f, more than %.0f%%", d.Name, d.A, d.B, d.Tolerance*100)
+}
+
+// CompareMetrics returns every metric which differs between a and b by more than its tolerance. Differences are
+// relative to a, or to 1 if a is smaller than that
+func CompareMetrics(a, b Metrics, tolerances MetricTolerances) []MetricDiff {
+	diffs := make([]MetricDiff, 0)
+	compare := func(name string, va, vb, tolerance float64) {
+		if tolerance <= 0 {
+			return
+		}
+		if math.Abs(vb-va)/math.Max(math.Abs(va), 1) > tolerance {
+			diffs = append(diffs, MetricDiff{Name: name, A: va, B: vb, Tolerance: tolerance})
+		}
+	}
+	// FloorRatio is already a fraction, so compare it as a percentage
+	compare("FloorRatio", a.FloorRatio*100, b.FloorRatio*100, tolerances.FloorRatio)
+	compare("Rooms", float64(a.Rooms), float64(b.Rooms), tolerances.Rooms)
+	compare("Doors", float64(a.Doors), float64(b.Doors), tolerances.Doors)
+	compare("DeadEnds", float64(a.DeadEnds), float64(b.DeadEnds), tolerances.DeadEnds)
+	compare("Loops", float64(a.Loops), float64(b.Loops), tolerances.Loops)
+	compare("Diameter", float64(a.Diameter), float64(b.Diameter), tolerances.Diameter)
+	return diffs
+}
+
+// AverageMetrics generates n worlds from cfg, so the result is the same for the same cfg.Seed, and returns their
+// average metrics rounded to the nearest whole number. Single worlds vary too much to be compared with CompareMetrics,
+// averages of a few dozen don't
+func AverageMetrics(cfg WorldConfig, n int) (Metrics, error) {
+	worlds, err := GenerateBatch(cfg, n, 0)
+	if err != nil {
+		return Metrics{}, err
+	}
+	var floor, rooms, doors, deadEnds, loops, diameter float64
+	for _, world := range worlds {
+		m := world.Metrics()
+		floor += m.FloorRatio
+		rooms += float64(m.Rooms)
+		doors += float64(m.Doors)
+		deadEnds += float64(m.DeadEnds)
+		loops += float64(m.Loops)
+		diameter += float64(m.Diameter)
+	}
+	count := float64(maxInt(len(worlds), 1))
+	return Metrics{
+		FloorRatio: floor / count,
+		Rooms:      int(math.Round(rooms / count)),
+		Doors:      int(math.Round(doors / count)),
+		DeadEnds:   int(math.Round(deadEnds / count)),
+		Loops:      int(math.Round(loops / count)),
+		Diameter:   int(math.Round(diameter / count)),
+	}, nil
+}
@@ -0,0 +1,292 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// ErrMissionTooLarge is returned when a mission grammar is still rewriting after MaxRewrites rewrites
+var ErrMissionTooLarge = errors.New("Mission grammar didn't finish rewriting")
+
+// Symbols rewritten by DefaultMissionGrammar, they never end up on rooms
+const (
+	SymbolMission  Tag = "mission"  // the whole dungeon
+	SymbolCycle    Tag = "cycle"    // two ways between the same rooms
+	SymbolObstacle Tag = "obstacle" // something dangerous in the way
+)
+
+// MissionRule rewrites a node of a mission graph which has the tag Symbol into the nodes and links of Result. Links
+// into the node go to Result's first node and links out of it leave from Result's Exit node, the node's other tags are
+// added to the first node. A link tagged TagLocked is opened by the nodes tagged TagKey of the same Result, both are
+// tagged with the key's name such as "key 2"
+type MissionRule struct {
+	Symbol Tag
+	Weight float64 // chance of being picked over the other rules for Symbol, 0 counts as 1
+	Result RoomGraph
+	Exit   int // node of Result which links out of the rewritten node leave from
+}
+
+// MissionGrammar rewrites a single node into the graph of rooms GenerateMission lays out, deciding where locks, keys
+// and danger go before any rooms are placed. Nodes are rewritten at random until none of their tags have rules, the
+// tags left are added to the rooms
+type MissionGrammar struct {
+	Start       Tag // tag of the node the mission starts as
+	Rules       []MissionRule
+	MaxRewrites int
+}
+
+// DefaultMissionGrammar returns a grammar in the style of cyclic dungeon generation: the entrance leads to a cycle
+// before the boss room. Cycles are either a door locked by a key found on a loop which drops back to the start, or two
+// dangerous ways to the same place, and can be followed by more cycles. Danger is TagArena rooms, some with a
+// TagTreasure room beside them
+func DefaultMissionGrammar() MissionGrammar {
+	return MissionGrammar{
+		Start:       SymbolMission,
+		MaxRewrites: 40,
+		Rules: []MissionRule{
+			{
+				Symbol: SymbolMission,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{Tags: []Tag{TagEntrance}}, {Tags: []Tag{SymbolCycle}}, {Tags: []Tag{TagBoss}}},
+					Links: []RoomLink{{From: 0, To: 1}, {From: 1, To: 2}},
+				},
+				Exit: 2,
+			},
+			// Lock and key
+			{
+				Symbol: SymbolCycle,
+				Weight: 2,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{}, {Tags: []Tag{SymbolObstacle}}, {Tags: []Tag{TagKey}}, {}},
+					Links: []RoomLink{
+						{From: 0, To: 1}, {From: 1, To: 2}, {From: 2, To: 0, OneWay: true},
+						{From: 0, To: 3, Tags: []Tag{TagLocked}},
+					},
+				},
+				Exit: 3,
+			},
+			// Two ways round
+			{
+				Symbol: SymbolCycle,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{}, {Tags: []Tag{SymbolObstacle}}, {Tags: []Tag{SymbolObstacle}}, {}},
+					Links: []RoomLink{{From: 0, To: 1}, {From: 1, To: 3}, {From: 0, To: 2}, {From: 2, To: 3}},
+				},
+				Exit: 3,
+			},
+			// One cycle after another
+			{
+				Symbol: SymbolCycle,
+				Weight: 0.5,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{Tags: []Tag{SymbolCycle}}, {Tags: []Tag{SymbolCycle}}},
+					Links: []RoomLink{{From: 0, To: 1}},
+				},
+				Exit: 1,
+			},
+			{
+				Symbol: SymbolObstacle,
+				Weight: 2,
+				Result: RoomGraph{Nodes: []RoomNode{{Tags: []Tag{TagArena}}}},
+			},
+			{
+				Symbol: SymbolObstacle,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{Tags: []Tag{TagArena}}, {Tags: []Tag{TagTreasure}}},
+					Links: []RoomLink{{From: 0, To: 1}},
+				},
+			},
+			{
+				Symbol: SymbolObstacle,
+				Weight: 0.5,
+				Result: RoomGraph{
+					Nodes: []RoomNode{{Tags: []Tag{SymbolObstacle}}, {Tags: []Tag{SymbolObstacle}}},
+					Links: []RoomLink{{From: 0, To: 1}},
+				},
+				Exit: 1,
+			},
+		},
+	}
+}
+
+// Expand rewrites the grammar's start node until no node has a tag with rules and returns the graph of rooms.
+// ErrMissionTooLarge is returned if that takes more than MaxRewrites rewrites. Rules are picked with a random source
+// seeded from the clock, GenerateMission uses the world's instead
+func (grammar MissionGrammar) Expand() (RoomGraph, error) {
+	return grammar.expand(rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// expand is Expand picking rules with rng
+func (grammar MissionGrammar) expand(rng *rand.Rand) (RoomGraph, error) {
+	rules := make(map[Tag][]MissionRule)
+	for _, rule := range grammar.Rules {
+		rules[rule.Symbol] = append(rules[rule.Symbol], rule)
+	}
+	g := RoomGraph{Nodes: []RoomNode{{Tags: []Tag{grammar.Start}}}}
+	keys := 0
+
+	for rewrites := 0; ; rewrites++ {
+		// Pick a random node which can still be rewritten
+		open := make([]int, 0)
+		for i, node := range g.Nodes {
+			if missionSymbol(node, rules) >= 0 {
+				open = append(open, i)
+			}
+		}
+		if len(open) == 0 {
+			return g, nil
+		}
+		if rewrites >= grammar.MaxRewrites {
+			return g, ErrMissionTooLarge
+		}
+		i := open[rng.Intn(len(open))]
+		s := missionSymbol(g.Nodes[i], rules)
+		symbol := g.Nodes[i].Tags[s]
+
+		candidates := rules[symbol]
+		var total float64
+		for _, rule := range candidates {
+			total += missionWeight(rule)
+		}
+		pick := rng.Float64() * total
+		rule := candidates[len(candidates)-1]
+		for _, r := range candidates {
+			if pick -= missionWeight(r); pick < 0 {
+				rule = r
+				break
+			}
+		}
+
+		var key Tag
+		for _, link := range rule.Result.Links {
+			if hasTag(link.Tags, TagLocked) {
+				keys++
+				key = Tag(fmt.Sprintf("key %d", keys))
+				break
+			}
+		}
+		g = rewriteMission(g, i, s, rule, key)
+	}
+}
+
+// rewriteMission replaces node i of g with the result of rule, tagging its locks and keys with key if it's set. s is
+// the index of the rule's symbol in the node's tags
+func rewriteMission(g RoomGraph, i, s int, rule MissionRule, key Tag) RoomGraph {
+	r := rule.Result
+	if len(r.Nodes) == 0 {
+		return g
+	}
+	base := len(g.Nodes) - 1
+	index := func(n int) int {
+		if n == 0 {
+			return i
+		}
+		return base + n
+	}
+	exit := index(minInt(maxInt(rule.Exit, 0), len(r.Nodes)-1))
+
+	rest := append(append([]Tag{}, g.Nodes[i].Tags[:s]...), g.Nodes[i].Tags[s+1:]...)
+	for n, node := range r.Nodes {
+		node.Tags = append([]Tag{}, node.Tags...)
+		if n == 0 {
+			node.Tags = append(node.Tags, rest...)
+		}
+		if key != "" && hasTag(node.Tags, TagKey) {
+			node.Tags = append(node.Tags, key)
+		}
+		if n == 0 {
+			g.Nodes[i] = node
+		} else {
+			g.Nodes = append(g.Nodes, node)
+		}
+	}
+	for l, link := range g.Links {
+		if link.From == i {
+			g.Links[l].From = exit
+		}
+	}
+	for _, link := range r.Links {
+		link.From, link.To = index(link.From), index(link.To)
+		link.Tags = append([]Tag{}, link.Tags...)
+		if key != "" && hasTag(link.Tags, TagLocked) {
+			link.Tags = append(link.Tags, key)
+		}
+		g.Links = append(g.Links, link)
+	}
+	return g
+}
+
+// missionSymbol returns the index of the first tag of node which has rules, or -1
+func missionSymbol(node RoomNode, rules map[Tag][]MissionRule) int {
+	for i, tag := range node.Tags {
+		if _, ok := rules[tag]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// missionWeight returns the weight of rule, 0 counts as 1
+func missionWeight(rule MissionRule) float64 {
+	if rule.Weight > 0 {
+		return rule.Weight
+	}
+	return 1
+}
+
+// hasTag returns true if tags contains tag
+func hasTag(tags []Tag, tag Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// missionKey returns the key name in tags, such as "key 2"
+func missionKey(tags []Tag) (string, bool) {
+	for _, t := range tags {
+		if strings.HasPrefix(string(t), "key ") {
+			return string(t), true
+		}
+	}
+	return "", false
+}
+
+// GenerateMission expands grammar into a graph of rooms and lays it out with GenerateFromGraph. The rooms are returned
+// in the order of the expanded graph's nodes along with the rules for Solve: each locked door needs the key from the
+// middle of its key room
+func (world *World) GenerateMission(grammar MissionGrammar, overrides ...Option) (rooms []Rect, rules Rules, err error) {
+	defer world.override(overrides)()
+	world.beginReport("Mission", 0)
+	defer func() { world.endReport(err) }()
+
+	g, err := grammar.expand(world.rng)
+	if err != nil {
+		return nil, Rules{}, err
+	}
+	world.Report.RoomsRequested = len(g.Nodes)
+	if rooms, err = world.GenerateFromGraph(g); err != nil {
+		return nil, Rules{}, err
+	}
+
+	rules = Rules{
+		Locks: make(map[Rect]string),
+		Items: make(map[Point]string),
+	}
+	for door, tags := range world.DoorTags {
+		if key, ok := missionKey(tags); ok && hasTag(tags, TagLocked) {
+			rules.Locks[door] = key
+		}
+	}
+	for i, node := range g.Nodes {
+		if key, ok := missionKey(node.Tags); ok && hasTag(node.Tags, TagKey) {
+			rules.Items[Point{X: rooms[i].X + rooms[i].W/2, Y: rooms[i].Y + rooms[i].H/2}] = key
+		}
+	}
+	return rooms, rules, nil
+}
@@ -0,0 +1,118 @@
+package generate
+
+import "math"
+
+// GeneratorSpec is one of the generators GenerateMixed runs
+type GeneratorSpec struct {
+	Name     string
+	Weight   float64 // share of the map the generator gets compared to the others, 0 counts as 1
+	Generate func(world *World) error
+}
+
+// GenerateMixed splits the map into an area for each spec, sized by its weight, runs each spec's generator in its
+// own area and then connects the areas with corridors, for maps which mix styles such as caves next to rooms. Areas
+// are cut in two along their longest side until each spec has one, keeping the specs in order. Each generator runs on
+// a world the size of its area with the same config, its border is world.WallThickness so there's room for walls
+// between areas. Its tiles, rooms, doors, corridors and tags are copied into the world.
+// Areas which can't be reached by corridors, such as islands surrounded by water, return ErrNoPath
+func (world *World) GenerateMixed(specs []GeneratorSpec, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("Mixed", 0)
+	defer func() { world.endReport(err) }()
+
+	world.ResetWorld(world.Width, world.Height)
+	if len(specs) == 0 {
+		return ErrNoGenerator
+	}
+	for _, spec := range specs {
+		if spec.Generate == nil {
+			return ErrNoGenerator
+		}
+	}
+
+	b := world.Border
+	areas := splitAreas(Rect{X: b, Y: b, W: world.Width - b*2, H: world.Height - b*2}, specs)
+	points := make([]Point, 0, len(specs))
+	for i, spec := range specs {
+		area := areas[i]
+		cfg := world.config()
+		cfg.Width, cfg.Height = area.W, area.H
+		cfg.Border = maxInt(world.WallThickness, 1)
+		cfg.Zones = nil
+		sub := cfg.build()
+		sub.reseed(world.rng.Int63())
+		sub.ShowErrorMessages = world.ShowErrorMessages
+		sub.Logf = world.Logf
+		sub.DurationBeforeRetry = world.DurationBeforeRetry
+		sub.DurationBeforeError = world.DurationBeforeError
+		sub.RouteCost = world.RouteCost
+		err := spec.Generate(sub)
+		world.Report.Retries += sub.Report.Retries
+		world.Report.Rollbacks += sub.Report.Rollbacks
+		if err != nil {
+			return err
+		}
+
+		for y := 0; y < area.H; y++ {
+			for x := 0; x < area.W; x++ {
+				if sub.Tiles[y][x] == TileVoid {
+					continue
+				}
+				world.SetTile(area.X+x, area.Y+y, sub.Tiles[y][x])
+				world.FloorKinds[area.Y+y][area.X+x] = sub.FloorKinds[y][x]
+				world.Biomes[area.Y+y][area.X+x] = sub.Biomes[y][x]
+			}
+		}
+		move := func(r Rect) Rect {
+			return Rect{X: r.X + area.X, Y: r.Y + area.Y, W: r.W, H: r.H}
+		}
+		copyStructure(world, sub, move, move, func(p Point) Point {
+			return Point{X: p.X + area.X, Y: p.Y + area.Y}
+		})
+
+		// Connect the walkable tile nearest the middle of the area's largest open space
+		open := sub.largestArea()
+		if len(open) == 0 {
+			continue
+		}
+		mid := Point{X: area.W / 2, Y: area.H / 2}
+		best := open[0]
+		for _, p := range open[1:] {
+			if p.distance(mid) < best.distance(mid) {
+				best = p
+			}
+		}
+		points = append(points, Point{X: best.X + area.X, Y: best.Y + area.Y})
+	}
+	return world.ConnectPOIs(points, RoadStyleCorridor)
+}
+
+// splitAreas cuts area into one rect per spec, each sized by its share of the total weight
+func splitAreas(area Rect, specs []GeneratorSpec) []Rect {
+	if len(specs) == 1 {
+		return []Rect{area}
+	}
+	weight := func(specs []GeneratorSpec) float64 {
+		var total float64
+		for _, spec := range specs {
+			if spec.Weight > 0 {
+				total += spec.Weight
+			} else {
+				total++
+			}
+		}
+		return total
+	}
+	half := len(specs) / 2
+	share := weight(specs[:half]) / weight(specs)
+
+	a, b := area, area
+	if area.W >= area.H {
+		a.W = int(math.Round(float64(area.W) * share))
+		b.X, b.W = area.X+a.W, area.W-a.W
+	} else {
+		a.H = int(math.Round(float64(area.H) * share))
+		b.Y, b.H = area.Y+a.H, area.H-a.H
+	}
+	return append(splitAreas(a, specs[:half]), splitAreas(b, specs[half:])...)
+}
@@ -0,0 +1,263 @@
+package generate
+
+import "sort"
+
+// MutationOp is a change Mutate can make to a finished map
+type MutationOp int8
+
+// Mutation ops
+const (
+	MutationSealCorridor MutationOp = iota // wall up a corridor which isn't the only way between its rooms
+	MutationOpenCorridor                   // dig a winding corridor between nearby rooms which aren't joined yet
+	MutationSwapContents                   // swap the tags, names and decoration of two rooms
+)
+
+// mutationAttempts is how many times Mutate tries to apply each change before giving up on it
+const mutationAttempts = 20
+
+// mutationNeighbours is how many of the nearest rooms MutationOpenCorridor tries to join a room to
+const mutationNeighbours = 3
+
+// Mutate makes random changes to a finished map, for dungeons which change between visits. amount (0-1) is how much
+// changes: up to amount times the number of rooms changes are made, each one picked at random from ops (all of them
+// if it's empty). Changes keep the map valid, every floor stays reachable and walls are repaired the way AddWalls
+// would. Rooms themselves aren't moved, so room IDs stay the same. The number of changes made is returned
+func (world *World) Mutate(amount float64, ops []MutationOp) (int, error) {
+	if len(world.Rooms) == 0 {
+		return 0, ErrNoRooms
+	}
+	if len(ops) == 0 {
+		ops = []MutationOp{MutationSealCorridor, MutationOpenCorridor, MutationSwapContents}
+	}
+	changes := int(clampFloat(amount, 0, 1)*float64(len(world.Rooms)) + 0.5)
+
+	applied := 0
+	for i := 0; i < changes; i++ {
+		op := ops[world.rng.Intn(len(ops))]
+		for a := 0; a < mutationAttempts; a++ {
+			var ok bool
+			switch op {
+			case MutationSealCorridor:
+				ok = world.sealCorridor()
+			case MutationOpenCorridor:
+				ok = world.openCorridor()
+			case MutationSwapContents:
+				ok = world.swapContents()
+			}
+			if ok {
+				applied++
+				break
+			}
+		}
+	}
+	return applied, nil
+}
+
+// sealCorridor walls up a random corridor with a door if every floor can still be reached without it
+func (world *World) sealCorridor() bool {
+	if len(world.Corridors) == 0 {
+		return false
+	}
+	i := world.rng.Intn(len(world.Corridors))
+	c := world.Corridors[i]
+	if _, ok := world.Doors[c.Door]; !ok || len(c.Rooms) != 2 {
+		return false
+	}
+	tiles := world.corridorTiles(c)
+	if len(tiles) == 0 {
+		return false
+	}
+
+	// Everything else must still be reachable
+	blocked := make(map[Point]bool, len(tiles))
+	for _, t := range tiles {
+		blocked[t] = true
+	}
+	room := c.Rooms[0]
+	start := Point{X: room.X + room.W/2, Y: room.Y + room.H/2}
+	dist := world.bfsLinks(start, func(x, y int) bool {
+		return world.walkable(x, y) && !blocked[Point{X: x, Y: y}]
+	}, world.portalLinks())
+	for y := range dist {
+		for x, d := range dist[y] {
+			if d < 0 && world.Tiles[y][x] == TileFloor && !blocked[Point{X: x, Y: y}] {
+				return false
+			}
+		}
+	}
+
+	world.repairWalls(pointBounds(tiles), func() {
+		for _, t := range tiles {
+			world.SetTile(t.X, t.Y, TileWall)
+		}
+	})
+	delete(world.Doors, c.Door)
+	delete(world.DoorTags, c.Door)
+	delete(world.Ledges, c.Door)
+	world.Corridors = append(world.Corridors[:i], world.Corridors[i+1:]...)
+	return true
+}
+
+// corridorTiles returns the floor tiles of a corridor which aren't in a room, the width of the corridor either side of
+// its path
+func (world *World) corridorTiles(c Corridor) []Point {
+	across := Point{X: 1}
+	if world.Doors[c.Door] == DoorDirectionVertical {
+		across = Point{Y: 1}
+	}
+	seen := make(map[Point]bool)
+	tiles := make([]Point, 0)
+	for _, p := range c.Path {
+		for n := 0; n < c.Width; n++ {
+			d := n - (c.Width-1)/2
+			t := Point{X: p.X + across.X*d, Y: p.Y + across.Y*d}
+			if seen[t] || t.X < 0 || t.Y < 0 || t.X >= world.Width || t.Y >= world.Height {
+				continue
+			}
+			seen[t] = true
+			if _, in := world.RoomAt(t.X, t.Y); !in && world.Tiles[t.Y][t.X] == TileFloor {
+				tiles = append(tiles, t)
+			}
+		}
+	}
+	return tiles
+}
+
+// openCorridor joins a random room to one of the nearest rooms it isn't joined to yet with a winding corridor
+func (world *World) openCorridor() bool {
+	rooms := world.RoomsOrdered()
+	if len(rooms) < 2 {
+		return false
+	}
+	a := rooms[world.rng.Intn(len(rooms))]
+	joined := make(map[Rect]bool)
+	for _, e := range world.BuildGraph().Edges {
+		if e.From == a {
+			joined[e.To] = true
+		} else if e.To == a {
+			joined[e.From] = true
+		}
+	}
+
+	candidates := make([]Rect, 0)
+	for _, b := range rooms {
+		if b != a && !joined[b] {
+			candidates = append(candidates, b)
+		}
+	}
+	distance := func(b Rect) int {
+		return absInt(b.X+b.W/2-a.X-a.W/2) + absInt(b.Y+b.H/2-a.Y-a.H/2)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+
+	for i := 0; i < minInt(len(candidates), mutationNeighbours); i++ {
+		b := candidates[i]
+		path := world.linkPath(a, b)
+		if path == nil {
+			continue
+		}
+		world.repairWalls(pointBounds(path), func() {
+			world.carveLink(path, b)
+		})
+		return true
+	}
+	return false
+}
+
+// swapContents swaps the tags, names and decoration of two random rooms. The decoration keeps its place relative to
+// the top left of the room, so the rooms are only swapped if every piece lands on walkable floor in the other room
+func (world *World) swapContents() bool {
+	rooms := world.RoomsOrdered()
+	if len(rooms) < 2 {
+		return false
+	}
+	i := world.rng.Intn(len(rooms))
+	j := (i + 1 + world.rng.Intn(len(rooms)-1)) % len(rooms)
+	a, b := rooms[i], rooms[j]
+
+	type piece struct {
+		layer  string
+		offset Point
+		tile   Tile
+		facing Direction
+		faces  bool
+	}
+	// contents returns the decoration in from, false if any of it doesn't fit in to
+	contents := func(from, to Rect) ([]piece, bool) {
+		pieces := make([]piece, 0)
+		names := make([]string, 0, len(world.Layers))
+		for name := range world.Layers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			layer := world.Layers[name]
+			for y := from.Y; y < from.Y+from.H; y++ {
+				for x := from.X; x < from.X+from.W; x++ {
+					if layer[y][x] == TileVoid {
+						continue
+					}
+					o := Point{X: x - from.X, Y: y - from.Y}
+					if o.X >= to.W || o.Y >= to.H || !world.walkable(to.X+o.X, to.Y+o.Y) {
+						return nil, false
+					}
+					facing, faces := world.Facing[Point{X: x, Y: y}]
+					pieces = append(pieces, piece{layer: name, offset: o, tile: layer[y][x], facing: facing, faces: faces})
+				}
+			}
+		}
+		return pieces, true
+	}
+	inA, okA := contents(a, b)
+	inB, okB := contents(b, a)
+	if !okA || !okB {
+		return false
+	}
+
+	// remove takes pieces out of room and place puts them into room
+	remove := func(room Rect, pieces []piece) {
+		for _, p := range pieces {
+			at := Point{X: room.X + p.offset.X, Y: room.Y + p.offset.Y}
+			world.Layers[p.layer][at.Y][at.X] = TileVoid
+			delete(world.Facing, at)
+		}
+	}
+	place := func(room Rect, pieces []piece) {
+		for _, p := range pieces {
+			at := Point{X: room.X + p.offset.X, Y: room.Y + p.offset.Y}
+			world.Layers[p.layer][at.Y][at.X] = p.tile
+			if p.faces {
+				world.Facing[at] = p.facing
+			}
+		}
+	}
+	remove(a, inA)
+	remove(b, inB)
+	place(b, inA)
+	place(a, inB)
+
+	world.RoomTags[a], world.RoomTags[b] = world.RoomTags[b], world.RoomTags[a]
+	world.RoomNames[a], world.RoomNames[b] = world.RoomNames[b], world.RoomNames[a]
+	for _, room := range [2]Rect{a, b} {
+		if len(world.RoomTags[room]) == 0 {
+			delete(world.RoomTags, room)
+		}
+		if world.RoomNames[room] == "" {
+			delete(world.RoomNames, room)
+		}
+		if world.themeRules != nil {
+			world.RoomThemes[room] = world.themeRules.roomTheme(world, room)
+		}
+	}
+	return true
+}
+
+// pointBounds returns the smallest rect containing every point
+func pointBounds(points []Point) Rect {
+	x0, y0, x1, y1 := points[0].X, points[0].Y, points[0].X, points[0].Y
+	for _, p := range points[1:] {
+		x0, y0 = minInt(x0, p.X), minInt(y0, p.Y)
+		x1, y1 = maxInt(x1, p.X), maxInt(y1, p.Y)
+	}
+	return Rect{X: x0, Y: y0, W: x1 - x0 + 1, H: y1 - y0 + 1}
+}
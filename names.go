@@ -0,0 +1,157 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// NamingStyle controls the words NameRooms picks from
+type NamingStyle int8
+
+// Naming styles
+const (
+	NamingStyleBiome   NamingStyle = iota // each room is named after its biome, the level after the most common one
+	NamingStyleDungeon                    // halls and galleries
+	NamingStyleCave                       // grottos and hollows
+	NamingStyleCrypt                      // tombs and ossuaries
+	NamingStyleWilds                      // glades and hollows for outdoor maps
+)
+
+// nameWords are the words a style builds names from
+type nameWords struct {
+	adjectives []string
+	rooms      []string
+	levels     []string
+	of         []string // used as "The Gallery of ..."
+}
+
+var (
+	namingWords = map[NamingStyle]nameWords{
+		NamingStyleDungeon: {
+			adjectives: []string{"Sunken", "Iron", "Forgotten", "Broken", "Silent", "Crimson", "Hollow", "Gilded"},
+			rooms:      []string{"Gallery", "Hall", "Chamber", "Armory", "Cellar", "Barracks", "Court", "Library"},
+			levels:     []string{"Keep", "Halls", "Dungeon", "Stronghold", "Bastion"},
+			of:         []string{"Chains", "Kings", "Echoes", "the Lost", "Iron", "Whispers"},
+		},
+		NamingStyleCave: {
+			adjectives: []string{"Dripping", "Glowing", "Deep", "Twisting", "Damp", "Crystal", "Echoing", "Black"},
+			rooms:      []string{"Grotto", "Hollow", "Cavern", "Pit", "Chasm", "Den", "Tunnel", "Pool"},
+			levels:     []string{"Depths", "Warrens", "Caverns", "Underdark", "Deeps"},
+			of:         []string{"Bats", "Stone", "the Deep", "Shadows", "Moss", "Dripping Water"},
+		},
+		NamingStyleCrypt: {
+			adjectives: []string{"Ashen", "Weeping", "Sealed", "Pale", "Cursed", "Silent", "Bone", "Withered"},
+			rooms:      []string{"Tomb", "Ossuary", "Crypt", "Sepulchre", "Vault", "Chapel", "Mausoleum", "Shrine"},
+			levels:     []string{"Barrows", "Catacombs", "Necropolis", "Crypts", "Graves"},
+			of:         []string{"the Dead", "Ash", "Sorrow", "the Forgotten", "Bones", "Saints"},
+		},
+		NamingStyleWilds: {
+			adjectives: []string{"Mossy", "Windswept", "Sunlit", "Tangled", "Quiet", "Golden", "Misty", "Wild"},
+			rooms:      []string{"Glade", "Meadow", "Thicket", "Clearing", "Grove", "Dell", "Knoll", "Shore"},
+			levels:     []string{"Wilds", "Reach", "Vale", "Isles", "Downs"},
+			of:         []string{"Thorns", "the Wind", "Foxes", "Larks", "Old Oaks", "the Tide"},
+		},
+	}
+	// biomeNamingStyles is the style NamingStyleBiome uses for each biome
+	biomeNamingStyles = map[Biome]NamingStyle{
+		BiomeNone:      NamingStyleDungeon,
+		BiomeDungeon:   NamingStyleDungeon,
+		BiomeCave:      NamingStyleCave,
+		BiomeCrypt:     NamingStyleCrypt,
+		BiomeGrassland: NamingStyleWilds,
+		BiomeForest:    NamingStyleWilds,
+		BiomeBeach:     NamingStyleWilds,
+		BiomeOcean:     NamingStyleWilds,
+	}
+	// tagRoomNames replace the room word for rooms with a tag, the first tag which has names wins
+	tagRoomNames = map[Tag][]string{
+		TagStart:    {"Gate", "Threshold", "Landing"},
+		TagBoss:     {"Throne", "Lair", "Sanctum"},
+		TagTreasure: {"Hoard", "Treasury", "Vault"},
+		TagShop:     {"Market", "Bazaar", "Trading Post"},
+		TagSafe:     {"Refuge", "Sanctuary", "Haven"},
+		TagArena:    {"Arena", "Pit", "Proving Ground"},
+	}
+)
+
+// NameRooms gives every room a flavor name such as "The Sunken Gallery", stored in world.RoomNames, and names the
+// level, stored in world.LevelName and returned. Rooms are named in the order of RoomsOrdered using the world's
+// random source, so the same seed gives the same names. Tagged rooms get fitting names, like a throne for the boss
+func (world *World) NameRooms(style NamingStyle) string {
+	world.RoomNames = make(map[Rect]string)
+	used := make(map[string]bool)
+	biomes := make(map[Biome]int)
+	for _, room := range world.RoomsOrdered() {
+		biome := world.Biomes[room.Y+room.H/2][room.X+room.W/2]
+		biomes[biome]++
+		words := styleWords(style, biome)
+
+		nouns := words.rooms
+		for _, tag := range world.RoomTags[room] {
+			if names, ok := tagRoomNames[tag]; ok {
+				nouns = names
+				break
+			}
+		}
+		// Try a few times to find a name which hasn't been used before numbering it
+		var name string
+		for i := 0; i < 8 && (name == "" || used[name]); i++ {
+			name = randomName(world.rng, words, nouns)
+		}
+		base := name
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s %s", base, romanNumeral(n))
+		}
+		used[name] = true
+		world.RoomNames[room] = name
+	}
+
+	biome, count := BiomeNone, 0
+	for b, c := range biomes {
+		if c > count || (c == count && b < biome) {
+			biome, count = b, c
+		}
+	}
+	words := styleWords(style, biome)
+	world.LevelName = fmt.Sprintf("The %s %s", pick(world.rng, words.adjectives), pick(world.rng, words.levels))
+	return world.LevelName
+}
+
+// styleWords returns the words for style, looking up the biome's style for NamingStyleBiome
+func styleWords(style NamingStyle, biome Biome) nameWords {
+	if style == NamingStyleBiome {
+		style = biomeNamingStyles[biome]
+	}
+	if words, ok := namingWords[style]; ok {
+		return words
+	}
+	return namingWords[NamingStyleDungeon]
+}
+
+// randomName returns "The Adjective Noun" or "The Noun of Something"
+func randomName(rng *rand.Rand, words nameWords, nouns []string) string {
+	if rng.Intn(3) == 0 {
+		return fmt.Sprintf("The %s of %s", pick(rng, nouns), pick(rng, words.of))
+	}
+	return fmt.Sprintf("The %s %s", pick(rng, words.adjectives), pick(rng, nouns))
+}
+
+// pick returns a random word
+func pick(rng *rand.Rand, words []string) string {
+	return words[rng.Intn(len(words))]
+}
+
+// romanNumeral returns n as a roman numeral, for numbering rooms which would otherwise share a name
+func romanNumeral(n int) string {
+	var b strings.Builder
+	for _, d := range []struct {
+		v int
+		s string
+	}{{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"}} {
+		for ; n >= d.v; n -= d.v {
+			b.WriteString(d.s)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Noise is seeded 2D value noise, returning values in the range 0-1
+type Noise struct {
+	seed uint64
+}
+
+// newNoise returns Noise seeded from rng
+func newNoise(rng *rand.Rand) *Noise {
+	return &Noise{seed: rng.Uint64()}
+}
+
+// hash returns a pseudo random value in the range 0-1 for a lattice point
+func (n *Noise) hash(x, y int) float64 {
+	h := n.seed ^ uint64(x)*0x9E3779B185EBCA87 ^ uint64(y)*0xC2B2AE3D27D4EB4F
+	h ^= h >> 33
+	h *= 0xFF51AFD7ED558CCD
+	h ^= h >> 33
+	h *= 0xC4CEB9FE1A85EC53
+	h ^= h >> 33
+	return float64(h>>11) / float64(1<<53)
+}
+
+// At returns smoothly interpolated noise at x,y, lattice points are 1 unit apart
+func (n *Noise) At(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	ix, iy := int(x0), int(y0)
+	fx, fy := x-x0, y-y0
+	// Smoothstep the fractions so the lattice isn't visible
+	fx = fx * fx * (3 - 2*fx)
+	fy = fy * fy * (3 - 2*fy)
+
+	top := n.hash(ix, iy) + (n.hash(ix+1, iy)-n.hash(ix, iy))*fx
+	bottom := n.hash(ix, iy+1) + (n.hash(ix+1, iy+1)-n.hash(ix, iy+1))*fx
+	return top + (bottom-top)*fy
+}
+
+// Fractal returns octaves of noise layered on top of each other, each at double the frequency and half the strength
+// of the last
+func (n *Noise) Fractal(x, y float64, octaves int) float64 {
+	var sum, total float64
+	amp := 1.0
+	for o := 0; o < octaves; o++ {
+		sum += n.At(x, y) * amp
+		total += amp
+		amp /= 2
+		x *= 2
+		y *= 2
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
@@ -0,0 +1,78 @@
+package generate
+
+// Option overrides some of the world's settings for a single call to a generator or AddWalls, such as
+// world.GenerateDungeon(20, WithCorridorSize(1)), putting them back afterwards so a long-lived world can be generated
+// in different styles without changing its config. Pass AddWalls the same wall thickness the generator was given.
+// Options change a copy of the world's config, changes to its Width, Height, Seed, Validate and Generate are ignored
+type Option func(cfg *WorldConfig)
+
+// WithCorridorSize makes every corridor size tiles wide
+func WithCorridorSize(size int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.MinCorridorSize, cfg.MaxCorridorSize = size, size
+	}
+}
+
+// WithCorridorSizes makes corridors from min to max tiles wide
+func WithCorridorSizes(min, max int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.MinCorridorSize, cfg.MaxCorridorSize = min, max
+	}
+}
+
+// WithRoomSize makes rooms from minW x minH to maxW x maxH
+func WithRoomSize(minW, minH, maxW, maxH int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.MinRoomWidth, cfg.MinRoomHeight = minW, minH
+		cfg.MaxRoomWidth, cfg.MaxRoomHeight = maxW, maxH
+	}
+}
+
+// WithWallThickness makes walls thickness tiles thick
+func WithWallThickness(thickness int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.WallThickness = thickness
+	}
+}
+
+// WithBorder keeps tiles border tiles away from the edge of the map
+func WithBorder(border int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.Border = border
+	}
+}
+
+// WithRoomSeparation keeps rooms at least separation tiles apart
+func WithRoomSeparation(separation int) Option {
+	return func(cfg *WorldConfig) {
+		cfg.MinRoomSeparation = separation
+	}
+}
+
+// WithDirections only grows rooms and corridors in dirs
+func WithDirections(dirs ...Direction) Option {
+	return func(cfg *WorldConfig) {
+		cfg.Directions = append([]Direction(nil), dirs...)
+	}
+}
+
+// WithZones replaces the world's zones
+func WithZones(zones ...Zone) Option {
+	return func(cfg *WorldConfig) {
+		cfg.Zones = append([]Zone(nil), zones...)
+	}
+}
+
+// override applies opts to the world's settings and returns a func which puts them back
+func (world *World) override(opts []Option) func() {
+	if len(opts) == 0 {
+		return func() {}
+	}
+	saved := world.config()
+	cfg := world.config()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.apply(world)
+	return func() { saved.apply(world) }
+}
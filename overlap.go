@@ -0,0 +1,66 @@
+package generate
+
+// overlappingRoom returns a w x h room overlapping last on its side in direction dir, sharing at least one row or
+// column with it and sticking out past it
+func (world *World) overlappingRoom(last Rect, w, h int, dir Direction) Rect {
+	part := Rect{W: w, H: h}
+	switch dir {
+	case DirectionWest:
+		part.X = last.X - w + world.randInt(1, minInt(w, last.W)-1)
+		part.Y = last.Y + world.randInt(1-h, last.H-1)
+	case DirectionEast:
+		part.X = last.X + last.W - world.randInt(1, minInt(w, last.W)-1)
+		part.Y = last.Y + world.randInt(1-h, last.H-1)
+	case DirectionNorth:
+		part.Y = last.Y - h + world.randInt(1, minInt(h, last.H)-1)
+		part.X = last.X + world.randInt(1-w, last.W-1)
+	case DirectionSouth:
+		part.Y = last.Y + last.H - world.randInt(1, minInt(h, last.H)-1)
+		part.X = last.X + world.randInt(1-w, last.W-1)
+	}
+	return part
+}
+
+// placeRoomPart digs part into the compound room it overlaps and records it in world.RoomParts. Like checkRoom, it
+// fails if there's floor within the room separation of part, except for the floor of the compound room itself
+func (world *World) placeRoomPart(part Rect, wt int) error {
+	sep := world.roomSeparation(wt)
+	m := world.edgeMargin(wt)
+	if part.X < m || part.Y < m || part.X+part.W > world.Width-m || part.Y+part.H > world.Height-m {
+		return ErrOutOfBounds
+	}
+	room, ok := Rect{}, false
+	for y := maxInt(part.Y-sep, 0); y < minInt(part.Y+part.H+sep, world.Height); y++ {
+		for x := maxInt(part.X-sep, 0); x < minInt(part.X+part.W+sep, world.Width); x++ {
+			if world.Tiles[y][x] != TileFloor {
+				continue
+			}
+			r, in := world.RoomAt(x, y)
+			if !in || ok && r != room {
+				return ErrFloorAlreadyPlaced
+			}
+			room, ok = r, true
+		}
+	}
+	if !ok {
+		return ErrNoRooms
+	}
+
+	if err := world.digRoom(part.X, part.Y, part.W, part.H, wt); err != nil {
+		return err
+	}
+	world.RoomParts[room] = append(world.RoomParts[room], part)
+	return nil
+}
+
+// roomPartAt returns the compound room which has a part containing x,y
+func (world *World) roomPartAt(x, y int) (Rect, bool) {
+	for room, parts := range world.RoomParts {
+		for _, part := range parts {
+			if part.contains(x, y) {
+				return room, true
+			}
+		}
+	}
+	return Rect{}, false
+}
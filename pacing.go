@@ -0,0 +1,254 @@
+package generate
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+// ErrPacing is returned when the spaces along the critical path don't alternate the way PacingOptions asks
+var ErrPacing = errors.New("Critical path doesn't meet the pacing constraints")
+
+// Pace is whether a space feels open or tight
+type Pace int8
+
+// Paces
+const (
+	PaceOpen  Pace = iota // a large room, lit
+	PaceTight             // a small room or a long corridor, dark
+)
+
+// PacingOptions constrains how open and tight spaces alternate along the critical path
+type PacingOptions struct {
+	OpenArea     int     // rooms with at least this area are open, the median room area if it's 0
+	MinCorridor  int     // corridors shorter than this are passed through too quickly to count as a space
+	MaxOpenRun   int     // most open spaces in a row
+	MaxTightRun  int     // most tight spaces in a row
+	MinOpenRatio float64 // fraction (0-1) of the spaces which must be open at least
+	MaxOpenRatio float64 // fraction (0-1) of the spaces which can be open at most
+}
+
+// DefaultPacingOptions returns options which never allow more than two open or three tight spaces in a row, with
+// between a quarter and two thirds of the spaces open
+func DefaultPacingOptions() PacingOptions {
+	return PacingOptions{
+		MinCorridor:  4,
+		MaxOpenRun:   2,
+		MaxTightRun:  3,
+		MinOpenRatio: 0.25,
+		MaxOpenRatio: 0.67,
+	}
+}
+
+// PaceSpace is a room or corridor along the critical path
+type PaceSpace struct {
+	Pace   Pace
+	Room   Rect // zero for corridors
+	IsRoom bool
+	Length int // tiles of the critical path in the space
+}
+
+// pacingAdjustments is how many rooms EnforcePacing grows or shrinks before giving up
+const pacingAdjustments = 20
+
+// Pacing returns the rooms and corridors along CriticalPath in order, each judged open or tight. Rooms are open if
+// their area is at least opts.OpenArea, corridors at least opts.MinCorridor long are always tight and shorter ones are
+// left out
+func (world *World) Pacing(opts PacingOptions) []PaceSpace {
+	if opts.OpenArea <= 0 {
+		opts.OpenArea = world.medianRoomArea()
+	}
+	spaces := make([]PaceSpace, 0)
+	for _, p := range world.CriticalPath() {
+		room, in := world.RoomAt(p.X, p.Y)
+		if n := len(spaces); n > 0 && spaces[n-1].IsRoom == in && spaces[n-1].Room == room {
+			spaces[n-1].Length++
+			continue
+		}
+		space := PaceSpace{Pace: PaceTight, Room: room, IsRoom: in, Length: 1}
+		if in && room.W*room.H >= opts.OpenArea {
+			space.Pace = PaceOpen
+		}
+		spaces = append(spaces, space)
+	}
+
+	kept := spaces[:0]
+	for _, s := range spaces {
+		if s.IsRoom || s.Length >= opts.MinCorridor {
+			// Rooms either side of a short corridor can end up next to each other
+			if n := len(kept); n > 0 && s.IsRoom && kept[n-1].IsRoom && kept[n-1].Room == s.Room {
+				kept[n-1].Length += s.Length
+				continue
+			}
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// CheckPacing returns ErrPacing if the spaces along the critical path break opts, or ErrNoPath if there's no critical
+// path
+func (world *World) CheckPacing(opts PacingOptions) error {
+	spaces := world.Pacing(opts)
+	if len(spaces) == 0 {
+		return ErrNoPath
+	}
+	if _, _, ok := pacingViolation(spaces, opts); !ok {
+		return ErrPacing
+	}
+	return nil
+}
+
+// EnforcePacing grows tight rooms into open ones and shrinks open rooms into tight ones along the critical path until
+// it meets opts, see ExpandRoom and ShrinkRoom. The rooms are judged against the median room area from before any were
+// changed, unless opts.OpenArea is set. ErrPacing is returned if it couldn't be met by changing a few rooms, re-roll
+// the world in that case. Call before any passes which depend on the size of the rooms
+func (world *World) EnforcePacing(opts PacingOptions) error {
+	if opts.OpenArea <= 0 {
+		opts.OpenArea = world.medianRoomArea()
+	}
+	for a := 0; a < pacingAdjustments; a++ {
+		spaces := world.Pacing(opts)
+		if len(spaces) == 0 {
+			return ErrNoPath
+		}
+		want, run, ok := pacingViolation(spaces, opts)
+		if ok {
+			return nil
+		}
+
+		// Try the rooms in the offending run first, then the rest of the path
+		rooms := make([]Rect, 0)
+		for _, s := range append(append([]PaceSpace(nil), run...), spaces...) {
+			if s.IsRoom && s.Pace != want {
+				rooms = append(rooms, s.Room)
+			}
+		}
+		changed := false
+		for _, room := range rooms {
+			if want == PaceOpen {
+				changed = world.growToArea(room, opts.OpenArea)
+			} else {
+				changed = world.shrinkBelowArea(room, opts.OpenArea)
+			}
+			if changed {
+				break
+			}
+		}
+		if !changed {
+			return ErrPacing
+		}
+	}
+	if err := world.CheckPacing(opts); err != nil {
+		return ErrPacing
+	}
+	return nil
+}
+
+// PacingDressing returns a pass which marks the tight rooms and the corridors along the critical path with TileDark on
+// the named layer, leaving the open rooms lit
+func PacingDressing(name string, opts PacingOptions) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		if opts.OpenArea <= 0 {
+			opts.OpenArea = world.medianRoomArea()
+		}
+		dark := make(map[Rect]bool)
+		for _, s := range world.Pacing(opts) {
+			if s.IsRoom && s.Pace == PaceTight {
+				dark[s.Room] = true
+			}
+		}
+		layer := world.Layer(name)
+		for _, p := range world.CriticalPath() {
+			room, in := world.RoomAt(p.X, p.Y)
+			if in && !dark[room] {
+				continue
+			}
+			if !in {
+				layer[p.Y][p.X] = TileDark
+				continue
+			}
+			for y := room.Y; y < room.Y+room.H; y++ {
+				for x := room.X; x < room.X+room.W; x++ {
+					if world.walkable(x, y) {
+						layer[y][x] = TileDark
+					}
+				}
+			}
+			delete(dark, room)
+		}
+	}
+}
+
+// pacingViolation returns false with the pace the spaces need more of and the run which is too long, if there is one,
+// when spaces break opts
+func pacingViolation(spaces []PaceSpace, opts PacingOptions) (Pace, []PaceSpace, bool) {
+	start := 0
+	for i := range spaces {
+		if spaces[i].Pace != spaces[start].Pace {
+			start = i
+		}
+		run := spaces[start : i+1]
+		if run[0].Pace == PaceOpen && opts.MaxOpenRun > 0 && len(run) > opts.MaxOpenRun {
+			return PaceTight, run, false
+		}
+		if run[0].Pace == PaceTight && opts.MaxTightRun > 0 && len(run) > opts.MaxTightRun {
+			return PaceOpen, run, false
+		}
+	}
+
+	open := 0
+	for _, s := range spaces {
+		if s.Pace == PaceOpen {
+			open++
+		}
+	}
+	ratio := float64(open) / float64(len(spaces))
+	if ratio < opts.MinOpenRatio {
+		return PaceOpen, nil, false
+	}
+	if opts.MaxOpenRatio > 0 && ratio > opts.MaxOpenRatio {
+		return PaceTight, nil, false
+	}
+	return PaceOpen, nil, true
+}
+
+// growToArea expands room by the fewest tiles which give it at least area, returning false if it can't grow enough
+func (world *World) growToArea(room Rect, area int) bool {
+	for n := 1; n <= maxInt(world.Width, world.Height)/2; n++ {
+		grown := room.Expand(n)
+		if grown.W*grown.H < area {
+			continue
+		}
+		_, err := world.ExpandRoom(room, n)
+		return err == nil
+	}
+	return false
+}
+
+// shrinkBelowArea shrinks room by the fewest tiles which leave it smaller than area, returning false if it can't
+func (world *World) shrinkBelowArea(room Rect, area int) bool {
+	for n := 1; ; n++ {
+		shrunk := room.Shrink(n)
+		if shrunk.W < 1 || shrunk.H < 1 {
+			return false
+		}
+		if shrunk.W*shrunk.H < area {
+			_, err := world.ShrinkRoom(room, n)
+			return err == nil
+		}
+	}
+}
+
+// medianRoomArea returns the median area of the rooms, 0 if there are none
+func (world *World) medianRoomArea() int {
+	areas := make([]int, 0, len(world.Rooms))
+	for room := range world.Rooms {
+		areas = append(areas, room.W*room.H)
+	}
+	if len(areas) == 0 {
+		return 0
+	}
+	sort.Ints(areas)
+	return areas[len(areas)/2]
+}
@@ -0,0 +1,259 @@
+package generate
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Point is a single tile position
+type Point struct {
+	X, Y int
+}
+
+// polarOffsets are the offsets of the 4 tiles touching a tile, in the same order as countSurroundingPolar
+var polarOffsets = [4]Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+// walkable returns true if the tile at x,y is in bounds and can be walked on
+func (world *World) walkable(x, y int) bool {
+	tile, err := world.GetTile(x, y)
+	return err == nil && tile.Walkable()
+}
+
+// bfs returns the distance of every tile from from, moving only in the 4 polar directions and only onto tiles which
+// are passable. Unreachable tiles are -1
+func (world *World) bfs(from Point, passable func(x, y int) bool) [][]int {
+	return world.bfsLinks(from, passable, nil)
+}
+
+// bfsLinks is bfs where each point in links can also step straight to the point it's linked to, like portals
+func (world *World) bfsLinks(from Point, passable func(x, y int) bool, links map[Point]Point) [][]int {
+	dist := make([][]int, world.Height)
+	for y := range dist {
+		dist[y] = make([]int, world.Width)
+		for x := range dist[y] {
+			dist[y][x] = -1
+		}
+	}
+	if !passable(from.X, from.Y) {
+		return dist
+	}
+
+	dist[from.Y][from.X] = 0
+	queue := []Point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, o := range polarOffsets {
+			x, y := p.X+o.X, p.Y+o.Y
+			if x < 0 || y < 0 || x >= world.Width || y >= world.Height || dist[y][x] != -1 || !passable(x, y) {
+				continue
+			}
+			dist[y][x] = dist[p.Y][p.X] + 1
+			queue = append(queue, Point{X: x, Y: y})
+		}
+		if to, ok := links[p]; ok && dist[to.Y][to.X] == -1 && passable(to.X, to.Y) {
+			dist[to.Y][to.X] = dist[p.Y][p.X] + 1
+			queue = append(queue, to)
+		}
+	}
+	return dist
+}
+
+// pathFromDistances walks back from to through a distance grid created by bfs or bfsLinks with the same links,
+// returning the path from the bfs origin to to (inclusive), or nil if to is unreachable
+func pathFromDistances(dist [][]int, to Point, links map[Point]Point) []Point {
+	if to.Y < 0 || to.Y >= len(dist) || to.X < 0 || to.X >= len(dist[to.Y]) || dist[to.Y][to.X] == -1 {
+		return nil
+	}
+	path := make([]Point, dist[to.Y][to.X]+1)
+	p := to
+	for d := dist[to.Y][to.X]; d >= 0; d-- {
+		path[d] = p
+		if from, ok := links[p]; ok && dist[from.Y][from.X] == d-1 {
+			p = from
+			continue
+		}
+		for _, o := range polarOffsets {
+			x, y := p.X+o.X, p.Y+o.Y
+			if y >= 0 && y < len(dist) && x >= 0 && x < len(dist[y]) && dist[y][x] == d-1 {
+				p = Point{X: x, Y: y}
+				break
+			}
+		}
+	}
+	return path
+}
+
+// DistanceField returns the walking distance of every tile from from, indexed [y][x], stepping through portals
+// counts as a single step. Unreachable tiles are -1
+func (world *World) DistanceField(from Point) [][]int {
+	return world.bfsLinks(from, world.walkable, world.portalLinks())
+}
+
+// ShortestPath returns the shortest walkable path between from and to (inclusive), or nil if there isn't one
+func (world *World) ShortestPath(from, to Point) []Point {
+	return pathFromDistances(world.DistanceField(from), to, world.portalLinks())
+}
+
+// RandomFloorTile returns a random walkable tile, such as floor, grass or road, for spawn points and item drops. It
+// uses the world's random source so the same seed gives the same tile. false is returned if nothing can be walked on
+func (world *World) RandomFloorTile() (Point, bool) {
+	tiles := make([]Point, 0)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if world.walkable(x, y) {
+				tiles = append(tiles, Point{X: x, Y: y})
+			}
+		}
+	}
+	if len(tiles) == 0 {
+		return Point{}, false
+	}
+	return tiles[world.rng.Intn(len(tiles))], true
+}
+
+// NearestWalkable returns the walkable tile closest to x,y in a straight line, which is x,y itself if it can be
+// walked on. Ties go to the tile nearest the top left. x,y doesn't have to be inside the world.
+// false is returned if nothing can be walked on
+func (world *World) NearestWalkable(x, y int) (Point, bool) {
+	from := Point{X: x, Y: y}
+	best, bestDist := Point{}, math.Inf(1)
+	// Search rings of tiles around x,y until the rings are further away than the best tile found
+	maxR := maxInt(maxInt(absInt(x), absInt(world.Width-1-x)), maxInt(absInt(y), absInt(world.Height-1-y)))
+	for r := 0; r <= maxR && float64(r) <= bestDist; r++ {
+		for oy := y - r; oy <= y+r; oy++ {
+			// Only the ends of the rows between the top and bottom of the ring are on it
+			step := 1
+			if r > 0 && absInt(oy-y) != r {
+				step = r * 2
+			}
+			for ox := x - r; ox <= x+r; ox += step {
+				p := Point{X: ox, Y: oy}
+				d := p.distance(from)
+				if !world.walkable(ox, oy) || d > bestDist {
+					continue
+				}
+				if d < bestDist || oy < best.Y || (oy == best.Y && ox < best.X) {
+					best, bestDist = p, d
+				}
+			}
+		}
+	}
+	return best, !math.IsInf(bestDist, 1)
+}
+
+// pathNode is an entry in the A* open set
+type pathNode struct {
+	p        Point
+	priority float64
+}
+
+// pathQueue is a min-heap of pathNodes
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// findPath returns the cheapest path from from to to (inclusive) using A*, where cost returns the cost of stepping
+// onto a tile. Tiles with a negative cost can't be entered. minCost must be the lowest cost cost can return, it keeps
+// the heuristic admissible. nil is returned if there's no path
+func (world *World) findPath(from, to Point, minCost float64, cost func(x, y int) float64) []Point {
+	inBounds := func(p Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < world.Width && p.Y < world.Height
+	}
+	if !inBounds(from) || !inBounds(to) {
+		return nil
+	}
+
+	g := make(map[Point]float64)
+	cameFrom := make(map[Point]Point)
+	g[from] = 0
+	open := &pathQueue{{p: from}}
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(pathNode)
+		if cur.p == to {
+			path := []Point{to}
+			for p := to; p != from; {
+				p = cameFrom[p]
+				path = append(path, p)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, o := range polarOffsets {
+			n := Point{X: cur.p.X + o.X, Y: cur.p.Y + o.Y}
+			if !inBounds(n) {
+				continue
+			}
+			c := cost(n.X, n.Y)
+			if c < 0 {
+				continue
+			}
+			ng := g[cur.p] + c
+			if old, ok := g[n]; ok && old <= ng {
+				continue
+			}
+			g[n] = ng
+			cameFrom[n] = cur.p
+			h := float64(absInt(to.X-n.X)+absInt(to.Y-n.Y)) * minCost
+			heap.Push(open, pathNode{p: n, priority: ng + h})
+		}
+	}
+	return nil
+}
+
+// dijkstra returns the cheapest cost of reaching every tile from from, where cost returns the cost of stepping onto a
+// tile. Tiles with a negative cost can't be entered, and tiles which can't be reached for less than max are -1
+func (world *World) dijkstra(from Point, max float64, cost func(x, y int) float64) [][]float64 {
+	dist := make([][]float64, world.Height)
+	for y := range dist {
+		dist[y] = make([]float64, world.Width)
+		for x := range dist[y] {
+			dist[y][x] = -1
+		}
+	}
+	if from.X < 0 || from.Y < 0 || from.X >= world.Width || from.Y >= world.Height {
+		return dist
+	}
+
+	dist[from.Y][from.X] = 0
+	open := &pathQueue{{p: from}}
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(pathNode)
+		if cur.priority > dist[cur.p.Y][cur.p.X] {
+			continue // already reached more cheaply
+		}
+		for _, o := range polarOffsets {
+			n := Point{X: cur.p.X + o.X, Y: cur.p.Y + o.Y}
+			if n.X < 0 || n.Y < 0 || n.X >= world.Width || n.Y >= world.Height {
+				continue
+			}
+			c := cost(n.X, n.Y)
+			if c < 0 {
+				continue
+			}
+			nd := cur.priority + c
+			if nd >= max || (dist[n.Y][n.X] >= 0 && dist[n.Y][n.X] <= nd) {
+				continue
+			}
+			dist[n.Y][n.X] = nd
+			heap.Push(open, pathNode{p: n, priority: nd})
+		}
+	}
+	return dist
+}
+
+// distance returns the straight line distance between two points
+func (p Point) distance(o Point) float64 {
+	return math.Hypot(float64(p.X-o.X), float64(p.Y-o.Y))
+}
@@ -0,0 +1,81 @@
+package generate
+
+// Portal is a pair of linked TilePortal tiles, stepping onto one moves to the other
+type Portal struct {
+	A, B Point
+}
+
+// PlacePortals places up to pairs portals, each linking two floor tiles at least minDistance steps apart (counting
+// the portals placed before it), picking the most distant tiles it can find. Each end is put in a different room
+// where there are rooms and portals between two rooms become graph edges. Portals are stored in world.Portals and
+// DistanceField, ShortestPath, Solve and Metrics all take them into account, so metrics measured afterwards include
+// the shortcuts. The number of portals placed is returned
+func (world *World) PlacePortals(pairs int, minDistance int) int {
+	used := make(map[Rect]struct{})
+	candidates := func() []Point {
+		points := make([]Point, 0)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if world.Tiles[y][x] != TileFloor || world.FloorKinds[y][x] == FloorKindCorridor {
+					continue
+				}
+				if room, ok := world.RoomAt(x, y); ok {
+					if _, taken := used[room]; taken {
+						continue
+					}
+				} else if len(world.Rooms) > 0 {
+					continue
+				}
+				points = append(points, Point{X: x, Y: y})
+			}
+		}
+		return points
+	}
+
+	placed := 0
+	for ; placed < pairs; placed++ {
+		points := candidates()
+		if len(points) < 2 {
+			break
+		}
+		// Start from a random tile and link it to the furthest tile from it, which is in a different room
+		a := points[world.rng.Intn(len(points))]
+		roomA, inRoomA := world.RoomAt(a.X, a.Y)
+		dist := world.DistanceField(a)
+		b, best := Point{}, -1
+		for _, p := range points {
+			if room, ok := world.RoomAt(p.X, p.Y); ok && inRoomA && room == roomA {
+				continue
+			}
+			if d := dist[p.Y][p.X]; d >= minDistance && d > best {
+				b, best = p, d
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		world.SetTile(a.X, a.Y, TilePortal)
+		world.SetTile(b.X, b.Y, TilePortal)
+		world.Portals = append(world.Portals, Portal{A: a, B: b})
+		for _, p := range [2]Point{a, b} {
+			if room, ok := world.RoomAt(p.X, p.Y); ok {
+				used[room] = struct{}{}
+			}
+		}
+	}
+	return placed
+}
+
+// portalLinks maps each end of every portal to its other end, nil if there aren't any portals
+func (world *World) portalLinks() map[Point]Point {
+	if len(world.Portals) == 0 {
+		return nil
+	}
+	links := make(map[Point]Point, len(world.Portals)*2)
+	for _, portal := range world.Portals {
+		links[portal.A] = portal.B
+		links[portal.B] = portal.A
+	}
+	return links
+}
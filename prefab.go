@@ -0,0 +1,62 @@
+package generate
+
+import "fmt"
+
+// Prefab is a hand made room interior which can be stamped into the world, indexed [y][x]
+type Prefab struct {
+	Width, Height int
+	Tiles         [][]Tile
+}
+
+// prefabTiles are the characters ParsePrefab understands
+var prefabTiles = map[rune]Tile{
+	' ': TileVoid,
+	'#': TileWall,
+	'.': TileFloor,
+	'~': TileWater,
+	'^': TileChasm,
+	'=': TileBridge,
+	'$': TileChest,
+	'T': TileTree,
+	'O': TilePillar,
+	'-': TileLowWall,
+}
+
+// ParsePrefab builds a Prefab from rows of text, one character per tile:
+// '.' floor, '#' wall, 'O' pillar, '-' low wall, '~' water, '^' chasm, '=' bridge, '$' chest, 'T' tree and ' ' void.
+// Every row must be the same length
+func ParsePrefab(rows ...string) (Prefab, error) {
+	p := Prefab{Height: len(rows), Tiles: make([][]Tile, len(rows))}
+	for y, row := range rows {
+		runes := []rune(row)
+		if y == 0 {
+			p.Width = len(runes)
+		} else if len(runes) != p.Width {
+			return Prefab{}, fmt.Errorf("prefab row %d is %d wide, expected %d", y, len(runes), p.Width)
+		}
+		p.Tiles[y] = make([]Tile, len(runes))
+		for x, r := range runes {
+			t, ok := prefabTiles[r]
+			if !ok {
+				return Prefab{}, fmt.Errorf("prefab row %d has unknown tile %q", y, r)
+			}
+			p.Tiles[y][x] = t
+		}
+	}
+	return p, nil
+}
+
+// stampPrefab places the prefab as a room at x,y, surrounded by walls wt thick
+func (world *World) stampPrefab(p Prefab, x, y, wt int) error {
+	if err := world.placeRoom(x, y, p.Width, p.Height, wt); err != nil {
+		return err
+	}
+	for py, row := range p.Tiles {
+		for px, t := range row {
+			if t != TileFloor {
+				world.SetTile(x+px, y+py, t)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package generate
+
+// PresetKind names one of the built in presets
+type PresetKind int8
+
+// Presets
+const (
+	PresetCrypt    PresetKind = iota // catacombs with treasure in some of the niches
+	PresetMine                       // winding caves held up by pillars
+	PresetSewer                      // a grid of rooms with water channels through the larger ones
+	PresetFortress                   // big rooms with thick walls and pillars
+)
+
+// PresetConfig bundles a generator with its parameters, a tile palette and decoration passes. The fields can be
+// changed before calling Generate to override any part of the preset
+type PresetConfig struct {
+	Border                    int
+	WallThickness             int
+	MinCorridorSize           int
+	MaxCorridorSize           int
+	AllowRandomCorridorOffset bool
+	MaxRoomWidth              int
+	MaxRoomHeight             int
+	MinRoomWidth              int
+	MinRoomHeight             int
+
+	Biome     Biome // set on every tile which isn't TileVoid and doesn't have a biome yet
+	Generator func(world *World) error
+	Palette   map[Tile]Tile  // tiles are swapped for their palette tile after generating
+	Dressing  []DressingPass // added to the world and run once after generating
+}
+
+// Preset returns a new copy of one of the built in presets
+func Preset(kind PresetKind) *PresetConfig {
+	p := &PresetConfig{
+		Border:          2,
+		WallThickness:   1,
+		MinCorridorSize: 1,
+		MaxCorridorSize: 1,
+		MaxRoomWidth:    8,
+		MaxRoomHeight:   8,
+		MinRoomWidth:    4,
+		MinRoomHeight:   4,
+		Palette:         make(map[Tile]Tile),
+	}
+
+	switch kind {
+	case PresetCrypt:
+		p.Biome = BiomeCrypt
+		p.MaxCorridorSize = 2
+		p.Generator = func(world *World) error {
+			segments := world.Width * world.Height / 200
+			err := world.GenerateCatacombs(segments, DefaultCatacombOptions())
+			world.AddWalls()
+			return err
+		}
+		p.Dressing = []DressingPass{ScatterDressing("decor", TileChest, FloorKindAlcove, 0.05)}
+	case PresetMine:
+		p.Biome = BiomeCave
+		p.WallThickness = 2
+		p.MinCorridorSize = 2 // 2 wide tunnels
+		p.MaxCorridorSize = 2
+		p.Generator = func(world *World) error {
+			err := world.GenerateRandomWalk(world.Width * world.Height / 4)
+			world.CleanIslands()
+			world.CleanWalls(5)
+			world.CleanWalls(5)
+			world.CleanIslands()
+			world.AddWalls()
+			return err
+		}
+		p.Dressing = []DressingPass{ScatterDressing("decor", TilePillar, FloorKindCave, 0.02)}
+	case PresetSewer:
+		p.Biome = BiomeDungeon
+		p.MaxCorridorSize = 2
+		p.MaxRoomWidth = 10
+		p.MaxRoomHeight = 10
+		p.Generator = func(world *World) error {
+			cw, ch := world.MaxRoomWidth+world.WallThickness, world.MaxRoomHeight+world.WallThickness
+			_, err := world.GenerateDungeonGrid(world.Width * world.Height / (cw * ch * 3))
+			world.AddWalls()
+			world.AddChasms(TileChasm, 40)
+			return err
+		}
+		// Swap the channels for water, remove this to get chasms instead
+		p.Palette[TileChasm] = TileWater
+	case PresetFortress:
+		p.Biome = BiomeDungeon
+		p.WallThickness = 2
+		p.MaxCorridorSize = 2
+		p.AllowRandomCorridorOffset = true
+		p.MaxRoomWidth = 12
+		p.MaxRoomHeight = 10
+		p.MinRoomWidth = 8
+		p.MinRoomHeight = 6
+		p.Generator = func(world *World) error {
+			// As many rooms as GenerateDungeon allows
+			mw := (world.Width-world.Border*2)/world.MaxRoomWidth - 2
+			mh := (world.Height-world.Border*2)/world.MaxRoomWidth - 2
+			_, err := world.GenerateDungeon(maxInt(mw*mh, 1))
+			world.AddWalls()
+			return err
+		}
+		p.Dressing = []DressingPass{ScatterDressing("decor", TilePillar, FloorKindRoom, 0.03)}
+	}
+	return p
+}
+
+// Generate sets the world's parameters from the preset, generates it, applies the palette and biome, then runs the
+// dressing passes
+func (p *PresetConfig) Generate(world *World) error {
+	world.Border = p.Border
+	world.WallThickness = p.WallThickness
+	world.MinCorridorSize = p.MinCorridorSize
+	world.MaxCorridorSize = p.MaxCorridorSize
+	world.AllowRandomCorridorOffset = p.AllowRandomCorridorOffset
+	world.MaxRoomWidth = p.MaxRoomWidth
+	world.MaxRoomHeight = p.MaxRoomHeight
+	world.MinRoomWidth = p.MinRoomWidth
+	world.MinRoomHeight = p.MinRoomHeight
+
+	if p.Generator == nil {
+		return ErrNoGenerator
+	}
+	err := p.Generator(world)
+
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			t := world.Tiles[y][x]
+			if to, ok := p.Palette[t]; ok {
+				world.Tiles[y][x] = to
+				t = to
+			}
+			if t != TileVoid && world.Biomes[y][x] == BiomeNone {
+				world.Biomes[y][x] = p.Biome
+			}
+		}
+	}
+
+	if len(p.Dressing) > 0 {
+		for _, pass := range p.Dressing {
+			world.AddDressing(pass)
+		}
+		world.Redecorate(world.rng.Int63())
+	}
+	return err
+}
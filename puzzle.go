@@ -0,0 +1,449 @@
+package generate
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PuzzleKind is a kind of puzzle laid out by PuzzleDressing
+type PuzzleKind int8
+
+// Puzzle kinds
+const (
+	PuzzleBlocks   PuzzleKind = iota // push every TileBlock onto a TilePlate
+	PuzzleSwitches                   // pull TileLever tiles to open every TilePortcullis
+)
+
+// PuzzleOptions controls PuzzleDressing
+type PuzzleOptions struct {
+	Kinds      []PuzzleKind // one is picked at random for each room, all of them if it's empty
+	Difficulty float64      // 0-1, harder puzzles have more pieces and longer solutions
+}
+
+// DefaultPuzzleOptions returns options for puzzles of every kind at medium difficulty
+func DefaultPuzzleOptions() PuzzleOptions {
+	return PuzzleOptions{Difficulty: 0.5}
+}
+
+// Puzzle is a puzzle laid out in a room by PuzzleDressing
+type Puzzle struct {
+	Room    Rect
+	Kind    PuzzleKind
+	Blocks  []Point // PuzzleBlocks; where each block starts
+	Plates  []Point // PuzzleBlocks; where the blocks have to be pushed to, in any order
+	Levers  []Point // PuzzleSwitches
+	Gates   []Point // PuzzleSwitches; every portcullis is closed to begin with
+	Toggles [][]int // PuzzleSwitches; the indexes into Gates which each lever opens or closes
+	Rewards []Point // PuzzleSwitches; a TileChest shut behind the portcullises if the room only has one entrance
+	Moves   int     // pushes or lever pulls in the shortest solution
+}
+
+// Limits on the puzzle search
+const (
+	puzzleAttempts  = 30    // puzzles generated for each room, the hardest one is kept
+	puzzleMaxStates = 20000 // states the block solver visits before giving up on a puzzle
+)
+
+// PuzzleDressing returns a pass which lays out a puzzle in every room tagged with TagPuzzle, on the named layer.
+// Block puzzles are made by pulling blocks off their plates, so they can always be pushed back, and switch puzzles
+// put a portcullis over every entrance but the first (or around a TileChest if there's only one) with levers which
+// each open and close some of them, or none at all. Either way the puzzle is checked with a solver and the hardest of a few attempts
+// is kept. Blocks and plates stay clear of the entrances so the way through the room can't be blocked for good.
+// The puzzles are recorded in world.Puzzles
+func PuzzleDressing(name string, opts PuzzleOptions) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		layer := world.Layer(name)
+		kinds := opts.Kinds
+		if len(kinds) == 0 {
+			kinds = []PuzzleKind{PuzzleBlocks, PuzzleSwitches}
+		}
+		difficulty := clampFloat(opts.Difficulty, 0, 1)
+		for _, room := range world.RoomsOrdered() {
+			if !world.RoomHasTag(room, TagPuzzle) {
+				continue
+			}
+			entrances := world.roomEntrances(room)
+			if len(entrances) == 0 {
+				continue
+			}
+			area := make(map[Point]bool)
+			for y := room.Y; y < room.Y+room.H; y++ {
+				for x := room.X; x < room.X+room.W; x++ {
+					if world.walkable(x, y) && layer[y][x] == TileVoid {
+						area[Point{X: x, Y: y}] = true
+					}
+				}
+			}
+
+			var puzzle Puzzle
+			var ok bool
+			switch kinds[rng.Intn(len(kinds))] {
+			case PuzzleBlocks:
+				puzzle, ok = blockPuzzle(rng, area, entrances, difficulty)
+			case PuzzleSwitches:
+				puzzle, ok = switchPuzzle(rng, area, entrances, difficulty)
+			}
+			if !ok {
+				continue
+			}
+			puzzle.Room = room
+			for _, p := range puzzle.Plates {
+				layer[p.Y][p.X] = TilePlate
+			}
+			for _, p := range puzzle.Blocks {
+				layer[p.Y][p.X] = TileBlock
+			}
+			for _, p := range puzzle.Levers {
+				layer[p.Y][p.X] = TileLever
+			}
+			for _, p := range puzzle.Gates {
+				layer[p.Y][p.X] = TilePortcullis
+			}
+			for _, p := range puzzle.Rewards {
+				layer[p.Y][p.X] = TileChest
+			}
+			world.Puzzles = append(world.Puzzles, puzzle)
+		}
+	}
+}
+
+// blockPuzzle lays out blocks and plates in area, returning false if no solvable puzzle could be made
+func blockPuzzle(rng *rand.Rand, area map[Point]bool, entrances []Point, difficulty float64) (Puzzle, bool) {
+	// Blocks are never on or next to an entrance
+	movable := func(p Point) bool {
+		if !area[p] {
+			return false
+		}
+		for _, e := range entrances {
+			if absInt(e.X-p.X) <= 1 && absInt(e.Y-p.Y) <= 1 {
+				return false
+			}
+		}
+		return true
+	}
+	spots := make([]Point, 0)
+	for p := range area {
+		if movable(p) {
+			spots = append(spots, p)
+		}
+	}
+	sortPoints(spots)
+	count := 1 + int(difficulty*2+0.5)
+	pulls := 5 + int(difficulty*25)
+	if len(spots) < count*3 {
+		return Puzzle{}, false
+	}
+
+	var best Puzzle
+	for a := 0; a < puzzleAttempts; a++ {
+		plates := make([]Point, 0, count)
+		taken := make(map[Point]bool)
+		for _, i := range rng.Perm(len(spots))[:count] {
+			plates = append(plates, spots[i])
+			taken[spots[i]] = true
+		}
+
+		// Pull the blocks off the plates from random places the player can reach, the reverse of pushing them on
+		blocks := make(map[Point]bool)
+		for _, p := range plates {
+			blocks[p] = true
+		}
+		free := func(p Point) bool { return area[p] && !blocks[p] }
+		player := spots[rng.Intn(len(spots))]
+		for blocks[player] {
+			player = spots[rng.Intn(len(spots))]
+		}
+		for i := 0; i < pulls; i++ {
+			type pull struct{ block, dir Point }
+			moves := make([]pull, 0)
+			reach := floodPoints(player, free)
+			for _, b := range sortedPoints(blocks) {
+				for _, d := range polarOffsets {
+					at := Point{X: b.X + d.X, Y: b.Y + d.Y}
+					to := Point{X: at.X + d.X, Y: at.Y + d.Y}
+					if reach[at] && free(to) && movable(at) {
+						moves = append(moves, pull{block: b, dir: d})
+					}
+				}
+			}
+			if len(moves) == 0 {
+				break
+			}
+			m := moves[rng.Intn(len(moves))]
+			delete(blocks, m.block)
+			blocks[Point{X: m.block.X + m.dir.X, Y: m.block.Y + m.dir.Y}] = true
+			player = Point{X: m.block.X + m.dir.X*2, Y: m.block.Y + m.dir.Y*2}
+		}
+
+		// The room has to stay passable both before and after the puzzle is solved
+		start := sortedPoints(blocks)
+		if !joinsEntrances(entrances, free) {
+			continue
+		}
+		solved := func(p Point) bool { return area[p] && !taken[p] }
+		if !joinsEntrances(entrances, solved) {
+			continue
+		}
+		moves := solveBlocks(area, movable, start, plates, entrances[0])
+		if moves > best.Moves {
+			best = Puzzle{Kind: PuzzleBlocks, Blocks: start, Plates: plates, Moves: moves}
+			if moves >= 1+int(difficulty*float64(pulls)/2) {
+				break
+			}
+		}
+	}
+	return best, best.Moves > 0
+}
+
+// solveBlocks returns the fewest pushes which get every block onto a plate starting from start, or -1 if there's no
+// solution within puzzleMaxStates. Blocks can only be pushed onto movable tiles
+func solveBlocks(area map[Point]bool, movable func(Point) bool, blocks, plates []Point, start Point) int {
+	type state struct {
+		blocks []Point
+		player Point
+	}
+	onPlates := func(blocks []Point) bool {
+		for _, p := range plates {
+			found := false
+			for _, b := range blocks {
+				found = found || b == p
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	// key identifies a state by its blocks and the first tile the player can reach, which every reachable tile shares
+	key := func(blocks []Point, reach map[Point]bool) string {
+		var sb strings.Builder
+		for _, b := range blocks {
+			sb.WriteString(strconv.Itoa(b.X) + "," + strconv.Itoa(b.Y) + ";")
+		}
+		first := sortedPoints(reach)
+		if len(first) > 0 {
+			sb.WriteString(strconv.Itoa(first[0].X) + "," + strconv.Itoa(first[0].Y))
+		}
+		return sb.String()
+	}
+
+	if onPlates(blocks) {
+		return 0
+	}
+	seen := make(map[string]bool)
+	frontier := []state{{blocks: blocks, player: start}}
+	for pushes := 1; len(frontier) > 0 && len(seen) < puzzleMaxStates; pushes++ {
+		next := make([]state, 0)
+		for _, s := range frontier {
+			taken := make(map[Point]bool, len(s.blocks))
+			for _, b := range s.blocks {
+				taken[b] = true
+			}
+			free := func(p Point) bool { return area[p] && !taken[p] }
+			reach := floodPoints(s.player, free)
+			k := key(s.blocks, reach)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			for i, b := range s.blocks {
+				for _, d := range polarOffsets {
+					from := Point{X: b.X - d.X, Y: b.Y - d.Y}
+					to := Point{X: b.X + d.X, Y: b.Y + d.Y}
+					if !reach[from] || !free(to) || !movable(to) {
+						continue
+					}
+					moved := append([]Point(nil), s.blocks...)
+					moved[i] = to
+					sortPoints(moved)
+					if onPlates(moved) {
+						return pushes
+					}
+					next = append(next, state{blocks: moved, player: b})
+				}
+			}
+		}
+		frontier = next
+	}
+	return -1
+}
+
+// switchPuzzle lays out levers and portcullises in area, returning false if no solvable puzzle could be made
+func switchPuzzle(rng *rand.Rand, area map[Point]bool, entrances []Point, difficulty float64) (Puzzle, bool) {
+	// Entrances next to each other belong to the same doorway, every doorway but the first is closed off
+	groups := make([][]Point, 0)
+	grouped := make(map[Point]bool)
+	isEntrance := make(map[Point]bool)
+	for _, e := range entrances {
+		isEntrance[e] = true
+	}
+	for _, e := range entrances {
+		if !grouped[e] {
+			group := sortedPoints(floodPoints(e, func(p Point) bool { return isEntrance[p] }))
+			for _, p := range group {
+				grouped[p] = true
+			}
+			groups = append(groups, group)
+		}
+	}
+	closed := groups[1:]
+	var chest []Point
+	if len(closed) == 0 {
+		// Shut a chest away in the far corner instead
+		dist := bfsPoints(entrances[0], func(p Point) bool { return area[p] })
+		far, farDist := Point{}, 0
+		for _, p := range sortedPoints(area) {
+			if dist[p] > farDist {
+				far, farDist = p, dist[p]
+			}
+		}
+		gate := make([]Point, 0)
+		for _, d := range polarOffsets {
+			if n := (Point{X: far.X + d.X, Y: far.Y + d.Y}); area[n] && !isEntrance[n] {
+				gate = append(gate, n)
+			}
+		}
+		if farDist < 3 || len(gate) == 0 {
+			return Puzzle{}, false
+		}
+		chest = []Point{far}
+		sortPoints(gate)
+		closed = [][]Point{gate}
+	}
+	gated := make(map[Point]bool)
+	gates := make([]Point, 0)
+	for _, group := range closed {
+		for _, p := range group {
+			gated[p] = true
+			gates = append(gates, p)
+		}
+	}
+	for _, p := range chest {
+		gated[p] = true
+	}
+	reach := floodPoints(entrances[0], func(p Point) bool { return area[p] && !gated[p] })
+	spots := make([]Point, 0)
+	for _, p := range sortedPoints(reach) {
+		if !isEntrance[p] {
+			spots = append(spots, p)
+		}
+	}
+	count := 2 + int(difficulty*3+0.5)
+	if len(spots) < count || len(gates) > 16 {
+		return Puzzle{}, false
+	}
+
+	// Each portcullis opens and closes on its own, bit i of a lever's mask is set if it toggles gates[i]
+	goal := uint64(1)<<uint(len(gates)) - 1
+	var best Puzzle
+	for a := 0; a < puzzleAttempts; a++ {
+		levers := make([]Point, 0, count)
+		for _, i := range rng.Perm(len(spots))[:count] {
+			levers = append(levers, spots[i])
+		}
+		masks := make([]uint64, count)
+		// Levers which don't toggle anything are decoys
+		for i := range masks {
+			masks[i] = uint64(rng.Int63()) & goal
+		}
+		moves := solveLevers(masks, goal)
+		if moves <= best.Moves {
+			continue
+		}
+		toggles := make([][]int, count)
+		for i, mask := range masks {
+			for g := range gates {
+				if mask&(1<<uint(g)) != 0 {
+					toggles[i] = append(toggles[i], g)
+				}
+			}
+		}
+		best = Puzzle{Kind: PuzzleSwitches, Levers: levers, Gates: gates, Toggles: toggles, Moves: moves}
+		if moves >= 1+int(difficulty*float64(count-1)+0.5) {
+			break
+		}
+	}
+	if best.Moves == 0 {
+		return best, false
+	}
+	best.Rewards = chest
+	return best, true
+}
+
+// solveLevers returns the fewest levers which have to be pulled so their masks add up to goal, pulling a lever twice
+// undoes it so each one is pulled at most once. -1 is returned if there's no solution
+func solveLevers(masks []uint64, goal uint64) int {
+	best := -1
+	for set := 0; set < 1<<uint(len(masks)); set++ {
+		var open uint64
+		pulled := 0
+		for i, mask := range masks {
+			if set&(1<<uint(i)) != 0 {
+				open ^= mask
+				pulled++
+			}
+		}
+		if open == goal && (best == -1 || pulled < best) {
+			best = pulled
+		}
+	}
+	return best
+}
+
+// joinsEntrances returns true if every entrance can be reached from the first through passable tiles
+func joinsEntrances(entrances []Point, passable func(Point) bool) bool {
+	reach := floodPoints(entrances[0], passable)
+	for _, e := range entrances {
+		if !reach[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// floodPoints returns every point which can be reached from from through passable points in the 4 polar directions
+func floodPoints(from Point, passable func(Point) bool) map[Point]bool {
+	dist := bfsPoints(from, passable)
+	reach := make(map[Point]bool, len(dist))
+	for p := range dist {
+		reach[p] = true
+	}
+	return reach
+}
+
+// bfsPoints returns the distance of every point which can be reached from from through passable points
+func bfsPoints(from Point, passable func(Point) bool) map[Point]int {
+	dist := map[Point]int{from: 0}
+	queue := []Point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, o := range polarOffsets {
+			n := Point{X: p.X + o.X, Y: p.Y + o.Y}
+			if _, ok := dist[n]; !ok && passable(n) {
+				dist[n] = dist[p] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	return dist
+}
+
+// sortedPoints returns the points in a set in row order
+func sortedPoints(set map[Point]bool) []Point {
+	points := make([]Point, 0, len(set))
+	for p := range set {
+		points = append(points, p)
+	}
+	sortPoints(points)
+	return points
+}
+
+// sortPoints sorts points in row order
+func sortPoints(points []Point) {
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Y < points[j].Y || points[i].Y == points[j].Y && points[i].X < points[j].X
+	})
+}
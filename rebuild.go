@@ -0,0 +1,166 @@
+package generate
+
+// RebuildMetadata works out the structure of the world again from its tiles, after they've been edited by hand or
+// imported, such as with ReadLDtk or ApplyDelta, so the analysis and decoration passes still work on the map. Rooms are
+// found again with InferRooms, along with the corridors and doors between them. Doors which are still on walkable
+// tiles are kept where a passage goes through them, with their tags, instead of the doors InferRooms picks, and the
+// rest are dropped. Each new room takes the tags, name and theme of the old room it overlaps the most, and sectors,
+// gates, ledges, puzzles, portals and landmarks are moved onto the new rooms and doors, dropping those whose tiles
+// can't be walked on any more. The room graph of the rebuilt world is returned
+func (world *World) RebuildMetadata() *Graph {
+	world.index = nil
+	world.exits = nil
+	world.budgets = nil
+
+	oldRooms := world.RoomsOrdered()
+	oldTags, oldNames, oldThemes := world.RoomTags, world.RoomNames, world.RoomThemes
+	// Corridors which don't lead to rooms are kept by InferRooms, but only if they can still be walked
+	corridors := world.Corridors[:0]
+	for _, c := range world.Corridors {
+		if len(c.Rooms) == 0 && len(world.corridorTiles(c)) > 0 {
+			corridors = append(corridors, c)
+		}
+	}
+	world.Corridors = corridors
+	oldDoors := make([]Rect, 0, len(world.Doors))
+	oldDirs, oldDoorTags := world.Doors, world.DoorTags
+	for door := range world.Doors {
+		if world.rectWalkable(door) {
+			oldDoors = append(oldDoors, door)
+		}
+	}
+	sortRects(oldDoors)
+	world.Doors = make(map[Rect]DoorDirection)
+	world.DoorTags = make(map[Rect][]Tag)
+
+	world.InferRooms()
+
+	// The old doors replace the doors of the passages they're on
+	used := make(map[Rect]bool)
+	for i, c := range world.Corridors {
+		for _, door := range oldDoors {
+			if used[door] || !pathCrosses(c.Path, door) {
+				continue
+			}
+			used[door] = true
+			delete(world.Doors, c.Door)
+			world.Doors[door] = oldDirs[door]
+			world.TagDoor(door, oldDoorTags[door]...)
+			world.Corridors[i].Door = door
+			break
+		}
+	}
+
+	// moved returns the new room which overlaps old the most
+	moved := func(old Rect) (Rect, bool) {
+		var best Rect
+		bestArea := 0
+		for _, room := range world.RoomsOrdered() {
+			if area := overlapArea(old, room); area > bestArea {
+				best, bestArea = room, area
+			}
+		}
+		return best, bestArea > 0
+	}
+	for _, old := range oldRooms {
+		room, ok := moved(old)
+		if !ok {
+			continue
+		}
+		world.TagRoom(room, oldTags[old]...)
+		if name, ok := oldNames[old]; ok && world.RoomNames[room] == "" {
+			world.RoomNames[room] = name
+		}
+		if theme, ok := oldThemes[old]; ok {
+			if _, set := world.RoomThemes[room]; !set {
+				world.RoomThemes[room] = theme
+			}
+		}
+	}
+
+	for i, s := range world.Sectors {
+		rooms := make([]Rect, 0, len(s.Rooms))
+		seen := make(map[Rect]bool)
+		for _, old := range s.Rooms {
+			if room, ok := moved(old); ok && !seen[room] {
+				seen[room] = true
+				rooms = append(rooms, room)
+			}
+		}
+		world.Sectors[i].Rooms = rooms
+	}
+	gates := world.Gates[:0]
+	for _, g := range world.Gates {
+		if _, ok := world.Doors[g.Door]; ok {
+			gates = append(gates, g)
+		}
+	}
+	world.Gates = gates
+	ledges := make(map[Rect]Rect)
+	for door, room := range world.Ledges {
+		if _, ok := world.Doors[door]; !ok {
+			continue
+		}
+		if room, ok := moved(room); ok {
+			ledges[door] = room
+		}
+	}
+	world.Ledges = ledges
+	puzzles := world.Puzzles[:0]
+	for _, p := range world.Puzzles {
+		if room, ok := moved(p.Room); ok {
+			p.Room = room
+			puzzles = append(puzzles, p)
+		}
+	}
+	world.Puzzles = puzzles
+	portals := world.Portals[:0]
+	for _, p := range world.Portals {
+		if world.walkable(p.A.X, p.A.Y) && world.walkable(p.B.X, p.B.Y) {
+			portals = append(portals, p)
+		}
+	}
+	world.Portals = portals
+	landmarks := world.Landmarks[:0]
+	for _, l := range world.Landmarks {
+		if world.walkable(l.Point.X, l.Point.Y) {
+			l.Room, l.InRoom = world.RoomAt(l.Point.X, l.Point.Y)
+			landmarks = append(landmarks, l)
+		}
+	}
+	world.Landmarks = landmarks
+
+	return world.BuildGraph()
+}
+
+// rectWalkable returns whether every tile of r can be walked on
+func (world *World) rectWalkable(r Rect) bool {
+	for y := r.Y; y < r.Y+r.H; y++ {
+		for x := r.X; x < r.X+r.W; x++ {
+			if !world.walkable(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// pathCrosses returns whether any point of path is in r
+func pathCrosses(path []Point, r Rect) bool {
+	for _, p := range path {
+		if r.contains(p.X, p.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapArea returns how many tiles a and b share
+func overlapArea(a, b Rect) int {
+	w := minInt(a.X+a.W, b.X+b.W) - maxInt(a.X, b.X)
+	h := minInt(a.Y+a.H, b.Y+b.H) - maxInt(a.Y, b.Y)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
@@ -0,0 +1,72 @@
+package generate
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Stats recorded after each generation, along with the name of the generator
+const (
+	StatGenerations = "generations" // counted once per generation
+	StatRetries     = "retries"     // times generation started over
+	StatRollbacks   = "rollbacks"   // rooms which had to be placed somewhere else
+	StatTimeouts    = "timeouts"    // generations which returned ErrGenerationTimeout
+	StatErrors      = "errors"      // generations which returned any error, including timeouts
+	StatDuration    = "duration"    // how long generation took
+)
+
+// StatsRecorder receives counters and timings from every generation of a world with world.Stats set, so server side
+// generation can be monitored. Implement it to forward stats to Prometheus or anything else, or use ExpvarRecorder.
+// It's called from whichever goroutine generated the world, so it must be safe for concurrent use
+type StatsRecorder interface {
+	Count(stat, generator string, delta int)
+	Timing(stat, generator string, d time.Duration)
+}
+
+// recordStats sends the finished world.Report to world.Stats
+func (world *World) recordStats() {
+	if world.Stats == nil {
+		return
+	}
+	r := world.Report
+	world.Stats.Count(StatGenerations, r.Generator, 1)
+	world.Stats.Count(StatRetries, r.Generator, r.Retries)
+	world.Stats.Count(StatRollbacks, r.Generator, r.Rollbacks)
+	if r.Err == ErrGenerationTimeout {
+		world.Stats.Count(StatTimeouts, r.Generator, 1)
+	}
+	if r.Err != nil {
+		world.Stats.Count(StatErrors, r.Generator, 1)
+	}
+	world.Stats.Timing(StatDuration, r.Generator, r.Duration)
+}
+
+// ExpvarRecorder is a StatsRecorder which publishes stats with expvar as a map of "generator.stat" keys. Timings are
+// the total number of nanoseconds spent
+type ExpvarRecorder struct {
+	vars *expvar.Map
+}
+
+var expvarMu sync.Mutex
+
+// NewExpvarRecorder returns an ExpvarRecorder publishing to the expvar map called name, which is shared with any other
+// recorder using the same name
+func NewExpvarRecorder(name string) *ExpvarRecorder {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if vars, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &ExpvarRecorder{vars: vars}
+	}
+	return &ExpvarRecorder{vars: expvar.NewMap(name)}
+}
+
+// Count adds delta to the counter for the stat
+func (r *ExpvarRecorder) Count(stat, generator string, delta int) {
+	r.vars.Add(generator+"."+stat, int64(delta))
+}
+
+// Timing adds d to the total time for the stat
+func (r *ExpvarRecorder) Timing(stat, generator string, d time.Duration) {
+	r.vars.Add(generator+"."+stat, int64(d))
+}
@@ -0,0 +1,133 @@
+package generate
+
+import "math/rand"
+
+// RegenerateOptions controls how RegenerateRoom rebuilds a room
+type RegenerateOptions struct {
+	MinWidth, MinHeight int            // smallest size of the new room, it's never bigger than the space it had
+	PillarChance        float64        // chance (0-1) of a TilePillar on each tile where one can't block the way
+	Dressing            []DressingPass // run to decorate the room, anything they place outside of it is undone
+}
+
+// DefaultRegenerateOptions returns options which keep rooms at least 3x3 with a few pillars and no dressing
+func DefaultRegenerateOptions() RegenerateOptions {
+	return RegenerateOptions{MinWidth: 3, MinHeight: 3, PillarChance: 0.2}
+}
+
+// RegenerateRoom rebuilds the room with the given RoomID inside the space it takes up now, for editors and for rooms
+// which look different each visit. The room gets a new random size and position within its old rect, with passages
+// walled in from each entrance so the doors and corridors around it don't move, and new pillars and decoration.
+// Compound rooms keep their rect and only get new contents. The room keeps its tags and its ID, budgets of the room
+// are dropped. The new rect is returned, ErrNoRooms if there's no room with that ID and ErrNotEnoughSpace if the
+// room is smaller than the minimum size
+func (world *World) RegenerateRoom(id int, opts RegenerateOptions) (Rect, error) {
+	rooms := world.RoomsOrdered()
+	if id < 0 || id >= len(rooms) {
+		return Rect{}, ErrNoRooms
+	}
+	room := rooms[id]
+	minW, minH := maxInt(opts.MinWidth, 1), maxInt(opts.MinHeight, 1)
+	if room.W < minW || room.H < minH {
+		return room, ErrNotEnoughSpace
+	}
+
+	regen := room
+	if len(world.RoomParts[room]) == 0 {
+		regen.W, regen.H = world.randInt(minW, room.W), world.randInt(minH, room.H)
+		regen.X += world.randInt(0, room.W-regen.W)
+		regen.Y += world.randInt(0, room.H-regen.H)
+	}
+	keep := world.entrancePassages(room, regen)
+	wall := world.roomWallTile(room)
+	for y := room.Y; y < room.Y+room.H; y++ {
+		for x := room.X; x < room.X+room.W; x++ {
+			switch {
+			case regen.contains(x, y):
+				world.setFloor(x, y, FloorKindRoom)
+			case keep[Point{X: x, Y: y}]:
+				world.setFloor(x, y, FloorKindCorridor)
+			default:
+				world.SetTile(x, y, wall)
+				world.FloorKinds[y][x] = FloorKindNone
+			}
+		}
+	}
+
+	// Pillars on every other tile away from the edge never touch each other, so they can't cut the room in two
+	for y := regen.Y + 1; y < regen.Y+regen.H-1; y += 2 {
+		for x := regen.X + 1; x < regen.X+regen.W-1; x += 2 {
+			if world.rng.Float64() < opts.PillarChance {
+				world.SetTile(x, y, TilePillar)
+			}
+		}
+	}
+
+	world.redecorateRoom(room, opts.Dressing)
+	world.replaceRoom(room, regen)
+	return regen, nil
+}
+
+// redecorateRoom clears the decoration in room and runs passes, undoing anything they change outside of it
+func (world *World) redecorateRoom(room Rect, passes []DressingPass) {
+	for _, layer := range world.Layers {
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				layer[y][x] = TileVoid
+			}
+		}
+	}
+	for p := range world.Facing {
+		if room.contains(p.X, p.Y) {
+			delete(world.Facing, p)
+		}
+	}
+	if len(passes) == 0 {
+		return
+	}
+
+	layers := make(map[string]Layer, len(world.Layers))
+	for name, layer := range world.Layers {
+		layers[name] = copyLayer(layer)
+	}
+	facing := make(map[Point]Direction, len(world.Facing))
+	for p, d := range world.Facing {
+		facing[p] = d
+	}
+	r := rand.New(rand.NewSource(world.rng.Int63()))
+	for _, pass := range passes {
+		pass(world, r)
+	}
+
+	for name, layer := range world.Layers {
+		old := layers[name]
+		for y := range layer {
+			for x := range layer[y] {
+				if room.contains(x, y) {
+					continue
+				}
+				if old != nil {
+					layer[y][x] = old[y][x]
+				} else {
+					layer[y][x] = TileVoid
+				}
+			}
+		}
+	}
+	for p := range world.Facing {
+		if !room.contains(p.X, p.Y) {
+			delete(world.Facing, p)
+		}
+	}
+	for p, d := range facing {
+		world.Facing[p] = d
+	}
+}
+
+// copyLayer returns a copy of layer
+func copyLayer(layer Layer) Layer {
+	c := make(Layer, len(layer))
+	for y := range layer {
+		c[y] = append([]Tile(nil), layer[y]...)
+	}
+	return c
+}
@@ -0,0 +1,237 @@
+package generate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// RenderMode chooses what Render colors tiles by
+type RenderMode int8
+
+// Render modes
+const (
+	RenderModeTile   RenderMode = iota // color by tile type, with doors and decoration layers drawn on top
+	RenderModeBiome                    // color by world.Biomes
+	RenderModeSector                   // color by world.Sectors, or by room if there aren't any sectors
+)
+
+// tileStyle is how a tile is drawn by Render, glyphs are 2 characters wide so the map keeps its aspect ratio
+type tileStyle struct {
+	name   string
+	glyph  string
+	fg, bg int // ANSI 256 color codes
+}
+
+var tileStyles = map[Tile]tileStyle{
+	TileVoid:       {"void", "  ", 0, 16},
+	TileWall:       {"wall", "##", 250, 240},
+	TilePreWall:    {"pre-wall", "++", 250, 238},
+	TileFloor:      {"floor", "..", 244, 234},
+	TileDoor:       {"door", "[]", 230, 94},
+	TileRoomBegin:  {"room begin", "<<", 46, 234},
+	TileRoomEnd:    {"room end", ">>", 196, 234},
+	TileWater:      {"water", "~~", 45, 25},
+	TileChasm:      {"chasm", "  ", 53, 53},
+	TileBridge:     {"bridge", "==", 223, 130},
+	TileChest:      {"chest", "$$", 226, 234},
+	TileGrass:      {"grass", "\"\"", 120, 28},
+	TileTree:       {"tree", "♣♣", 22, 28},
+	TileRoad:       {"road", "::", 180, 137},
+	TileSand:       {"sand", "..", 180, 222},
+	TileBoat:       {"boat", "<>", 231, 25},
+	TilePillar:     {"pillar", "()", 252, 242},
+	TileLowWall:    {"low wall", "--", 250, 236},
+	TileCounter:    {"counter", "__", 223, 94},
+	TileShelf:      {"shelf", "||", 180, 58},
+	TileNPC:        {"npc", "@@", 213, 234},
+	TilePortal:     {"portal", "()", 201, 54},
+	TileBush:       {"bush", "%%", 70, 28},
+	TileStairs:     {"stairs", "//", 250, 236},
+	TileGas:        {"gas", "~~", 149, 58},
+	TileCold:       {"cold", "**", 195, 24},
+	TileDark:       {"darkness", "..", 237, 232},
+	TileBlock:      {"block", "OO", 252, 94},
+	TilePlate:      {"plate", "__", 226, 234},
+	TileLever:      {"lever", "!!", 226, 234},
+	TilePortcullis: {"portcullis", "HH", 180, 94},
+	TileTrack:      {"track", "≡≡", 137, 234},
+	TileJunction:   {"junction", "++", 208, 234},
+}
+
+var biomeStyles = map[Biome]tileStyle{
+	BiomeNone:      {"none", "  ", 0, 234},
+	BiomeDungeon:   {"dungeon", "  ", 0, 60},
+	BiomeCave:      {"cave", "  ", 0, 94},
+	BiomeCrypt:     {"crypt", "  ", 0, 54},
+	BiomeGrassland: {"grassland", "  ", 0, 28},
+	BiomeForest:    {"forest", "  ", 0, 22},
+	BiomeBeach:     {"beach", "  ", 0, 222},
+	BiomeOcean:     {"ocean", "  ", 0, 25},
+}
+
+// regionColors are cycled through for sectors and rooms
+var regionColors = []int{124, 28, 25, 130, 91, 30, 166, 64, 61, 131, 29, 97}
+
+func (s tileStyle) write(w *bufio.Writer, glyph string) {
+	fmt.Fprintf(w, "\x1b[38;5;%dm\x1b[48;5;%dm%s", s.fg, s.bg, glyph)
+}
+
+// Render writes the world to w using ANSI 256 colors followed by a legend of everything shown
+func (world *World) Render(w io.Writer, mode RenderMode) error {
+	bw := bufio.NewWriter(w)
+	tiles := world.shownTiles(mode == RenderModeTile)
+
+	region := make(map[Point]int)
+	var regionNames []string
+	if mode == RenderModeSector {
+		if len(world.Sectors) > 0 {
+			for s, sector := range world.Sectors {
+				for _, room := range sector.Rooms {
+					for x := room.X; x < room.X+room.W; x++ {
+						for y := room.Y; y < room.Y+room.H; y++ {
+							region[Point{X: x, Y: y}] = s
+						}
+					}
+				}
+				regionNames = append(regionNames, fmt.Sprintf("sector %d", s))
+			}
+		} else {
+			for i, room := range world.sortedRooms() {
+				for x := room.X; x < room.X+room.W; x++ {
+					for y := room.Y; y < room.Y+room.H; y++ {
+						region[Point{X: x, Y: y}] = i
+					}
+				}
+				regionNames = append(regionNames, fmt.Sprintf("room %d", i))
+			}
+		}
+	}
+
+	usedTiles := make(map[Tile]struct{})
+	usedBiomes := make(map[Biome]struct{})
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			t := tiles[y][x]
+			style, ok := tileStyles[t]
+			if !ok && t.IsUser() {
+				style = tileStyle{name: fmt.Sprintf("user tile %d", t-TileUser), glyph: "??", fg: 226, bg: 16}
+			} else if !ok {
+				style = tileStyle{name: fmt.Sprintf("tile %d", t), glyph: "??", fg: 196, bg: 16}
+			}
+			switch mode {
+			case RenderModeBiome:
+				if t != TileVoid {
+					b := world.Biomes[y][x]
+					style.bg = biomeStyles[b].bg
+					usedBiomes[b] = struct{}{}
+				}
+			case RenderModeSector:
+				if r, ok := region[Point{X: x, Y: y}]; ok {
+					style.bg = regionColors[r%len(regionColors)]
+				}
+			}
+			usedTiles[t] = struct{}{}
+			style.write(bw, style.glyph)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+
+	// Legend
+	bw.WriteString("\n")
+	legendTiles := make([]Tile, 0, len(usedTiles))
+	for t := range usedTiles {
+		legendTiles = append(legendTiles, t)
+	}
+	sort.Slice(legendTiles, func(i, j int) bool { return legendTiles[i] < legendTiles[j] })
+	for _, t := range legendTiles {
+		if style, ok := tileStyles[t]; ok {
+			style.write(bw, style.glyph)
+			fmt.Fprintf(bw, "\x1b[0m %s\n", style.name)
+		}
+	}
+	switch mode {
+	case RenderModeBiome:
+		for b := BiomeNone; b <= BiomeOcean; b++ {
+			if _, ok := usedBiomes[b]; ok {
+				style := biomeStyles[b]
+				style.write(bw, style.glyph)
+				fmt.Fprintf(bw, "\x1b[0m %s\n", style.name)
+			}
+		}
+	case RenderModeSector:
+		for i, name := range regionNames {
+			tileStyle{bg: regionColors[i%len(regionColors)]}.write(bw, "  ")
+			fmt.Fprintf(bw, "\x1b[0m %s\n", name)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// shownTiles returns what's drawn at each tile, with the doors and decoration layers on top of the tiles if overlay is
+// set
+func (world *World) shownTiles(overlay bool) [][]Tile {
+	tiles := make([][]Tile, world.Height)
+	for y := range tiles {
+		tiles[y] = make([]Tile, world.Width)
+		copy(tiles[y], world.Tiles[y])
+	}
+	if !overlay {
+		return tiles
+	}
+	for door := range world.Doors {
+		for x := door.X; x < door.X+door.W; x++ {
+			for y := door.Y; y < door.Y+door.H; y++ {
+				tiles[y][x] = TileDoor
+			}
+		}
+	}
+	names := make([]string, 0, len(world.Layers))
+	for name := range world.Layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for y, row := range world.Layers[name] {
+			for x, t := range row {
+				if t != TileVoid {
+					tiles[y][x] = t
+				}
+			}
+		}
+	}
+	return tiles
+}
+
+// Art returns the world as plain text with one character per tile and a line per row, for snapshot tests and logs.
+// Tiles are drawn like RenderModeTile without colors, using the first character of their glyph, so doors and
+// decoration are shown on top and tiles which look alike there look alike here. Tiles without a glyph are drawn as ?
+func (world *World) Art() string {
+	var sb strings.Builder
+	for _, row := range world.shownTiles(true) {
+		for _, t := range row {
+			glyph := "?"
+			if style, ok := tileStyles[t]; ok {
+				glyph = style.glyph
+			}
+			r, _ := utf8.DecodeRuneInString(glyph)
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// sortedRooms returns the rooms sorted top to bottom, left to right
+func (world *World) sortedRooms() []Rect {
+	rooms := make([]Rect, 0, len(world.Rooms))
+	for room := range world.Rooms {
+		rooms = append(rooms, room)
+	}
+	sortRects(rooms)
+	return rooms
+}
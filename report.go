@@ -0,0 +1,39 @@
+package generate
+
+import "time"
+
+// GenReport describes the last generation
+type GenReport struct {
+	Generator      string
+	RoomsRequested int // 0 for generators which aren't asked for a number of rooms
+	RoomsPlaced    int
+	Retries        int // how many times generation started over after world.DurationBeforeRetry
+	Rollbacks      int // how many rooms couldn't be placed and were tried somewhere else
+	Duration       time.Duration
+	Err            error
+}
+
+// beginReport starts world.Report for a generator. Generators which call other generators only get one report
+func (world *World) beginReport(generator string, roomsRequested int) {
+	world.reportDepth++
+	if world.reportDepth > 1 {
+		return
+	}
+	world.Report = GenReport{
+		Generator:      generator,
+		RoomsRequested: roomsRequested,
+	}
+	world.reportStart = time.Now()
+}
+
+// endReport finishes world.Report with the generator's result
+func (world *World) endReport(err error) {
+	world.reportDepth--
+	if world.reportDepth > 0 {
+		return
+	}
+	world.Report.RoomsPlaced = len(world.Rooms)
+	world.Report.Duration = time.Now().Sub(world.reportStart)
+	world.Report.Err = err
+	world.recordStats()
+}
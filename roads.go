@@ -0,0 +1,97 @@
+package generate
+
+// RoadStyle controls what ConnectPOIs carves
+type RoadStyle int8
+
+// Road styles
+const (
+	RoadStyleDirt     RoadStyle = iota // winding TileRoad paths for overworld maps, water is crossed with TileBridge
+	RoadStyleCorridor                  // TileFloor corridors for connecting structures inside of a dungeon
+)
+
+// roadWinding is how much the noise cost field can add to each step, higher values make roads wind more
+const roadWinding = 4
+
+// ConnectPOIs carves paths between points of interest so every point can be reached from every other point. Each
+// point is connected to the closest point which has already been connected, and paths wind along a noise cost field
+// so they don't look ruled. Existing roads and floors are reused where possible and world.RouteCost is applied on top.
+// RoadStyleCorridor paths are added to world.Corridors
+func (world *World) ConnectPOIs(points []Point, style RoadStyle) error {
+	if len(points) < 2 {
+		return nil
+	}
+
+	n := newNoise(world.rng)
+	cost := func(x, y int) float64 {
+		if x < world.Border || y < world.Border || x >= world.Width-world.Border || y >= world.Height-world.Border {
+			return -1
+		}
+		wind := 1 + roadWinding*n.Fractal(float64(x)/10, float64(y)/10, 2)
+		switch style {
+		case RoadStyleDirt:
+			switch world.Tiles[y][x] {
+			case TileRoad, TileBridge:
+				return 0.5
+			case TileTree:
+				return wind * 3
+			case TileWater:
+				return wind * 12
+			case TileWall, TilePreWall, TileChasm:
+				return -1
+			}
+		case RoadStyleCorridor:
+			switch world.Tiles[y][x] {
+			case TileWall, TilePreWall:
+				return wind * 5
+			case TileWater, TileChasm, TileTree:
+				return -1
+			}
+			if world.walkable(x, y) {
+				return 0.5
+			}
+		}
+		return wind
+	}
+
+	minCost, cost := world.routeCost(0.5, cost)
+
+	connected := []Point{points[0]}
+	for _, p := range points[1:] {
+		nearest := connected[0]
+		for _, c := range connected[1:] {
+			if p.distance(c) < p.distance(nearest) {
+				nearest = c
+			}
+		}
+
+		path := world.findPath(nearest, p, minCost, cost)
+		if path == nil {
+			return ErrNoPath
+		}
+		for _, t := range path {
+			world.carveRoad(t.X, t.Y, style)
+		}
+		if style == RoadStyleCorridor {
+			world.addCorridor(path, 1, Rect{})
+		}
+		connected = append(connected, p)
+	}
+	return nil
+}
+
+// carveRoad carves a single tile of road, keeping tiles which can already be walked on
+func (world *World) carveRoad(x, y int, style RoadStyle) {
+	switch style {
+	case RoadStyleDirt:
+		switch tile := world.Tiles[y][x]; {
+		case tile == TileWater:
+			world.SetTile(x, y, TileBridge)
+		case !tile.Walkable() || tile == TileGrass || tile == TileSand:
+			world.SetTile(x, y, TileRoad)
+		}
+	case RoadStyleCorridor:
+		if !world.walkable(x, y) {
+			world.setFloor(x, y, FloorKindCorridor)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package generate
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidGraph is returned by GenerateFromGraph when a link doesn't join two different nodes or some nodes can't be
+// reached from the first one
+var ErrInvalidGraph = errors.New("Room graph is invalid")
+
+// RoomNode is a room of a RoomGraph
+type RoomNode struct {
+	Name string // stored in world.RoomNames if it isn't empty
+	W, H int    // size of the room, 0 picks a random size from the room sizes of the zone it's placed from
+	Tags []Tag
+}
+
+// RoomLink connects two nodes of a RoomGraph by their index in Nodes
+type RoomLink struct {
+	From, To int
+	OneWay   bool  // the door is a ledge which can only be passed from From to To
+	Tags     []Tag // added to the door
+}
+
+// RoomGraph is a layout for GenerateFromGraph, the rooms a dungeon should have and which of them are connected
+type RoomGraph struct {
+	Nodes []RoomNode
+	Links []RoomLink
+}
+
+const (
+	// graphPlacementAttempts is how many spots GenerateFromGraph tries for each room before starting the layout again
+	graphPlacementAttempts = 50
+	// graphCrowdedCost is the cost of routing a link through a tile closer than the room separation to other floor
+	graphCrowdedCost = 4
+)
+
+// GenerateFromGraph lays out a dungeon with the rooms and connections of g, so layouts designed by hand or by a
+// grammar can be given a procedural shape. The first node is placed in the middle of the map and the rest are placed
+// breadth first, each beside the node which links to it first with a straight corridor between them like
+// GenerateDungeon, or nearby with a winding corridor when there's no space left beside it. Links which close loops are
+// routed as corridors around the other rooms. Each link gets a door, one way links get a ledge and the door tags of
+// the link. Nodes without a size which have more than 3 links get the largest room size so their links fit.
+// The rooms are returned in the order of g.Nodes. When the rooms don't fit the layout is started again with new sizes
+// and positions until world.DurationBeforeError is exceeded and ErrGenerationTimeout is returned, a room which is
+// bigger than the map returns ErrNotEnoughSpace straight away. ErrInvalidGraph is returned for links which don't join
+// two different nodes and for nodes which aren't linked to the first one
+func (world *World) GenerateFromGraph(g RoomGraph, overrides ...Option) (rooms []Rect, err error) {
+	defer world.override(overrides)()
+	world.beginReport("Graph", len(g.Nodes))
+	defer func() { world.endReport(err) }()
+
+	world.ResetWorld(world.Width, world.Height)
+	if len(g.Nodes) == 0 {
+		return nil, ErrNoRooms
+	}
+	n := len(g.Nodes)
+	links := make([][]int, n)
+	for i, link := range g.Links {
+		if link.From < 0 || link.To < 0 || link.From >= n || link.To >= n || link.From == link.To {
+			return nil, ErrInvalidGraph
+		}
+		links[link.From] = append(links[link.From], i)
+		links[link.To] = append(links[link.To], i)
+	}
+
+	// Place breadth first, each node is placed from the first link found to it
+	order := []int{0}
+	via := make([]int, n)
+	tree := make([]bool, len(g.Links))
+	seen := make([]bool, n)
+	seen[0] = true
+	for i := 0; i < len(order); i++ {
+		for _, l := range links[order[i]] {
+			next := g.Links[l].To
+			if next == order[i] {
+				next = g.Links[l].From
+			}
+			if !seen[next] {
+				seen[next] = true
+				via[next] = l
+				tree[l] = true
+				order = append(order, next)
+			}
+		}
+	}
+	if len(order) != n {
+		return nil, ErrInvalidGraph
+	}
+
+	b, sep := world.Border, world.roomSeparation(world.maxWallThickness())
+	for _, node := range g.Nodes {
+		if node.W > world.Width-(b+sep)*2 || node.H > world.Height-(b+sep)*2 {
+			return nil, ErrNotEnoughSpace
+		}
+	}
+
+	world.genStartTime = time.Now()
+	for {
+		if rooms, err = world.layoutGraph(g, order, via, tree); err == nil {
+			break
+		}
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return nil, ErrGenerationTimeout
+		}
+		world.logf("Graph didn't fit, retrying gen: %v", err)
+		world.Report.Retries++
+	}
+
+	for i, node := range g.Nodes {
+		if node.Name != "" {
+			world.RoomNames[rooms[i]] = node.Name
+		}
+		world.TagRoom(rooms[i], node.Tags...)
+	}
+	return rooms, nil
+}
+
+// layoutGraph makes one attempt at placing the nodes of g in order and carving the links between them. via is the
+// link each node is placed from and tree marks the links which place a node
+func (world *World) layoutGraph(g RoomGraph, order, via []int, tree []bool) ([]Rect, error) {
+	degree := make([]int, len(g.Nodes))
+	for _, link := range g.Links {
+		degree[link.From]++
+		degree[link.To]++
+	}
+	world.ResetWorld(world.Width, world.Height)
+	world.index = newSpatialIndex(world)
+	defer func() { world.index = nil }()
+
+	rooms := make([]Rect, len(g.Nodes))
+	p := world.paramsAt(world.Width/2, world.Height/2)
+	w, h := world.graphRoomSize(g.Nodes[order[0]], degree[order[0]], p)
+	rooms[order[0]] = Rect{X: (world.Width - w) / 2, Y: (world.Height - h) / 2, W: w, H: h}
+	if err := world.placeRoom(rooms[order[0]].X, rooms[order[0]].Y, w, h, p.WallThickness); err != nil {
+		return nil, err
+	}
+
+	for _, node := range order[1:] {
+		link := g.Links[via[node]]
+		from := link.From
+		if from == node {
+			from = link.To
+		}
+		room, door, err := world.placeLinkedRoom(rooms[from], g.Nodes[node], degree[node])
+		if err != nil {
+			return nil, err
+		}
+		rooms[node] = room
+		world.addLink(door, link, rooms)
+	}
+	for i, link := range g.Links {
+		if tree[i] {
+			continue
+		}
+		path := world.linkPath(rooms[link.From], rooms[link.To])
+		if path == nil {
+			return nil, ErrNoPath
+		}
+		world.addLink(world.carveLink(path, rooms[link.To]), link, rooms)
+	}
+	return rooms, nil
+}
+
+// graphRoomSize returns the size of the room for node, picking sizes from p for sizes which aren't set. Nodes with more
+// than 3 links get the largest size, a random size otherwise
+func (world *World) graphRoomSize(node RoomNode, degree int, p ZoneOverrides) (int, int) {
+	w, h := node.W, node.H
+	if w <= 0 {
+		w = world.randInt(p.MinRoomWidth, p.MaxRoomWidth)
+		if degree > 3 {
+			w = p.MaxRoomWidth
+		}
+	}
+	if h <= 0 {
+		h = world.randInt(p.MinRoomHeight, p.MaxRoomHeight)
+		if degree > 3 {
+			h = p.MaxRoomHeight
+		}
+	}
+	return w, h
+}
+
+// placeLinkedRoom places the room for node beside from, in a random direction and sliding along that side as long as
+// a straight corridor fits between them, then carves the corridor and returns the room and its door. When nothing
+// fits beside from the room is placed nearby instead and joined with a winding corridor
+func (world *World) placeLinkedRoom(from Rect, node RoomNode, degree int) (Rect, Rect, error) {
+	p := world.paramsAt(from.X+from.W/2, from.Y+from.H/2)
+	sep := world.roomSeparation(p.WallThickness)
+	for a := 0; a < graphPlacementAttempts; a++ {
+		w, h := world.graphRoomSize(node, degree, p)
+		cs := world.randInt(p.MinCorridorSize, p.MaxCorridorSize)
+		// offset returns where the corridor starts along the part of the side the rooms share
+		offset := func(shared int) int {
+			if world.AllowRandomCorridorOffset {
+				return world.randInt(0, shared-cs)
+			}
+			return centered(0, shared, cs)
+		}
+
+		room := Rect{W: w, H: h}
+		var corridor Rect
+		dir := DoorDirectionHorizontal
+		switch d := world.randomDirection(); d {
+		case DirectionWest, DirectionEast:
+			cs = minInt(cs, minInt(h, from.H))
+			room.Y = world.randInt(from.Y-h+cs, from.Y+from.H-cs)
+			lo, hi := maxInt(from.Y, room.Y), minInt(from.Y+from.H, room.Y+h)
+			corridor = Rect{X: from.X + from.W, Y: lo + offset(hi-lo), W: sep, H: cs}
+			room.X = from.X + from.W + sep
+			if d == DirectionWest {
+				corridor.X = from.X - sep
+				room.X = from.X - sep - w
+			}
+			dir = DoorDirectionVertical
+		case DirectionNorth, DirectionSouth:
+			cs = minInt(cs, minInt(w, from.W))
+			room.X = world.randInt(from.X-w+cs, from.X+from.W-cs)
+			lo, hi := maxInt(from.X, room.X), minInt(from.X+from.W, room.X+w)
+			corridor = Rect{X: lo + offset(hi-lo), Y: from.Y + from.H, W: cs, H: sep}
+			room.Y = from.Y + from.H + sep
+			if d == DirectionNorth {
+				corridor.Y = from.Y - sep
+				room.Y = from.Y - sep - h
+			}
+		}
+
+		if err := world.placeRoom(room.X, room.Y, room.W, room.H, p.WallThickness); err != nil {
+			world.heat(HeatmapRetries, room.X+room.W/2, room.Y+room.H/2, 1)
+			world.Report.Rollbacks++
+			continue
+		}
+		for y := corridor.Y; y < corridor.Y+corridor.H; y++ {
+			for x := corridor.X; x < corridor.X+corridor.W; x++ {
+				world.setFloor(x, y, FloorKindCorridor)
+			}
+		}
+		return room, world.addDoorway(corridor, dir), nil
+	}
+
+	// Rooms which are crowded have no space left beside them, so try further away with a winding corridor
+	reach := maxInt(p.MaxRoomWidth, p.MaxRoomHeight)*2 + sep
+	for a := 0; a < graphPlacementAttempts; a++ {
+		w, h := world.graphRoomSize(node, degree, p)
+		x := world.randInt(from.X-reach-w, from.X+from.W+reach)
+		y := world.randInt(from.Y-reach-h, from.Y+from.H+reach)
+		if err := world.checkRoom(x, y, w, h, p.WallThickness); err != nil {
+			world.Report.Rollbacks++
+			continue
+		}
+		room := Rect{X: x, Y: y, W: w, H: h}
+		path := world.linkPath(from, room)
+		if path == nil {
+			world.Report.Rollbacks++
+			continue
+		}
+		world.placeRoom(x, y, w, h, p.WallThickness)
+		return room, world.carveLink(path, room), nil
+	}
+	return Rect{}, Rect{}, ErrNotEnoughSpace
+}
+
+// linkPath returns a path a tile wide from room a to room b, not including either room, which never touches any other
+// floor so it doesn't open into anything else, and keeps the room separation away from it where it can. It enters b
+// straight on through a tile of its side so a door there is held between walls. b doesn't have to be placed yet. nil
+// is returned if there's no path
+func (world *World) linkPath(a, b Rect) []Point {
+	// How close each tile is to other floor, up to the room separation
+	sep := world.roomSeparation(world.maxWallThickness())
+	near := make([][]int, world.Height)
+	for y := range near {
+		near[y] = make([]int, world.Width)
+		for x := range near[y] {
+			near[y][x] = sep + 2
+		}
+	}
+	for y := range world.Tiles {
+		for x, t := range world.Tiles[y] {
+			if t != TileFloor || a.contains(x, y) || b.contains(x, y) {
+				continue
+			}
+			for ny := maxInt(y-sep-1, 0); ny <= minInt(y+sep+1, world.Height-1); ny++ {
+				for nx := maxInt(x-sep-1, 0); nx <= minInt(x+sep+1, world.Width-1); nx++ {
+					near[ny][nx] = minInt(near[ny][nx], maxInt(absInt(nx-x), absInt(ny-y)))
+				}
+			}
+		}
+	}
+	bo := world.Border
+	cost := func(x, y int) float64 {
+		switch {
+		case a.contains(x, y) || b.contains(x, y):
+			return 1
+		case b.closedAround(x, y):
+			return -1
+		case x < bo || y < bo || x >= world.Width-bo || y >= world.Height-bo || near[y][x] <= 1:
+			return -1
+		case near[y][x] <= sep+1:
+			return graphCrowdedCost
+		}
+		return 1
+	}
+	path := world.findPath(Point{X: a.X + a.W/2, Y: a.Y + a.H/2}, Point{X: b.X + b.W/2, Y: b.Y + b.H/2}, 1, cost)
+
+	// Keep the part of the path between the rooms
+	start := 0
+	for start < len(path) && a.contains(path[start].X, path[start].Y) {
+		start++
+	}
+	end := start
+	for end < len(path) && !b.contains(path[end].X, path[end].Y) {
+		end++
+	}
+	if end == start || end == len(path) {
+		return nil
+	}
+	// Only the last tile beside a and the first beside b are kept, so the path doesn't run along either room before
+	// turning in and opens into each through a single tile
+	for i := end - 1; i > start; i-- {
+		if a.beside(path[i].X, path[i].Y) {
+			start = i
+			break
+		}
+	}
+	for i := start; i < end; i++ {
+		if b.beside(path[i].X, path[i].Y) {
+			end = i + 1
+			break
+		}
+	}
+	path = path[start:end]
+
+	// BuildGraph finds the rooms of a door by looking straight through it, so it mustn't see a different room
+	last := path[len(path)-1]
+	ra, rb, ok := world.doorRoomsWith(Rect{X: last.X, Y: last.Y, W: 1, H: 1}, linkDoorDirection(last, b),
+		func(x, y int) (Rect, bool) {
+			if b.contains(x, y) {
+				return b, true
+			}
+			return world.RoomAt(x, y)
+		})
+	if ok && !(ra == a && rb == b) && !(ra == b && rb == a) {
+		return nil
+	}
+	return path
+}
+
+// carveLink carves a path from linkPath into room b and returns its door. The door is where the corridor enters b so
+// a ledge on it drops into b
+func (world *World) carveLink(path []Point, b Rect) Rect {
+	for _, t := range path {
+		world.setFloor(t.X, t.Y, FloorKindCorridor)
+	}
+	last := path[len(path)-1]
+	door := Rect{X: last.X, Y: last.Y, W: 1, H: 1}
+	world.Doors[door] = linkDoorDirection(last, b)
+	world.addCorridor(path, 1, door)
+	return door
+}
+
+// linkDoorDirection returns the direction of a door at p, next to room b
+func linkDoorDirection(p Point, b Rect) DoorDirection {
+	if p.X < b.X || p.X >= b.X+b.W {
+		return DoorDirectionVertical
+	}
+	return DoorDirectionHorizontal
+}
+
+// addLink records the ledge and tags of link on its door
+func (world *World) addLink(door Rect, link RoomLink, rooms []Rect) {
+	if link.OneWay {
+		world.Ledges[door] = rooms[link.To]
+	}
+	world.TagDoor(door, link.Tags...)
+}
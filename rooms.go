@@ -0,0 +1,298 @@
+package generate
+
+// addRoom records a room in world.Rooms and remembers the order it was placed in for RoomsOrdered
+func (world *World) addRoom(room Rect) {
+	if _, ok := world.Rooms[room]; !ok {
+		world.roomOrder = append(world.roomOrder, room)
+	}
+	world.Rooms[room] = struct{}{}
+	if world.index != nil {
+		world.index.addRoom(room)
+	}
+}
+
+// RoomsOrdered returns every room in the order it was placed, so the index of a room can be used as an ID which stays
+// the same for every run with the same seed. Rooms added to world.Rooms by hand come last, sorted by position
+func (world *World) RoomsOrdered() []Rect {
+	rooms := make([]Rect, 0, len(world.Rooms))
+	seen := make(map[Rect]bool, len(world.Rooms))
+	for _, room := range world.roomOrder {
+		if _, ok := world.Rooms[room]; ok && !seen[room] {
+			seen[room] = true
+			rooms = append(rooms, room)
+		}
+	}
+	extra := make([]Rect, 0)
+	for room := range world.Rooms {
+		if !seen[room] {
+			extra = append(extra, room)
+		}
+	}
+	sortRects(extra)
+	return append(rooms, extra...)
+}
+
+// RoomID returns the index of room in RoomsOrdered, or -1 if it isn't a room
+func (world *World) RoomID(room Rect) int {
+	for i, r := range world.RoomsOrdered() {
+		if r == room {
+			return i
+		}
+	}
+	return -1
+}
+
+// Shrink returns the rect moved in by n on every side, W or H is 0 or less if it's too small
+func (r Rect) Shrink(n int) Rect {
+	return Rect{X: r.X + n, Y: r.Y + n, W: r.W - n*2, H: r.H - n*2}
+}
+
+// Expand returns the rect moved out by n on every side
+func (r Rect) Expand(n int) Rect {
+	return r.Shrink(-n)
+}
+
+// Grid returns a copy of the world's tiles inside the rect, indexed [y][x] from its top left corner, for furnishing
+// code and prefabs which work in room-local coordinates. Tiles outside of the map are TileVoid. Use SetLocal to change
+// them
+func (r Rect) Grid(world *World) [][]Tile {
+	grid := make([][]Tile, maxInt(r.H, 0))
+	for y := range grid {
+		grid[y] = make([]Tile, maxInt(r.W, 0))
+		wy := r.Y + y
+		if wy < 0 || wy >= world.Height {
+			continue
+		}
+		for x := range grid[y] {
+			if wx := r.X + x; wx >= 0 && wx < world.Width {
+				grid[y][x] = world.Tiles[wy][wx]
+			}
+		}
+	}
+	return grid
+}
+
+// GetLocal returns the tile at x,y from the rect's top left corner. ErrOutOfBounds is returned if x,y is outside of
+// the rect or the map
+func (r Rect) GetLocal(world *World, x, y int) (Tile, error) {
+	if x < 0 || y < 0 || x >= r.W || y >= r.H {
+		return TileVoid, ErrOutOfBounds
+	}
+	wx, wy := r.X+x, r.Y+y
+	if wx < 0 || wy < 0 || wx >= world.Width || wy >= world.Height {
+		return TileVoid, ErrOutOfBounds
+	}
+	return world.Tiles[wy][wx], nil
+}
+
+// SetLocal sets the tile at x,y from the rect's top left corner with SetTile. ErrOutOfBounds is returned if x,y is
+// outside of the rect or the map, or SetTile refuses it
+func (r Rect) SetLocal(world *World, x, y int, t Tile) error {
+	if x < 0 || y < 0 || x >= r.W || y >= r.H {
+		return ErrOutOfBounds
+	}
+	wx, wy := r.X+x, r.Y+y
+	if wx < 0 || wy < 0 || wx >= world.Width || wy >= world.Height {
+		return ErrOutOfBounds
+	}
+	return world.SetTile(wx, wy, t)
+}
+
+// ShrinkRoom moves every side of a room in by n tiles, walling off the space it gave up. Each entrance keeps a
+// passage through the new wall to the room, so doors and corridors stay attached. The room keeps its tags and its
+// place in RoomsOrdered, and corridors, ledges, sectors and exits are updated to the new rect. Budgets of the room
+// are dropped, call AllocateBudgets again. ErrNotEnoughSpace is returned if the room would be smaller than 1x1
+func (world *World) ShrinkRoom(room Rect, n int) (Rect, error) {
+	if _, ok := world.Rooms[room]; !ok {
+		return room, ErrNoRooms
+	}
+	shrunk := room.Shrink(n)
+	if n < 0 || shrunk.W < 1 || shrunk.H < 1 {
+		return room, ErrNotEnoughSpace
+	}
+	if n == 0 {
+		return room, nil
+	}
+
+	keep := world.entrancePassages(room, shrunk)
+	wall := world.roomWallTile(room)
+	for y := room.Y; y < room.Y+room.H; y++ {
+		for x := room.X; x < room.X+room.W; x++ {
+			switch {
+			case shrunk.contains(x, y):
+			case keep[Point{X: x, Y: y}]:
+				world.setFloor(x, y, FloorKindCorridor)
+			default:
+				world.SetTile(x, y, wall)
+				world.FloorKinds[y][x] = FloorKindNone
+			}
+		}
+	}
+	world.replaceRoom(room, shrunk)
+	return shrunk, nil
+}
+
+// ExpandRoom moves every side of a room out by n tiles into the walls around it, walling in the new edge. Corridors
+// which pass through the space become part of the room. ErrNotEnoughSpace is returned without changing anything if
+// the room would leave the map or touch another room, including through its new walls. Rooms, corridors and the
+// rest are updated the same way as ShrinkRoom
+func (world *World) ExpandRoom(room Rect, n int) (Rect, error) {
+	if _, ok := world.Rooms[room]; !ok {
+		return room, ErrNoRooms
+	}
+	if n < 0 {
+		return room, ErrNotEnoughSpace
+	}
+	if n == 0 {
+		return room, nil
+	}
+	grown := room.Expand(n)
+	wt := maxInt(world.paramsAt(room.X+room.W/2, room.Y+room.H/2).WallThickness, 1)
+	walled := grown.Expand(wt)
+	for y := walled.Y; y < walled.Y+walled.H; y++ {
+		for x := walled.X; x < walled.X+walled.W; x++ {
+			if room.contains(x, y) {
+				continue
+			}
+			if _, err := world.GetTile(x, y); err != nil && grown.contains(x, y) {
+				return room, ErrNotEnoughSpace
+			}
+			if other, ok := world.RoomAt(x, y); ok && other != room {
+				return room, ErrNotEnoughSpace
+			}
+		}
+	}
+
+	wall := world.roomWallTile(room)
+	for y := walled.Y; y < walled.Y+walled.H; y++ {
+		for x := walled.X; x < walled.X+walled.W; x++ {
+			switch {
+			case room.contains(x, y):
+			case grown.contains(x, y):
+				world.setFloor(x, y, FloorKindRoom)
+			default:
+				if t, err := world.GetTile(x, y); err == nil && t == TileVoid {
+					world.SetTile(x, y, wall)
+				}
+			}
+		}
+	}
+	world.replaceRoom(room, grown)
+	return grown, nil
+}
+
+// entrancePassages returns passages from each entrance of room to the nearest tile of inner, a rect inside of it,
+// going inwards first
+func (world *World) entrancePassages(room, inner Rect) map[Point]bool {
+	keep := make(map[Point]bool)
+	for _, e := range world.roomEntrances(room) {
+		target := Point{
+			X: minInt(maxInt(e.X, inner.X), inner.X+inner.W-1),
+			Y: minInt(maxInt(e.Y, inner.Y), inner.Y+inner.H-1),
+		}
+		inwardX := e.X == room.X || e.X == room.X+room.W-1
+		for p := e; !inner.contains(p.X, p.Y); {
+			keep[p] = true
+			if (inwardX && p.X != target.X) || (!inwardX && p.Y == target.Y) {
+				p.X += sign(target.X - p.X)
+			} else {
+				p.Y += sign(target.Y - p.Y)
+			}
+		}
+	}
+	return keep
+}
+
+// roomWallTile returns TileWall if the room has already been walled in by AddWalls, otherwise TilePreWall
+func (world *World) roomWallTile(room Rect) Tile {
+	ring := room.Expand(1)
+	for y := ring.Y; y < ring.Y+ring.H; y++ {
+		for x := ring.X; x < ring.X+ring.W; x++ {
+			if t, err := world.GetTile(x, y); err == nil && t == TileWall {
+				return TileWall
+			}
+		}
+	}
+	return TilePreWall
+}
+
+// replaceRoom swaps old for room everywhere the world refers to it
+func (world *World) replaceRoom(old, room Rect) {
+	delete(world.Rooms, old)
+	world.Rooms[room] = struct{}{}
+	for i, r := range world.roomOrder {
+		if r == old {
+			world.roomOrder[i] = room
+		}
+	}
+	if parts, ok := world.RoomParts[old]; ok {
+		delete(world.RoomParts, old)
+		world.RoomParts[room] = parts
+	}
+	if tags, ok := world.RoomTags[old]; ok {
+		delete(world.RoomTags, old)
+		world.RoomTags[room] = tags
+	}
+	if name, ok := world.RoomNames[old]; ok {
+		delete(world.RoomNames, old)
+		world.RoomNames[room] = name
+	}
+	if theme, ok := world.RoomThemes[old]; ok {
+		delete(world.RoomThemes, old)
+		world.RoomThemes[room] = theme
+	}
+	for door, to := range world.Ledges {
+		if to == old {
+			world.Ledges[door] = room
+		}
+	}
+	for _, c := range world.Corridors {
+		for i, r := range c.Rooms {
+			if r == old {
+				c.Rooms[i] = room
+			}
+		}
+	}
+	for _, s := range world.Sectors {
+		for i, r := range s.Rooms {
+			if r == old {
+				s.Rooms[i] = room
+			}
+		}
+	}
+	for i, e := range world.exits {
+		if e.InRoom && e.Room == old {
+			world.exits[i].Room = room
+		}
+	}
+	delete(world.budgets, old)
+}
+
+// RoomIDGrid returns a grid the size of the world holding RoomID+1 of the room each tile is in and 0 for tiles outside
+// every room, so gameplay can look up which room a tile is in without searching the rooms. Parts of compound rooms
+// hold the ID of their room. The grid is a snapshot, build it again after the rooms change
+func (world *World) RoomIDGrid() [][]int {
+	grid := make([][]int, world.Height)
+	for y := range grid {
+		grid[y] = make([]int, world.Width)
+	}
+	mark := func(r Rect, id int) {
+		for y := maxInt(r.Y, 0); y < minInt(r.Y+r.H, world.Height); y++ {
+			for x := maxInt(r.X, 0); x < minInt(r.X+r.W, world.Width); x++ {
+				if grid[y][x] == 0 {
+					grid[y][x] = id
+				}
+			}
+		}
+	}
+	rooms := world.RoomsOrdered()
+	for i, room := range rooms {
+		mark(room, i+1)
+	}
+	for i, room := range rooms {
+		for _, part := range world.RoomParts[room] {
+			mark(part, i+1)
+		}
+	}
+	return grid
+}
@@ -0,0 +1,24 @@
+package generate
+
+// TileRun is a horizontal run of identical tiles in a row
+type TileRun struct {
+	X, Length int
+	Tile      Tile
+}
+
+// Runs returns the row y as runs of identical tiles from left to right, so renderers can batch draw calls and
+// exporters can run length encode rows. nil is returned if y is out of bounds
+func (world *World) Runs(y int) []TileRun {
+	if y < 0 || y >= world.Height {
+		return nil
+	}
+	runs := make([]TileRun, 0)
+	for x, t := range world.Tiles[y] {
+		if n := len(runs); n > 0 && runs[n-1].Tile == t {
+			runs[n-1].Length++
+			continue
+		}
+		runs = append(runs, TileRun{X: x, Length: 1, Tile: t})
+	}
+	return runs
+}
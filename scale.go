@@ -0,0 +1,362 @@
+package generate
+
+import "errors"
+
+// ErrInvalidFactor is returned when a world is scaled by less than 1
+var ErrInvalidFactor = errors.New("Scale factor must be at least 1")
+
+// Downscale returns a copy of the world factor times smaller, for minimaps or to refine a quick low resolution
+// generation. Each tile is the most common tile of the factor x factor block it covers (ties go to the lowest tile),
+// and the same for elevation, except that blocks which corridors or doors pass through are floor so narrow corridors
+// aren't lost. Floor which is still cut off afterwards is joined back with corridors. Layers keep the most common
+// non-void tile so sparse decoration isn't lost. Rooms are shrunk to the blocks they fill, or the block at their middle
+// if they're smaller than a block. Doors, corridors and tags are scaled down with the tiles, Sectors and Gates aren't
+// copied and should be partitioned again
+func (world *World) Downscale(factor int) (*World, error) {
+	if factor < 1 {
+		return nil, ErrInvalidFactor
+	}
+	down := func(v int) int { return v / factor }
+	up := func(v int) int { return (v + factor - 1) / factor }
+	scaleRect := func(r Rect) Rect {
+		x, y := down(r.X), down(r.Y)
+		return Rect{X: x, Y: y, W: maxInt(up(r.X+r.W)-x, 1), H: maxInt(up(r.Y+r.H)-y, 1)}
+	}
+	// Rooms keep the blocks which are all room, so their edges don't land on blocks which are mostly wall
+	scaleRoom := func(r Rect) Rect {
+		x, y := up(r.X), up(r.Y)
+		w, h := down(r.X+r.W)-x, down(r.Y+r.H)-y
+		if w < 1 {
+			x, w = down(r.X+r.W/2), 1
+		}
+		if h < 1 {
+			y, h = down(r.Y+r.H/2), 1
+		}
+		return Rect{X: x, Y: y, W: w, H: h}
+	}
+	scaled := world.scaledCopy(up(world.Width), up(world.Height), func(v int) int { return maxInt(down(v), 1) }, scaleRect)
+
+	for y := 0; y < scaled.Height; y++ {
+		for x := 0; x < scaled.Width; x++ {
+			tiles := make(map[int]int)
+			kinds := make(map[int]int)
+			biomes := make(map[int]int)
+			for by := y * factor; by < minInt((y+1)*factor, world.Height); by++ {
+				for bx := x * factor; bx < minInt((x+1)*factor, world.Width); bx++ {
+					tiles[int(world.Tiles[by][bx])]++
+					kinds[int(world.FloorKinds[by][bx])]++
+					biomes[int(world.Biomes[by][bx])]++
+				}
+			}
+			scaled.Tiles[y][x] = Tile(majority(tiles, false))
+			scaled.FloorKinds[y][x] = FloorKind(majority(kinds, false))
+			scaled.Biomes[y][x] = Biome(majority(biomes, false))
+		}
+	}
+	if world.Elevation != nil {
+		scaled.SetElevationRect(Rect{W: scaled.Width, H: scaled.Height}, 0)
+		for y := range scaled.Elevation {
+			for x := range scaled.Elevation[y] {
+				levels := make(map[int]int)
+				for by := y * factor; by < minInt((y+1)*factor, world.Height); by++ {
+					for bx := x * factor; bx < minInt((x+1)*factor, world.Width); bx++ {
+						levels[world.Elevation[by][bx]]++
+					}
+				}
+				scaled.Elevation[y][x] = majority(levels, false)
+			}
+		}
+	}
+	for name, layer := range world.Layers {
+		l := scaled.Layer(name)
+		for y := range l {
+			for x := range l[y] {
+				tiles := make(map[int]int)
+				for by := y * factor; by < minInt((y+1)*factor, world.Height); by++ {
+					for bx := x * factor; bx < minInt((x+1)*factor, world.Width); bx++ {
+						tiles[int(layer[by][bx])]++
+					}
+				}
+				l[y][x] = Tile(majority(tiles, true))
+			}
+		}
+	}
+
+	// Corridors a tile wide lose the vote in every block they pass through, so they and the doors in them win instead,
+	// along with rooms too small to win a block of their own
+	keep := make([]Point, 0)
+	hasWalls := false
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if world.FloorKinds[y][x] == FloorKindCorridor && world.walkable(x, y) {
+				keep = append(keep, Point{X: down(x), Y: down(y)})
+			}
+			hasWalls = hasWalls || world.Tiles[y][x] == TileWall
+		}
+	}
+	for _, c := range world.Corridors {
+		for _, p := range c.Path {
+			keep = append(keep, Point{X: down(p.X), Y: down(p.Y)})
+		}
+	}
+	for door := range world.Doors {
+		keep = append(keep, Point{X: down(door.X), Y: down(door.Y)})
+	}
+	for room := range world.Rooms {
+		if r := scaleRoom(room); r.W == 1 || r.H == 1 {
+			for y := r.Y; y < r.Y+r.H; y++ {
+				for x := r.X; x < r.X+r.W; x++ {
+					keep = append(keep, Point{X: x, Y: y})
+				}
+			}
+		}
+	}
+	widen := func() {
+		for _, p := range keep {
+			if !scaled.walkable(p.X, p.Y) {
+				scaled.setFloor(p.X, p.Y, FloorKindCorridor)
+			}
+		}
+	}
+	if hasWalls {
+		scaled.repairWalls(Rect{W: scaled.Width, H: scaled.Height}, widen)
+	} else {
+		widen()
+	}
+
+	copyStructure(scaled, world, scaleRoom, scaleRect, func(p Point) Point { return Point{X: down(p.X), Y: down(p.Y)} })
+	for i, c := range scaled.Corridors {
+		scaled.Corridors[i].Width = maxInt(c.Width/factor, 1)
+		// Neighbouring points of the path fall in the same block
+		path := make([]Point, 0, len(c.Path)/factor+1)
+		for _, p := range c.Path {
+			if len(path) == 0 || path[len(path)-1] != p {
+				path = append(path, p)
+			}
+		}
+		scaled.Corridors[i].Path = path
+	}
+	scaled.joinFloor()
+	return scaled, nil
+}
+
+// Upscale returns a copy of the world factor times larger, each tile becoming a factor x factor block. With smooth,
+// the stepped edges between void, walls and floor are rounded off outside of the rooms, other tiles are left as they
+// are. Rooms, doors,
+// corridors and tags are scaled up with the tiles, Sectors and Gates aren't copied and should be partitioned again
+func (world *World) Upscale(factor int, smooth bool) (*World, error) {
+	if factor < 1 {
+		return nil, ErrInvalidFactor
+	}
+	scaleRect := func(r Rect) Rect {
+		return Rect{X: r.X * factor, Y: r.Y * factor, W: r.W * factor, H: r.H * factor}
+	}
+	scaled := world.scaledCopy(world.Width*factor, world.Height*factor, func(v int) int { return v * factor }, scaleRect)
+
+	for y := 0; y < scaled.Height; y++ {
+		for x := 0; x < scaled.Width; x++ {
+			sx, sy := x/factor, y/factor
+			scaled.Tiles[y][x] = world.Tiles[sy][sx]
+			scaled.FloorKinds[y][x] = world.FloorKinds[sy][sx]
+			scaled.Biomes[y][x] = world.Biomes[sy][sx]
+		}
+	}
+	if world.Elevation != nil {
+		scaled.SetElevationRect(Rect{W: scaled.Width, H: scaled.Height}, 0)
+		for y := range scaled.Elevation {
+			for x := range scaled.Elevation[y] {
+				scaled.Elevation[y][x] = world.Elevation[y/factor][x/factor]
+			}
+		}
+	}
+	for name, layer := range world.Layers {
+		l := scaled.Layer(name)
+		for y := range l {
+			for x := range l[y] {
+				l[y][x] = layer[y/factor][x/factor]
+			}
+		}
+	}
+	if smooth && factor > 1 {
+		scaled.smoothEdges(func(x, y int) bool {
+			_, in := world.RoomAt(x/factor, y/factor)
+			return in
+		})
+	}
+
+	copyStructure(scaled, world, scaleRect, scaleRect, func(p Point) Point {
+		return Point{X: p.X*factor + factor/2, Y: p.Y*factor + factor/2}
+	})
+	for i, c := range scaled.Corridors {
+		scaled.Corridors[i].Width = c.Width * factor
+		// Fill in the tiles between the scaled points so the path stays continuous
+		path := make([]Point, 0, len(c.Path)*factor)
+		for j, p := range c.Path {
+			if j > 0 {
+				prev := path[len(path)-1]
+				for prev.X != p.X || prev.Y != p.Y {
+					prev.X += sign(p.X - prev.X)
+					prev.Y += sign(p.Y - prev.Y)
+					path = append(path, prev)
+				}
+				continue
+			}
+			path = append(path, p)
+		}
+		scaled.Corridors[i].Path = path
+	}
+	return scaled, nil
+}
+
+// scaledCopy returns an empty world of the given size with the world's config, sizes scaled by scale and zones by
+// scaleRect
+func (world *World) scaledCopy(width, height int, scale func(int) int, scaleRect func(Rect) Rect) *World {
+	cfg := world.config()
+	cfg.Width, cfg.Height = width, height
+	if world.Border > 0 {
+		cfg.Border = scale(world.Border)
+	}
+	cfg.WallThickness = scale(world.WallThickness)
+	cfg.MinCorridorSize = scale(world.MinCorridorSize)
+	cfg.MaxCorridorSize = scale(world.MaxCorridorSize)
+	cfg.MaxRoomWidth = scale(world.MaxRoomWidth)
+	cfg.MaxRoomHeight = scale(world.MaxRoomHeight)
+	cfg.MinRoomWidth = scale(world.MinRoomWidth)
+	cfg.MinRoomHeight = scale(world.MinRoomHeight)
+	cfg.MinRoomSeparation = scale(world.MinRoomSeparation)
+	for i, z := range cfg.Zones {
+		cfg.Zones[i].Area = scaleRect(z.Area)
+	}
+	scaled := cfg.build()
+	scaled.reseed(world.rng.Int63())
+	scaled.ShowErrorMessages = world.ShowErrorMessages
+	scaled.Logf = world.Logf
+	scaled.DurationBeforeRetry = world.DurationBeforeRetry
+	scaled.DurationBeforeError = world.DurationBeforeError
+	scaled.RouteCost = world.RouteCost
+	scaled.WeatherCheck = world.WeatherCheck
+	return scaled
+}
+
+// copyStructure copies the rooms and their parts, doors, corridors, their tags, names and themes from world to scaled,
+// rooms scaled with scaleRoom, doors with scaleDoor and points with scalePoint
+func copyStructure(scaled, world *World, scaleRoom, scaleDoor func(Rect) Rect, scalePoint func(Point) Point) {
+	for _, room := range world.RoomsOrdered() {
+		scaled.addRoom(scaleRoom(room))
+	}
+	for room, parts := range world.RoomParts {
+		for _, part := range parts {
+			scaled.RoomParts[scaleRoom(room)] = append(scaled.RoomParts[scaleRoom(room)], scaleRoom(part))
+		}
+	}
+	for room, tags := range world.RoomTags {
+		scaled.RoomTags[scaleRoom(room)] = append([]Tag(nil), tags...)
+	}
+	for door, dir := range world.Doors {
+		scaled.Doors[scaleDoor(door)] = dir
+	}
+	for room, name := range world.RoomNames {
+		scaled.RoomNames[scaleRoom(room)] = name
+	}
+	for room, theme := range world.RoomThemes {
+		scaled.RoomThemes[scaleRoom(room)] = theme
+	}
+	scaled.themeRules = world.themeRules
+	scaled.LevelName = world.LevelName
+	for door, tags := range world.DoorTags {
+		scaled.DoorTags[scaleDoor(door)] = append([]Tag(nil), tags...)
+	}
+	for door, room := range world.Ledges {
+		scaled.Ledges[scaleDoor(door)] = scaleRoom(room)
+	}
+	for p, dir := range world.Facing {
+		scaled.Facing[scalePoint(p)] = dir
+	}
+	for _, c := range world.Corridors {
+		path := make([]Point, len(c.Path))
+		for i, p := range c.Path {
+			path[i] = scalePoint(p)
+		}
+		rooms := make([]Rect, len(c.Rooms))
+		for i, r := range c.Rooms {
+			rooms[i] = scaleRoom(r)
+		}
+		scaled.Corridors = append(scaled.Corridors, Corridor{
+			Path:  path,
+			Width: c.Width,
+			From:  scalePoint(c.From),
+			To:    scalePoint(c.To),
+			Rooms: rooms,
+			Door:  scaleDoor(c.Door),
+		})
+	}
+}
+
+// smoothEdges rounds off the corners between void, walls and floor by giving each of those tiles the most common of
+// those tiles around it. Tiles where skip returns true are left as they are
+func (world *World) smoothEdges(skip func(x, y int) bool) {
+	structural := func(t Tile) bool {
+		return t == TileVoid || t == TileWall || t == TilePreWall || t == TileFloor
+	}
+	tiles := make([][]Tile, world.Height)
+	for y := range tiles {
+		tiles[y] = append([]Tile(nil), world.Tiles[y]...)
+	}
+	for y := 1; y < world.Height-1; y++ {
+		for x := 1; x < world.Width-1; x++ {
+			if !structural(tiles[y][x]) || skip(x, y) {
+				continue
+			}
+			counts := make(map[int]int)
+			var from Point
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if t := tiles[y+dy][x+dx]; structural(t) {
+						counts[int(t)]++
+					}
+				}
+			}
+			t := Tile(majority(counts, false))
+			if t == tiles[y][x] || counts[int(t)] < 5 {
+				continue
+			}
+			// Take the floor kind and biome from a neighbour which has the new tile
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if tiles[y+dy][x+dx] == t {
+						from = Point{X: x + dx, Y: y + dy}
+					}
+				}
+			}
+			world.Tiles[y][x] = t
+			world.FloorKinds[y][x] = world.FloorKinds[from.Y][from.X]
+			world.Biomes[y][x] = world.Biomes[from.Y][from.X]
+		}
+	}
+}
+
+// majority returns the most common key in counts, ties go to the lowest. With skipZero, the zero value is only
+// returned if there's nothing else
+func majority(counts map[int]int, skipZero bool) int {
+	best, bestCount := 0, 0
+	for v, c := range counts {
+		if skipZero && v == 0 {
+			continue
+		}
+		if c > bestCount || (c == bestCount && v < best) {
+			best, bestCount = v, c
+		}
+	}
+	return best
+}
+
+// sign returns -1, 0 or 1 depending on the sign of v
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package generate
+
+// Sector is a contiguous group of rooms with its own theme, entered through locked gates
+type Sector struct {
+	Rooms  []Rect
+	Biome  Biome // theme of the sector, applied to world.Biomes
+	Parent int   // the sector this one grew from, -1 for the first sector
+	Key    Point // position of the key which unlocks the gates into this sector, in a sector before this one
+}
+
+// Gate is a locked door between two sectors, opened with the key of sector To
+type Gate struct {
+	Door     Rect
+	From, To int // From < To
+}
+
+// sectorThemes are the biomes cycled through by PartitionSectors
+var sectorThemes = []Biome{BiomeDungeon, BiomeCrypt, BiomeCave}
+
+// PartitionSectors splits the rooms into count contiguous sectors, growing outwards from start, and locks every door
+// between two sectors. The key for each sector is placed in a room of an earlier sector (tagged with TagKey), so the
+// sectors must be cleared in order. The sectors are stored in world.Sectors and the locked doors in world.Gates and
+// tagged with TagLocked. ErrNotEnoughSpace is returned without changing the world if there are fewer than count rooms
+// or the rooms connected to start run out before count sectors have been grown
+func (world *World) PartitionSectors(start Rect, count int) error {
+	if _, ok := world.Rooms[start]; !ok {
+		return ErrNoRooms
+	}
+	if count <= 0 || count > len(world.Rooms) {
+		return ErrNotEnoughSpace
+	}
+
+	graph := world.BuildGraph()
+	adj := make(map[Rect][]Rect)
+	for _, e := range graph.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+		adj[e.To] = append(adj[e.To], e.From)
+	}
+
+	sectorOf := map[Rect]int{}
+	sectors := make([]Sector, 0, count)
+	target := (len(world.Rooms) + count - 1) / count
+	seed, parent := start, -1
+	for s := 0; s < count; s++ {
+		// Grow the sector breadth first through unassigned rooms
+		sector := Sector{Parent: parent, Biome: sectorThemes[s%len(sectorThemes)]}
+		queue := []Rect{seed}
+		sectorOf[seed] = s
+		for len(queue) > 0 && len(sector.Rooms) < target {
+			r := queue[0]
+			queue = queue[1:]
+			sector.Rooms = append(sector.Rooms, r)
+			for _, n := range adj[r] {
+				if _, ok := sectorOf[n]; !ok && len(sector.Rooms)+len(queue) < target {
+					sectorOf[n] = s
+					queue = append(queue, n)
+				}
+			}
+		}
+		for _, r := range queue {
+			sector.Rooms = append(sector.Rooms, r)
+		}
+		sectors = append(sectors, sector)
+
+		// The next sector grows from an unassigned room touching the newest sector possible
+		found := false
+		for p := s; p >= 0 && !found; p-- {
+			for _, r := range sectors[p].Rooms {
+				for _, n := range adj[r] {
+					if _, ok := sectorOf[n]; !ok {
+						seed, parent, found = n, p, true
+						break
+					}
+				}
+				if found {
+					break
+				}
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if len(sectors) < count {
+		return ErrNotEnoughSpace
+	}
+
+	// Rooms which weren't reached join the smallest sector next to them
+	for changed := true; changed; {
+		changed = false
+		for _, room := range world.RoomsOrdered() {
+			if _, ok := sectorOf[room]; ok {
+				continue
+			}
+			best := -1
+			for _, n := range adj[room] {
+				if s, ok := sectorOf[n]; ok && (best == -1 || len(sectors[s].Rooms) < len(sectors[best].Rooms)) {
+					best = s
+				}
+			}
+			if best != -1 {
+				sectorOf[room] = best
+				sectors[best].Rooms = append(sectors[best].Rooms, room)
+				changed = true
+			}
+		}
+	}
+
+	world.Sectors = sectors
+	world.Gates = make([]Gate, 0)
+	for s := range sectors {
+		for _, room := range sectors[s].Rooms {
+			world.SetBiomeRect(room, sectors[s].Biome)
+		}
+	}
+	for _, e := range graph.Edges {
+		a, okA := sectorOf[e.From]
+		b, okB := sectorOf[e.To]
+		if !okA || !okB || a == b {
+			continue
+		}
+		world.Gates = append(world.Gates, Gate{Door: e.Door, From: minInt(a, b), To: maxInt(a, b)})
+		world.TagDoor(e.Door, TagLocked)
+	}
+
+	// Keys go in the parent sector, so each sector is opened from the one before it
+	for s := 1; s < len(sectors); s++ {
+		rooms := sectors[sectors[s].Parent].Rooms
+		room := rooms[world.rng.Int()%len(rooms)]
+		sectors[s].Key = Point{X: world.randInt(room.X, room.X+room.W-1), Y: world.randInt(room.Y, room.Y+room.H-1)}
+		world.TagRoom(room, TagKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsatisfiable is returned when a set piece has nowhere it can be placed
+var ErrUnsatisfiable = errors.New("Set piece can't be placed")
+
+// SetPieceRule returns every point a set piece could be placed at
+type SetPieceRule func(world *World) []Point
+
+// SetPiece is something which has to be placed once the world's layout has been generated
+type SetPiece struct {
+	Name string
+	Rule SetPieceRule
+}
+
+// Placement is where a set piece was placed
+type Placement struct {
+	Name   string
+	Point  Point
+	Room   Rect
+	InRoom bool
+}
+
+// AddSetPiece registers a set piece to be placed by PlaceSetPieces. Set pieces are kept between generations
+func (world *World) AddSetPiece(name string, rule SetPieceRule) {
+	world.setPieces = append(world.setPieces, SetPiece{Name: name, Rule: rule})
+}
+
+// ClearSetPieces removes every registered set piece
+func (world *World) ClearSetPieces() {
+	world.setPieces = nil
+}
+
+// PlaceSetPieces picks a position for every registered set piece, in the order they were added. Each set piece gets
+// its own room if it's in one. ErrUnsatisfiable is returned, along with the set pieces which were placed, if a set
+// piece can't be placed
+func (world *World) PlaceSetPieces() ([]Placement, error) {
+	placements := make([]Placement, 0, len(world.setPieces))
+	usedRooms := make(map[Rect]struct{})
+	for _, piece := range world.setPieces {
+		candidates := make([]Point, 0)
+		for _, p := range piece.Rule(world) {
+			if room, ok := world.RoomAt(p.X, p.Y); ok {
+				if _, used := usedRooms[room]; used {
+					continue
+				}
+			}
+			candidates = append(candidates, p)
+		}
+		if len(candidates) == 0 {
+			return placements, fmt.Errorf("%w: %s", ErrUnsatisfiable, piece.Name)
+		}
+
+		p := candidates[world.rng.Intn(len(candidates))]
+		placement := Placement{Name: piece.Name, Point: p}
+		placement.Room, placement.InRoom = world.RoomAt(p.X, p.Y)
+		if placement.InRoom {
+			usedRooms[placement.Room] = struct{}{}
+		}
+		placements = append(placements, placement)
+	}
+	return placements, nil
+}
+
+// CriticalPath returns the shortest path from the center of the room tagged TagStart to the center of the room tagged
+// TagBoss. Without a start room the path starts at the first walkable tile, and without a boss room it ends at the
+// walkable tile furthest from the start
+func (world *World) CriticalPath() []Point {
+	var start, end Point
+	var hasStart, hasEnd bool
+	for _, room := range world.sortedRooms() {
+		center := Point{X: room.X + room.W/2, Y: room.Y + room.H/2}
+		if !hasStart && world.RoomHasTag(room, TagStart) {
+			start, hasStart = center, true
+		}
+		if !hasEnd && world.RoomHasTag(room, TagBoss) {
+			end, hasEnd = center, true
+		}
+	}
+	if !hasStart {
+	find:
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if world.walkable(x, y) {
+					start, hasStart = Point{X: x, Y: y}, true
+					break find
+				}
+			}
+		}
+		if !hasStart {
+			return nil
+		}
+	}
+	if !hasEnd {
+		end, _ = furthest(world.DistanceField(start))
+	}
+	return world.ShortestPath(start, end)
+}
+
+// AlongCriticalPath places a set piece on the critical path, between min and max (0-1) of the way along it
+func AlongCriticalPath(min, max float64) SetPieceRule {
+	return func(world *World) []Point {
+		path := world.CriticalPath()
+		if len(path) == 0 {
+			return nil
+		}
+		from := int(clampFloat(min, 0, 1) * float64(len(path)-1))
+		to := int(clampFloat(max, 0, 1) * float64(len(path)-1))
+		if from > to {
+			return nil
+		}
+		return append([]Point(nil), path[from:to+1]...)
+	}
+}
+
+// InDeadEnd places a set piece in a room with only one connection
+func InDeadEnd() SetPieceRule {
+	return func(world *World) []Point {
+		degree := world.BuildGraph().degrees()
+		points := make([]Point, 0)
+		for _, room := range world.sortedRooms() {
+			if degree[room] == 1 {
+				points = append(points, world.roomFloor(room)...)
+			}
+		}
+		return points
+	}
+}
+
+// InTaggedRoom places a set piece in a room with tag
+func InTaggedRoom(tag Tag) SetPieceRule {
+	return func(world *World) []Point {
+		points := make([]Point, 0)
+		for _, room := range world.sortedRooms() {
+			if world.RoomHasTag(room, tag) {
+				points = append(points, world.roomFloor(room)...)
+			}
+		}
+		return points
+	}
+}
+
+// InCorridor places a set piece on a corridor tile
+func InCorridor() SetPieceRule {
+	return func(world *World) []Point {
+		points := make([]Point, 0)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if world.Tiles[y][x] == TileFloor && world.FloorKinds[y][x] == FloorKindCorridor {
+					points = append(points, Point{X: x, Y: y})
+				}
+			}
+		}
+		return points
+	}
+}
+
+// All places a set piece at a point allowed by every one of rules
+func All(rules ...SetPieceRule) SetPieceRule {
+	return func(world *World) []Point {
+		if len(rules) == 0 {
+			return nil
+		}
+		points := rules[0](world)
+		for _, rule := range rules[1:] {
+			allowed := make(map[Point]struct{})
+			for _, p := range rule(world) {
+				allowed[p] = struct{}{}
+			}
+			kept := points[:0]
+			for _, p := range points {
+				if _, ok := allowed[p]; ok {
+					kept = append(kept, p)
+				}
+			}
+			points = kept
+		}
+		return points
+	}
+}
+
+// roomFloor returns the TileFloor tiles in room
+func (world *World) roomFloor(room Rect) []Point {
+	points := make([]Point, 0, room.W*room.H)
+	for y := room.Y; y < room.Y+room.H; y++ {
+		for x := room.X; x < room.X+room.W; x++ {
+			if world.Tiles[y][x] == TileFloor {
+				points = append(points, Point{X: x, Y: y})
+			}
+		}
+	}
+	return points
+}
@@ -0,0 +1,81 @@
+package generate
+
+import "math/rand"
+
+// ShopDressing returns a pass which furnishes every room tagged with TagShop on the named layer. The room is laid out
+// relative to its first entrance: a TileNPC shopkeeper stands against the far wall facing the entrance, behind a
+// TileCounter which spans the room, and TileShelf lines the side walls without blocking any entrances.
+// The shopkeeper's facing is recorded in world.Facing
+func ShopDressing(name string) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		layer := world.Layer(name)
+		for _, room := range world.RoomsOrdered() {
+			if !world.RoomHasTag(room, TagShop) {
+				continue
+			}
+			entrances := world.roomEntrances(room)
+			if len(entrances) == 0 {
+				continue
+			}
+
+			// u runs along the far wall, v runs from the far wall towards the entrance
+			door := entrances[0]
+			facing := DirectionSouth
+			u, v := room.W, room.H
+			toWorld := func(lu, lv int) (int, int) {
+				return room.X + lu, room.Y + lv
+			}
+			switch {
+			case door.Y == room.Y:
+				facing = DirectionNorth
+				toWorld = func(lu, lv int) (int, int) {
+					return room.X + lu, room.Y + room.H - 1 - lv
+				}
+			case door.X == room.X && door.Y != room.Y+room.H-1:
+				facing = DirectionWest
+				u, v = room.H, room.W
+				toWorld = func(lu, lv int) (int, int) {
+					return room.X + room.W - 1 - lv, room.Y + lu
+				}
+			case door.X == room.X+room.W-1 && door.Y != room.Y+room.H-1:
+				facing = DirectionEast
+				u, v = room.H, room.W
+				toWorld = func(lu, lv int) (int, int) {
+					return room.X + lv, room.Y + lu
+				}
+			}
+			if u < 4 || v < 4 {
+				continue
+			}
+
+			nearEntrance := func(x, y int) bool {
+				for _, e := range entrances {
+					if absInt(e.X-x) <= 1 && absInt(e.Y-y) <= 1 {
+						return true
+					}
+				}
+				return false
+			}
+			place := func(lu, lv int, t Tile) {
+				x, y := toWorld(lu, lv)
+				if world.Tiles[y][x] == TileFloor && !nearEntrance(x, y) {
+					layer[y][x] = t
+				}
+			}
+
+			x, y := toWorld(u/2, 0)
+			layer[y][x] = TileNPC
+			world.Facing[Point{X: x, Y: y}] = facing
+			for lu := 1; lu < u-1; lu++ {
+				place(lu, 1, TileCounter)
+			}
+			for lv := 3; lv < v-1; lv++ {
+				for _, lu := range [2]int{0, u - 1} {
+					if rng.Float64() < 0.8 {
+						place(lu, lv, TileShelf)
+					}
+				}
+			}
+		}
+	}
+}
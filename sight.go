@@ -0,0 +1,60 @@
+package generate
+
+// lineOfSight returns true if nothing opaque lies on the line between a and b. The end points themselves aren't
+// checked so walls can be seen
+func (world *World) lineOfSight(a, b Point) bool {
+	// Bresenham
+	dx, dy := absInt(b.X-a.X), -absInt(b.Y-a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	err := dx + dy
+	x, y := a.X, a.Y
+	for {
+		if x == b.X && y == b.Y {
+			return true
+		}
+		if (x != a.X || y != a.Y) && (y < 0 || y >= world.Height || x < 0 || x >= world.Width || world.Tiles[y][x].Opaque()) {
+			return false
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// Exposure returns how many other walkable tiles within radius can see each walkable tile, for tactical AI looking
+// for cover and for hiding treasure or snipers in corners which are hard to see. Tiles which can't be walked on are 0
+func (world *World) Exposure(radius int) FloatLayer {
+	layer := NewFloatLayer(world.Width, world.Height)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if !world.walkable(x, y) {
+				continue
+			}
+			tile := Point{X: x, Y: y}
+			for oy := maxInt(y-radius, 0); oy <= minInt(y+radius, world.Height-1); oy++ {
+				for ox := maxInt(x-radius, 0); ox <= minInt(x+radius, world.Width-1); ox++ {
+					dx, dy := ox-x, oy-y
+					if dx == 0 && dy == 0 || dx*dx+dy*dy > radius*radius || !world.walkable(ox, oy) {
+						continue
+					}
+					if world.lineOfSight(Point{X: ox, Y: oy}, tile) {
+						layer[y][x]++
+					}
+				}
+			}
+		}
+	}
+	return layer
+}
@@ -0,0 +1,173 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrTooManyItems is returned when Rules has more distinct items than the solver can track
+var ErrTooManyItems = errors.New("Too many different items to solve")
+
+// Rules describes what Solve has to work around. Items are never used up, so an item opens every lock which needs it
+type Rules struct {
+	Locks map[Rect]string  // doors which can only be passed while holding the item
+	Items map[Point]string // items which are picked up by walking over them
+}
+
+// Solution is a way through the world found by Solve
+type Solution struct {
+	Path  []Point  // every tile walked from start to goal (inclusive)
+	Items []string // items in the order they were picked up
+}
+
+// Rules returns the rules for the world's gates: each gate is locked by the key of the sector it leads to, and that
+// key is found at the sector's Key
+func (world *World) Rules() Rules {
+	rules := Rules{
+		Locks: make(map[Rect]string),
+		Items: make(map[Point]string),
+	}
+	for _, gate := range world.Gates {
+		rules.Locks[gate.Door] = fmt.Sprintf("key %d", gate.To)
+	}
+	for i, sector := range world.Sectors {
+		if sector.Parent >= 0 {
+			rules.Items[sector.Key] = fmt.Sprintf("key %d", i)
+		}
+	}
+	return rules
+}
+
+// Solve proves that goal can be reached from start by walking, picking up items to get through locked doors, only
+// dropping down ledges in the direction they go and stepping through portals, returning the shortest path which does
+// it. ErrNoPath is returned if the dungeon can't be completed. Use world.Rules() for the locks and keys placed by
+// PartitionSectors
+func (world *World) Solve(start, goal Point, rules Rules) (Solution, error) {
+	// Give every item a bit
+	names := make([]string, 0)
+	bits := make(map[string]uint64)
+	for _, name := range rules.Items {
+		if _, ok := bits[name]; !ok {
+			names = append(names, name)
+			bits[name] = 0
+		}
+	}
+	if len(names) > 63 { // the last bit is for locks which nothing opens
+		return Solution{}, ErrTooManyItems
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		bits[name] = 1 << uint(i)
+	}
+
+	locks := make(map[Point]uint64)
+	for door, name := range rules.Locks {
+		bit, ok := bits[name]
+		if !ok {
+			bit = 1 << 63
+		}
+		for y := door.Y; y < door.Y+door.H; y++ {
+			for x := door.X; x < door.X+door.W; x++ {
+				locks[Point{X: x, Y: y}] |= bit
+			}
+		}
+	}
+	items := make(map[Point]uint64)
+	for p, name := range rules.Items {
+		items[p] = bits[name]
+	}
+	ledges := world.ledgeSteps()
+	portals := world.portalLinks()
+
+	type state struct {
+		p     Point
+		items uint64
+	}
+	first := state{p: start, items: items[start]}
+	if !world.walkable(start.X, start.Y) || locks[start]&^first.items != 0 {
+		return Solution{}, ErrNoPath
+	}
+	parents := map[state]state{first: first}
+	queue := []state{first}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur.p == goal {
+			solution := Solution{}
+			for s := cur; ; s = parents[s] {
+				solution.Path = append(solution.Path, s.p)
+				if s == first {
+					break
+				}
+			}
+			for i, j := 0, len(solution.Path)-1; i < j; i, j = i+1, j-1 {
+				solution.Path[i], solution.Path[j] = solution.Path[j], solution.Path[i]
+			}
+			held := uint64(0)
+			for _, p := range solution.Path {
+				if bit := items[p]; bit != 0 && held&bit == 0 {
+					held |= bit
+					solution.Items = append(solution.Items, rules.Items[p])
+				}
+			}
+			return solution, nil
+		}
+
+		for i := 0; i < len(polarOffsets)+1; i++ {
+			next := state{items: cur.items}
+			if i < len(polarOffsets) {
+				o := polarOffsets[i]
+				next.p = Point{X: cur.p.X + o.X, Y: cur.p.Y + o.Y}
+				if !ledgeAllows(ledges, cur.p, o) || !ledgeAllows(ledges, next.p, o) {
+					continue
+				}
+			} else if to, ok := portals[cur.p]; ok {
+				next.p = to
+			} else {
+				continue
+			}
+			if !world.walkable(next.p.X, next.p.Y) || locks[next.p]&^cur.items != 0 {
+				continue
+			}
+			next.items |= items[next.p]
+			if _, ok := parents[next]; ok {
+				continue
+			}
+			parents[next] = cur
+			queue = append(queue, next)
+		}
+	}
+	return Solution{}, ErrNoPath
+}
+
+// ledgeSteps returns the direction which each tile of a ledge has to be crossed in
+func (world *World) ledgeSteps() map[Point]Point {
+	steps := make(map[Point]Point)
+	for door, to := range world.Ledges {
+		var step Point
+		if world.Doors[door] == DoorDirectionVertical {
+			step.X = sign(to.X + to.W/2 - door.X)
+		} else {
+			step.Y = sign(to.Y + to.H/2 - door.Y)
+		}
+		for y := door.Y; y < door.Y+door.H; y++ {
+			for x := door.X; x < door.X+door.W; x++ {
+				steps[Point{X: x, Y: y}] = step
+			}
+		}
+	}
+	return steps
+}
+
+// ledgeAllows returns false if step goes the wrong way across the ledge at p. Steps along a ledge are allowed
+func ledgeAllows(ledges map[Point]Point, p Point, step Point) bool {
+	want, ok := ledges[p]
+	if !ok {
+		return true
+	}
+	if (want.X != 0 && step.X != 0) || (want.Y != 0 && step.Y != 0) {
+		return step == want
+	}
+	return true
+}
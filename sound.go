@@ -0,0 +1,30 @@
+package generate
+
+// soundWallCost is how many tiles of open floor a tile of wall or rock dampens sound as much as
+const soundWallCost = 6
+
+// SoundField returns how well a sound made at source can be heard from each tile, from 1 at the source down to 0.
+// Sound loses falloff per tile it travels across, so it's heard 1/falloff tiles away in the open. Walls, rock and
+// anything else which blocks sight dampen it soundWallCost times as much, so sounds carry around corners and through
+// doors further than through walls
+func (world *World) SoundField(source Point, falloff float64) FloatLayer {
+	layer := NewFloatLayer(world.Width, world.Height)
+	if falloff <= 0 {
+		return layer
+	}
+
+	dist := world.dijkstra(source, 1/falloff, func(x, y int) float64 {
+		if world.Tiles[y][x].Opaque() {
+			return soundWallCost
+		}
+		return 1
+	})
+	for y, row := range dist {
+		for x, d := range row {
+			if d >= 0 {
+				layer[y][x] = clampFloat(1-d*falloff, 0, 1)
+			}
+		}
+	}
+	return layer
+}
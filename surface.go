@@ -0,0 +1,160 @@
+package generate
+
+// GenerateSurfaceEntrance generates a cave with GenerateRandomWalk and then covers the strip of the map along edge with
+// a surface of grass and trees, depth tiles deep (give or take some noise). A tunnel connects the cave to the surface
+// and its mouth is recorded in world.Doors, tagged with TagEntrance. Parts of the cave cut off by the surface are dug
+// back to the rest of it, so all of its floor can be reached through the tunnel.
+// Call AddWalls afterwards, the surface isn't walled in
+func (world *World) GenerateSurfaceEntrance(edge Direction, depth int, tileCount int, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("SurfaceEntrance", 0)
+	defer func() { world.endReport(err) }()
+
+	span, length := world.Height, world.Width
+	if edge == DirectionEast || edge == DirectionWest {
+		span, length = world.Width, world.Height
+	}
+	if depth <= 0 || depth+depth/2+wallGap(world.WallThickness) >= span/2 {
+		return ErrNotEnoughSpace
+	}
+
+	if err := world.GenerateRandomWalk(tileCount); err != nil {
+		return err
+	}
+
+	// Convert between map coordinates and distance from/along the edge
+	toLocal := func(x, y int) (a, d int) {
+		switch edge {
+		case DirectionNorth:
+			return x, y
+		case DirectionSouth:
+			return x, world.Height - 1 - y
+		case DirectionWest:
+			return y, x
+		}
+		return y, world.Width - 1 - x
+	}
+	toWorld := func(a, d int) (x, y int) {
+		switch edge {
+		case DirectionNorth:
+			return a, d
+		case DirectionSouth:
+			return a, world.Height - 1 - d
+		case DirectionWest:
+			return d, a
+		}
+		return world.Width - 1 - d, a
+	}
+
+	n := newNoise(world.rng)
+	boundary := make([]int, length)
+	for a := range boundary {
+		boundary[a] = depth + int((n.Fractal(float64(a)/8, 0, 3)-0.5)*float64(depth))
+	}
+
+	// Surface, with a void gap between it and the cave which AddWalls turns into a cliff
+	trees := newNoise(world.rng)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			a, d := toLocal(x, y)
+			switch {
+			case d < boundary[a]:
+				if trees.Fractal(float64(x)/6, float64(y)/6, 2) > 0.6 {
+					world.SetTile(x, y, TileTree)
+					world.Biomes[y][x] = BiomeForest
+				} else {
+					world.SetTile(x, y, TileGrass)
+					world.Biomes[y][x] = BiomeGrassland
+				}
+			case d < boundary[a]+wallGap(world.WallThickness):
+				world.SetTile(x, y, TileVoid)
+			default:
+				world.Biomes[y][x] = BiomeCave
+			}
+		}
+	}
+
+	// Tunnel from the cave floor closest to the surface
+	bestA, bestD, bestGap := -1, 0, span
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if a, d := toLocal(x, y); world.Tiles[y][x] == TileFloor && d-boundary[a] < bestGap {
+				bestA, bestD, bestGap = a, d, d-boundary[a]
+			}
+		}
+	}
+	if bestA == -1 {
+		return ErrNotEnoughSpace
+	}
+
+	var door Rect
+	tunnel := make([]Point, 0)
+	for d := bestD - 1; d >= boundary[bestA]; d-- {
+		x, y := toWorld(bestA, d)
+		world.setFloor(x, y, FloorKindCorridor)
+		world.Biomes[y][x] = BiomeCave
+		door = Rect{X: x, Y: y, W: 1, H: 1}
+		tunnel = append(tunnel, Point{X: x, Y: y})
+	}
+	// Trees can't block the way out
+	if x, y := toWorld(bestA, boundary[bestA]-1); world.Tiles[y][x] == TileTree {
+		world.SetTile(x, y, TileGrass)
+		world.Biomes[y][x] = BiomeGrassland
+	}
+
+	// The surface can cut the cave into pieces. Dig from each piece the tunnel can't reach back towards it through the
+	// rock below the surface, until every cave floor can be walked to from the tunnel
+	gap, b := wallGap(world.WallThickness), world.Border
+	rock := func(x, y int) bool {
+		if x < b || y < b || x >= world.Width-b || y >= world.Height-b {
+			return false
+		}
+		a, d := toLocal(x, y)
+		return d >= boundary[a]+gap
+	}
+	startX, startY := toWorld(bestA, bestD)
+	start := Point{X: startX, Y: startY}
+	dig := world.bfs(start, func(x, y int) bool { return world.walkable(x, y) || rock(x, y) })
+	for {
+		reached := world.bfs(start, world.walkable)
+		cut, found := Point{}, false
+		for y := 0; y < world.Height && !found; y++ {
+			for x := 0; x < world.Width; x++ {
+				if world.Tiles[y][x] == TileFloor && reached[y][x] == -1 && dig[y][x] != -1 {
+					cut, found = Point{X: x, Y: y}, true
+					break
+				}
+			}
+		}
+		if !found {
+			break
+		}
+		// Walk down the dig distances from the cut off floor, which leads back to the tunnel by the shortest way
+		for p := cut; reached[p.Y][p.X] == -1; {
+			if !world.walkable(p.X, p.Y) {
+				world.setFloor(p.X, p.Y, FloorKindCave)
+			}
+			for _, o := range polarOffsets {
+				x, y := p.X+o.X, p.Y+o.Y
+				if x >= 0 && y >= 0 && x < world.Width && y < world.Height && dig[y][x] == dig[p.Y][p.X]-1 {
+					p = Point{X: x, Y: y}
+					break
+				}
+			}
+		}
+	}
+
+	// The surface changed the cave, so its rooms are found again before the tunnel is recorded
+	world.InferRooms()
+
+	dir := DoorDirectionHorizontal
+	if edge == DirectionEast || edge == DirectionWest {
+		dir = DoorDirectionVertical
+	}
+	world.Doors[door] = dir
+	world.TagDoor(door, TagEntrance)
+	if len(tunnel) > 0 {
+		world.addCorridor(tunnel, 1, door)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package generate
+
+// Tag labels a room or door with its purpose
+type Tag string
+
+// Tags used by the generators and passes, any other string can be used too
+const (
+	TagStart    Tag = "start"
+	TagBoss     Tag = "boss"
+	TagTreasure Tag = "treasure"
+	TagShop     Tag = "shop"
+	TagSafe     Tag = "safe"
+	TagEntrance Tag = "entrance"
+	TagArena    Tag = "arena"
+	TagLocked   Tag = "locked"
+	TagKey      Tag = "key"
+	TagPuzzle   Tag = "puzzle"
+	TagLandmark Tag = "landmark"
+)
+
+// TagRoom adds tags to a room, tags which the room already has are ignored
+func (world *World) TagRoom(room Rect, tags ...Tag) {
+	for _, tag := range tags {
+		if !world.RoomHasTag(room, tag) {
+			world.RoomTags[room] = append(world.RoomTags[room], tag)
+		}
+	}
+}
+
+// RoomHasTag returns true if the room has been tagged with tag
+func (world *World) RoomHasTag(room Rect, tag Tag) bool {
+	for _, t := range world.RoomTags[room] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagDoor adds tags to a door, tags which the door already has are ignored
+func (world *World) TagDoor(door Rect, tags ...Tag) {
+	for _, tag := range tags {
+		if !world.DoorHasTag(door, tag) {
+			world.DoorTags[door] = append(world.DoorTags[door], tag)
+		}
+	}
+}
+
+// DoorHasTag returns true if the door has been tagged with tag
+func (world *World) DoorHasTag(door Rect, tag Tag) bool {
+	for _, t := range world.DoorTags[door] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// UntagRoom removes tags from a room
+func (world *World) UntagRoom(room Rect, tags ...Tag) {
+	kept := world.RoomTags[room][:0]
+	for _, t := range world.RoomTags[room] {
+		remove := false
+		for _, tag := range tags {
+			if t == tag {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(world.RoomTags, room)
+		return
+	}
+	world.RoomTags[room] = kept
+}
@@ -0,0 +1,174 @@
+package generate
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Theme is how a 3D renderer, such as a grid based first person dungeon crawler, should build part of the world
+type Theme struct {
+	FloorMaterial string  `json:"floorMaterial"`
+	CeilingHeight float64 `json:"ceilingHeight"` // in tiles, 0 for open sky
+	WallSet       string  `json:"wallSet"`
+}
+
+// ThemeRules decides the theme of each room and tile. A room gets the theme of its first tag which has one, otherwise
+// the theme of the biome at its middle. Tiles outside of rooms get the theme of their biome, anything else gets Default
+type ThemeRules struct {
+	Default Theme
+	Biomes  map[Biome]Theme
+	Tags    map[Tag]Theme
+}
+
+// DefaultThemeRules returns stone and brick for dungeons, rock for caves and open sky for the outdoor biomes, with
+// taller ceilings for arenas and boss rooms
+func DefaultThemeRules() ThemeRules {
+	return ThemeRules{
+		Default: Theme{FloorMaterial: "stone", CeilingHeight: 1, WallSet: "brick"},
+		Biomes: map[Biome]Theme{
+			BiomeDungeon:   {FloorMaterial: "stone", CeilingHeight: 1, WallSet: "brick"},
+			BiomeCave:      {FloorMaterial: "dirt", CeilingHeight: 1.5, WallSet: "rock"},
+			BiomeCrypt:     {FloorMaterial: "flagstone", CeilingHeight: 1, WallSet: "ossuary"},
+			BiomeGrassland: {FloorMaterial: "grass", WallSet: "hedge"},
+			BiomeForest:    {FloorMaterial: "moss", WallSet: "trees"},
+			BiomeBeach:     {FloorMaterial: "sand", WallSet: "cliff"},
+			BiomeOcean:     {FloorMaterial: "water", WallSet: "cliff"},
+		},
+		Tags: map[Tag]Theme{
+			TagBoss:     {FloorMaterial: "obsidian", CeilingHeight: 3, WallSet: "pillars"},
+			TagArena:    {FloorMaterial: "sand", CeilingHeight: 2, WallSet: "brick"},
+			TagTreasure: {FloorMaterial: "marble", CeilingHeight: 1, WallSet: "gilded"},
+			TagShop:     {FloorMaterial: "wood", CeilingHeight: 1, WallSet: "timber"},
+		},
+	}
+}
+
+// AssignThemes gives every room a theme with rules, stored in world.RoomThemes, and keeps rules for the tiles outside
+// of rooms. Call it again after changing rooms, tags or biomes
+func (world *World) AssignThemes(rules ThemeRules) {
+	world.themeRules = &rules
+	world.RoomThemes = make(map[Rect]Theme)
+	for room := range world.Rooms {
+		world.RoomThemes[room] = rules.roomTheme(world, room)
+	}
+}
+
+// roomTheme returns the theme of a room, from its tags or the biome at its middle
+func (rules ThemeRules) roomTheme(world *World, room Rect) Theme {
+	for _, tag := range world.RoomTags[room] {
+		if theme, ok := rules.Tags[tag]; ok {
+			return theme
+		}
+	}
+	return rules.biomeTheme(world.Biomes[room.Y+room.H/2][room.X+room.W/2])
+}
+
+// biomeTheme returns the theme of a biome, or the default theme
+func (rules ThemeRules) biomeTheme(biome Biome) Theme {
+	if theme, ok := rules.Biomes[biome]; ok {
+		return theme
+	}
+	return rules.Default
+}
+
+// ThemeAt returns the theme of the tile at x,y: the theme of the room it's in, otherwise the theme of its biome.
+// false is returned if AssignThemes hasn't been called or x,y is out of bounds
+func (world *World) ThemeAt(x, y int) (Theme, bool) {
+	if world.themeRules == nil || x < 0 || y < 0 || x >= world.Width || y >= world.Height {
+		return Theme{}, false
+	}
+	if room, ok := world.RoomAt(x, y); ok {
+		if theme, ok := world.RoomThemes[room]; ok {
+			return theme, true
+		}
+	}
+	return world.themeRules.biomeTheme(world.Biomes[y][x]), true
+}
+
+// themeExport is written by WriteThemes
+type themeExport struct {
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	Themes []Theme           `json:"themes"`
+	Cells  [][]int           `json:"cells"` // indexed [y][x], index into themes or -1
+	Rooms  []themeExportRoom `json:"rooms"` // in the order of RoomsOrdered
+}
+
+// themeExportRoom is a room written by WriteThemes
+type themeExportRoom struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	W     int    `json:"w"`
+	H     int    `json:"h"`
+	Name  string `json:"name,omitempty"`
+	Theme int    `json:"theme"`
+}
+
+// WriteThemes writes the themes of the world as JSON for 3D renderers: the distinct themes, a grid of which theme
+// each tile uses and the theme of each room. Walkable tiles use their own theme and walls use the theme of a walkable
+// tile beside them, so each wall face can be built from the wall set of the space it faces. Other tiles are -1.
+// AssignThemes is called with DefaultThemeRules if it hasn't been called yet
+func (world *World) WriteThemes(w io.Writer) error {
+	if world.themeRules == nil {
+		world.AssignThemes(DefaultThemeRules())
+	}
+	export := themeExport{
+		Width:  world.Width,
+		Height: world.Height,
+		Themes: make([]Theme, 0),
+		Cells:  make([][]int, world.Height),
+		Rooms:  make([]themeExportRoom, 0, len(world.Rooms)),
+	}
+	indexes := make(map[Theme]int)
+	index := func(theme Theme) int {
+		i, ok := indexes[theme]
+		if !ok {
+			i = len(export.Themes)
+			indexes[theme] = i
+			export.Themes = append(export.Themes, theme)
+		}
+		return i
+	}
+
+	for y := range export.Cells {
+		export.Cells[y] = make([]int, world.Width)
+		for x := range export.Cells[y] {
+			export.Cells[y][x] = -1
+			at := Point{X: x, Y: y}
+			if !world.walkable(x, y) {
+				if world.Tiles[y][x] != TileWall {
+					continue
+				}
+				found := false
+				for _, o := range polarOffsets {
+					if world.walkable(x+o.X, y+o.Y) {
+						at, found = Point{X: x + o.X, Y: y + o.Y}, true
+						break
+					}
+				}
+				if !found {
+					continue
+				}
+			}
+			if theme, ok := world.ThemeAt(at.X, at.Y); ok {
+				export.Cells[y][x] = index(theme)
+			}
+		}
+	}
+	for _, room := range world.RoomsOrdered() {
+		theme, ok := world.RoomThemes[room]
+		if !ok {
+			theme = world.themeRules.roomTheme(world, room)
+		}
+		export.Rooms = append(export.Rooms, themeExportRoom{
+			X:     room.X,
+			Y:     room.Y,
+			W:     room.W,
+			H:     room.H,
+			Name:  world.RoomNames[room],
+			Theme: index(theme),
+		})
+	}
+
+	return json.NewEncoder(w).Encode(export)
+}
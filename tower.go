@@ -0,0 +1,270 @@
+package generate
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidShrink is returned by GenerateTower when TowerOptions.Shrink isn't from 0 up to 1
+var ErrInvalidShrink = errors.New("Tower shrink must be at least 0 and less than 1")
+
+// TowerShape is the outline of the floors of a tower
+type TowerShape int8
+
+const (
+	// TowerShapeSquare floors fill a rect, like a keep
+	TowerShapeSquare TowerShape = iota
+	// TowerShapeRound floors fill the ellipse inside their rect, like a round tower
+	TowerShapeRound
+)
+
+// TowerOptions controls GenerateTower
+type TowerOptions struct {
+	Floors int // floors in the tower including the ground floor, less than 1 counts as 1
+	Shape  TowerShape
+	Shrink float64 // share (0-1) of the smaller side of the ground floor each floor is inset by from the one below
+	// Generate lays out each floor and any passes after it, such as AddWalls, on a world whose border is the floor's
+	// footprint. GenerateBSP(3) then AddWalls if nil
+	Generate func(world *World) error
+}
+
+// DefaultTowerOptions returns options for a round tower of 4 floors, each a little narrower than the one below
+func DefaultTowerOptions() TowerOptions {
+	return TowerOptions{
+		Floors: 4,
+		Shape:  TowerShapeRound,
+		Shrink: 0.1,
+	}
+}
+
+// TowerFloor is one floor of a tower made by GenerateTower
+type TowerFloor struct {
+	World     *World
+	Footprint Rect  // bounds of the floor's outline
+	Up        Point // stairs to the next floor up, at the same point as its Down, if HasUp
+	Down      Point // stairs to the floor below, if HasDown
+	HasUp     bool
+	HasDown   bool
+}
+
+// GenerateTower generates a tower of opts.Floors floors, each a world the size of this one, stacked so the same x,y is
+// above or below on every floor. The world is the ground floor and its footprint is the area inside world.Border,
+// every floor above is inset from it by opts.Shrink of its smaller side more than the floor below, so the tower
+// narrows as it's climbed. Round floors are trimmed to the ellipse inside their footprint, keeping their walls inside
+// it, then their rooms are found again with RebuildMetadata and anything cut off from the rest of the floor is
+// filled in. Floors above the ground floor are separate worlds with the same config and their own random source.
+// Each pair of floors is joined by stairs at the same point on both, on TileStairs in the largest walkable area of
+// each, as far as it can be walked from the stairs coming up so climbing the tower crosses every floor. Rooms are
+// preferred for stairwells. If the floors share no walkable tile a corridor is carved on the lower floor to the tile
+// under the nearest floor of the upper one. The stairs are also recorded as exits named "up" and "down" on each floor
+// so Exits and ExitMatrix can be used, their Side isn't used.
+// The floors are returned from the ground up, ErrNotEnoughSpace is returned if a floor's footprint is too small for
+// anything to be generated in it and ErrInvalidShrink if opts.Shrink is out of range
+func (world *World) GenerateTower(opts TowerOptions, overrides ...Option) (floors []TowerFloor, err error) {
+	defer world.override(overrides)()
+	world.beginReport("Tower", 0)
+	defer func() { world.endReport(err) }()
+
+	if opts.Shrink < 0 || opts.Shrink >= 1 {
+		return nil, ErrInvalidShrink
+	}
+	generate := opts.Generate
+	if generate == nil {
+		generate = func(world *World) error {
+			if err := world.GenerateBSP(3); err != nil {
+				return err
+			}
+			world.AddWalls()
+			return nil
+		}
+	}
+	count := maxInt(opts.Floors, 1)
+
+	b := world.Border
+	ground := Rect{X: b, Y: b, W: world.Width - b*2, H: world.Height - b*2}
+	side := float64(minInt(ground.W, ground.H))
+	floors = make([]TowerFloor, 0, count)
+	for i := 0; i < count; i++ {
+		inset := int(math.Round(side * opts.Shrink * float64(i)))
+		footprint := Rect{X: ground.X + inset, Y: ground.Y + inset, W: ground.W - inset*2, H: ground.H - inset*2}
+		if footprint.W < 1 || footprint.H < 1 {
+			return nil, ErrNotEnoughSpace
+		}
+
+		floor := world
+		if i > 0 {
+			cfg := world.config()
+			cfg.Border = b + inset
+			floor = cfg.build()
+			floor.reseed(world.rng.Int63())
+			floor.ShowErrorMessages = world.ShowErrorMessages
+			floor.Logf = world.Logf
+			floor.DurationBeforeRetry = world.DurationBeforeRetry
+			floor.DurationBeforeError = world.DurationBeforeError
+			floor.RouteCost = world.RouteCost
+			floor.WeatherCheck = world.WeatherCheck
+		}
+		err := generate(floor)
+		if i > 0 {
+			world.Report.Retries += floor.Report.Retries
+			world.Report.Rollbacks += floor.Report.Rollbacks
+		}
+		if err != nil {
+			return nil, err
+		}
+		if opts.Shape == TowerShapeRound {
+			floor.trimToOutline(towerOutline(footprint, wallGap(floor.maxWallThickness())))
+		}
+		if len(floor.largestArea()) == 0 {
+			return nil, ErrNotEnoughSpace
+		}
+		floor.exits = nil
+		floors = append(floors, TowerFloor{World: floor, Footprint: footprint})
+	}
+
+	for i := 0; i+1 < len(floors); i++ {
+		p, err := linkTowerFloors(&floors[i], &floors[i+1], opts.Shape)
+		if err != nil {
+			return nil, err
+		}
+		floors[i].Up, floors[i].HasUp = p, true
+		floors[i+1].Down, floors[i+1].HasDown = p, true
+	}
+	return floors, nil
+}
+
+// towerOutline returns whether x,y is far enough inside the ellipse filling footprint for its walls, t thick, to fit
+func towerOutline(footprint Rect, t int) func(x, y int) bool {
+	rx, ry := float64(footprint.W)/2-float64(t), float64(footprint.H)/2-float64(t)
+	cx, cy := float64(footprint.X)+float64(footprint.W)/2, float64(footprint.Y)+float64(footprint.H)/2
+	return func(x, y int) bool {
+		if rx <= 0 || ry <= 0 {
+			return false
+		}
+		dx, dy := (float64(x)+0.5-cx)/rx, (float64(y)+0.5-cy)/ry
+		return dx*dx+dy*dy <= 1
+	}
+}
+
+// trimToOutline clears every walkable tile outside inside, and every one cut off from the largest walkable area after
+// that, then rebuilds the rooms, doors and corridors from what's left
+func (world *World) trimToOutline(inside func(x, y int) bool) {
+	cut := func(keep func(x, y int) bool) bool {
+		gone := make([]Point, 0)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if tile := world.Tiles[y][x]; tile != TileVoid && tile != TileWall && tile != TilePreWall && !keep(x, y) {
+					gone = append(gone, Point{X: x, Y: y})
+				}
+			}
+		}
+		if len(gone) == 0 {
+			return false
+		}
+		world.repairWalls(pointBounds(gone), func() {
+			for _, p := range gone {
+				world.SetTile(p.X, p.Y, TileVoid)
+			}
+		})
+		return true
+	}
+
+	trimmed := cut(inside)
+	kept := make(map[Point]bool)
+	for _, p := range world.largestArea() {
+		kept[p] = true
+	}
+	if cut(func(x, y int) bool { return kept[Point{X: x, Y: y}] || !world.walkable(x, y) }) || trimmed {
+		world.RebuildMetadata()
+	}
+}
+
+// linkTowerFloors places the stairs between lower and the floor above it, returning where they are
+func linkTowerFloors(lower, upper *TowerFloor, shape TowerShape) (Point, error) {
+	below, above := lower.World, upper.World
+	upperArea := above.largestArea()
+	onUpper := make(map[Point]bool, len(upperArea))
+	for _, p := range upperArea {
+		onUpper[p] = true
+	}
+	lowerArea := below.largestArea()
+
+	// Walking distance from the stairs coming up, or from the middle of the ground floor
+	from := lower.Down
+	if !lower.HasDown {
+		mid := Point{X: lower.Footprint.X + lower.Footprint.W/2, Y: lower.Footprint.Y + lower.Footprint.H/2}
+		from = lowerArea[0]
+		for _, p := range lowerArea[1:] {
+			if p.distance(mid) < from.distance(mid) {
+				from = p
+			}
+		}
+	}
+	dist := below.DistanceField(from)
+
+	best, bestScore := Point{}, -1
+	for _, p := range lowerArea {
+		if !onUpper[p] || below.Tiles[p.Y][p.X] != TileFloor || above.Tiles[p.Y][p.X] != TileFloor {
+			continue
+		}
+		score := dist[p.Y][p.X] * 4
+		if _, in := below.RoomAt(p.X, p.Y); in {
+			score += below.Width + below.Height
+		}
+		if _, in := above.RoomAt(p.X, p.Y); in {
+			score += below.Width + below.Height
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+
+	if bestScore == -1 {
+		// Dig from the lower floor to the nearest floor tile of the upper one, staying inside the lower floor's outline
+		var to Point
+		found := false
+		for _, p := range upperArea {
+			if above.Tiles[p.Y][p.X] != TileFloor {
+				continue
+			}
+			if !found || p.distance(from) < to.distance(from) {
+				to, found = p, true
+			}
+		}
+		if !found {
+			return Point{}, ErrNoPath
+		}
+		cost := below.RouteCost
+		if shape == TowerShapeRound {
+			inside := towerOutline(lower.Footprint, wallGap(below.maxWallThickness()))
+			below.RouteCost = func(world *World, x, y int) float64 {
+				if !inside(x, y) {
+					return -1
+				}
+				if cost == nil {
+					return 1
+				}
+				return cost(world, x, y)
+			}
+		}
+		_, err := below.CarveCorridor(from, to, maxInt(below.MinCorridorSize, 1), CorridorStyleShort)
+		below.RouteCost = cost
+		if err != nil {
+			return Point{}, err
+		}
+		best = to
+	}
+
+	below.SetTile(best.X, best.Y, TileStairs)
+	above.SetTile(best.X, best.Y, TileStairs)
+	below.addStairsExit("up", best)
+	above.addStairsExit("down", best)
+	return best, nil
+}
+
+// addStairsExit records the stairs at p as an exit called name
+func (world *World) addStairsExit(name string, p Point) {
+	exit := Exit{Name: name, Point: p}
+	exit.Room, exit.InRoom = world.RoomAt(p.X, p.Y)
+	world.exits = append(world.exits, exit)
+}
@@ -0,0 +1,111 @@
+package generate
+
+import "math/rand"
+
+// TrackOptions controls how TrackDressing lays out its network
+type TrackOptions struct {
+	Coverage float64 // fraction (0-1) of the rooms the network reaches, it always joins at least 2
+	Loops    float64 // chance (0-1) of track along each other corridor between two rooms on the network
+}
+
+// DefaultTrackOptions returns options for a network reaching half of the rooms with a few loops
+func DefaultTrackOptions() TrackOptions {
+	return TrackOptions{Coverage: 0.5, Loops: 0.3}
+}
+
+// TrackDressing returns a pass which lays a minecart track network on the named layer, for mines and games with rails.
+// The network branches out from a random room along corridors until it reaches opts.Coverage of the rooms, then
+// closes loops along some of the corridors between rooms it already reaches. Track runs down the middle of each
+// corridor and on to the middle of the rooms at either end, and TileJunction marks where three or more tracks meet
+func TrackDressing(name string, opts TrackOptions) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		corridors := make([]Corridor, 0)
+		for _, c := range world.Corridors {
+			if len(c.Rooms) == 2 && len(c.Path) > 0 && c.Rooms[0] != c.Rooms[1] {
+				corridors = append(corridors, c)
+			}
+		}
+		if len(corridors) == 0 {
+			return
+		}
+		target := maxInt(int(clampFloat(opts.Coverage, 0, 1)*float64(len(world.Rooms))+0.5), 2)
+
+		// Grow the network a corridor at a time from a random room, like a spanning tree
+		start := corridors[rng.Intn(len(corridors))].Rooms[rng.Intn(2)]
+		reached := map[Rect]bool{start: true}
+		laid := make([]bool, len(corridors))
+		for len(reached) < target {
+			frontier := make([]int, 0)
+			for i, c := range corridors {
+				if !laid[i] && reached[c.Rooms[0]] != reached[c.Rooms[1]] {
+					frontier = append(frontier, i)
+				}
+			}
+			if len(frontier) == 0 {
+				break
+			}
+			i := frontier[rng.Intn(len(frontier))]
+			laid[i] = true
+			reached[corridors[i].Rooms[0]] = true
+			reached[corridors[i].Rooms[1]] = true
+		}
+		for i, c := range corridors {
+			if !laid[i] && reached[c.Rooms[0]] && reached[c.Rooms[1]] && rng.Float64() < opts.Loops {
+				laid[i] = true
+			}
+		}
+
+		layer := world.Layer(name)
+		lay := func(p Point) {
+			if world.walkable(p.X, p.Y) && layer[p.Y][p.X] == TileVoid {
+				layer[p.Y][p.X] = TileTrack
+			}
+		}
+		for i, c := range corridors {
+			if !laid[i] {
+				continue
+			}
+			for _, p := range c.Path {
+				lay(p)
+			}
+			// Run on from each end to the middle of the nearest of the corridor's rooms
+			for _, end := range [2]Point{c.From, c.To} {
+				room := c.Rooms[0]
+				if roomDistance(c.Rooms[1], end) < roomDistance(room, end) {
+					room = c.Rooms[1]
+				}
+				// Go straight into the room first so the track doesn't run into its walls
+				yFirst := end.X >= room.X && end.X < room.X+room.W
+				for _, p := range elbowPath(end, Point{X: room.X + room.W/2, Y: room.Y + room.H/2}, yFirst) {
+					lay(p)
+				}
+			}
+		}
+
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if layer[y][x] != TileTrack {
+					continue
+				}
+				joins := 0
+				for _, o := range polarOffsets {
+					nx, ny := x+o.X, y+o.Y
+					if nx >= 0 && ny >= 0 && nx < world.Width && ny < world.Height &&
+						(layer[ny][nx] == TileTrack || layer[ny][nx] == TileJunction) {
+						joins++
+					}
+				}
+				if joins >= 3 {
+					layer[y][x] = TileJunction
+				}
+			}
+		}
+	}
+}
+
+// roomDistance returns how many tiles p is outside of room in each direction added together, 0 if it's inside
+func roomDistance(room Rect, p Point) int {
+	dx := maxInt(maxInt(room.X-p.X, p.X-(room.X+room.W-1)), 0)
+	dy := maxInt(maxInt(room.Y-p.Y, p.Y-(room.Y+room.H-1)), 0)
+	return dx + dy
+}
@@ -0,0 +1,67 @@
+package generate
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotUserTile is returned when registering a tile outside of the TileUser range
+var ErrNotUserTile = errors.New("Only tiles from TileUser to TileUserMax can be registered")
+
+// TileTraits is how a tile behaves for pathfinding, line of sight and the room graph
+type TileTraits struct {
+	Walkable bool
+	Opaque   bool // blocks line of sight and sound
+}
+
+var (
+	userTraitsMu sync.RWMutex
+	userTraits   = make(map[Tile]TileTraits)
+)
+
+// RegisterTile sets the traits of a user tile, so it can be walked on or seen through like the built in tiles.
+// Unregistered user tiles can't be walked on and block sight
+func RegisterTile(t Tile, traits TileTraits) error {
+	if !t.IsUser() {
+		return ErrNotUserTile
+	}
+	userTraitsMu.Lock()
+	defer userTraitsMu.Unlock()
+	userTraits[t] = traits
+	return nil
+}
+
+// traits returns the traits of a user tile and whether it has been registered
+func (t Tile) traits() (TileTraits, bool) {
+	userTraitsMu.RLock()
+	defer userTraitsMu.RUnlock()
+	traits, ok := userTraits[t]
+	return traits, ok
+}
+
+// Walkable returns true if the tile can be walked on
+func (t Tile) Walkable() bool {
+	switch t {
+	case TileFloor, TileDoor, TileRoomBegin, TileRoomEnd, TileBridge, TileGrass, TileRoad, TileSand, TilePortal,
+		TileStairs:
+		return true
+	}
+	if t.IsUser() {
+		traits, _ := t.traits()
+		return traits.Walkable
+	}
+	return false
+}
+
+// Opaque returns true if the tile blocks line of sight
+func (t Tile) Opaque() bool {
+	switch t {
+	case TileVoid, TileWall, TilePreWall, TileTree, TilePillar:
+		return true
+	}
+	if t.IsUser() {
+		traits, ok := t.traits()
+		return !ok || traits.Opaque
+	}
+	return false
+}
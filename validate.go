@@ -0,0 +1,60 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidWorld is returned by Validate, wrapped with a description of what's wrong
+var ErrInvalidWorld = errors.New("World is invalid")
+
+// Validate checks a finished world for the mistakes which make a map unplayable: TilePreWall left over (call
+// AddWalls first), rooms which overlap void or walls, and TileFloor which can't be walked to from the first room of
+// RoomsOrdered (or the first floor tile if there are no rooms). The first problem found is returned, wrapping
+// ErrInvalidWorld. Set WorldConfig.Validate to run it after every generation in a batch
+func (world *World) Validate() error {
+	for y := range world.Tiles {
+		for x, t := range world.Tiles[y] {
+			if t == TilePreWall {
+				return fmt.Errorf("%w: pre-wall left at %d,%d", ErrInvalidWorld, x, y)
+			}
+		}
+	}
+
+	var start Point
+	found := false
+	for _, room := range world.RoomsOrdered() {
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				switch t, err := world.GetTile(x, y); {
+				case err != nil:
+					return fmt.Errorf("%w: room %v is outside of the map", ErrInvalidWorld, room)
+				case t == TileVoid || t == TileWall || t == TilePreWall:
+					return fmt.Errorf("%w: room %v has %s at %d,%d", ErrInvalidWorld, room, tileStyles[t].name, x, y)
+				case !found && world.walkable(x, y):
+					start, found = Point{X: x, Y: y}, true
+				}
+			}
+		}
+	}
+	for y := 0; y < world.Height && !found; y++ {
+		for x := 0; x < world.Width && !found; x++ {
+			if world.Tiles[y][x] == TileFloor {
+				start, found = Point{X: x, Y: y}, true
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	dist := world.DistanceField(start)
+	for y := range dist {
+		for x, d := range dist[y] {
+			if d < 0 && world.Tiles[y][x] == TileFloor {
+				return fmt.Errorf("%w: floor at %d,%d can't be reached from %d,%d", ErrInvalidWorld, x, y, start.X, start.Y)
+			}
+		}
+	}
+	return nil
+}
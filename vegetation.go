@@ -0,0 +1,141 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+)
+
+// VegetationOptions controls how VegetationDressing scatters plants
+type VegetationOptions struct {
+	Clusters   int     // how many clumps of plants to grow
+	Radius     float64 // how far a clump spreads from its seed
+	Spacing    float64 // the closest two plants can be, in tiles
+	Tiles      []Tile  // plants from the middle of a clump to its edge, so trees can be ringed by bushes
+	Ground     []Tile  // tiles plants can grow on
+	RoadMargin int     // tiles kept clear on each side of TileRoad and TileBridge
+	Footprints []Rect  // areas kept clear, such as where buildings will be placed
+}
+
+// DefaultVegetationOptions returns options for small copses of trees ringed by bushes, growing on grass
+func DefaultVegetationOptions() VegetationOptions {
+	return VegetationOptions{
+		Clusters:   8,
+		Radius:     6,
+		Spacing:    1.5,
+		Tiles:      []Tile{TileTree, TileTree, TileBush},
+		Ground:     []Tile{TileGrass},
+		RoadMargin: 1,
+	}
+}
+
+// vegetationAttempts is how many times a plant tries to seed a neighbour before giving up
+const vegetationAttempts = 20
+
+// VegetationDressing returns a pass which grows clumps of plants on the named layer for outdoor maps. Each clump
+// starts at a random ground tile and spreads out with Poisson-disk sampling, thinning out towards its edge. Roads,
+// bridges and footprints are kept clear, as is anything already on the layer
+func VegetationDressing(name string, opts VegetationOptions) DressingPass {
+	return func(world *World, rng *rand.Rand) {
+		if opts.Clusters <= 0 || opts.Radius <= 0 || len(opts.Tiles) == 0 {
+			return
+		}
+		layer := world.Layer(name)
+		spacing := math.Max(opts.Spacing, 1)
+
+		ground := make(map[Tile]bool, len(opts.Ground))
+		for _, t := range opts.Ground {
+			ground[t] = true
+		}
+		blocked := make([][]bool, world.Height)
+		for y := range blocked {
+			blocked[y] = make([]bool, world.Width)
+		}
+		block := func(area Rect) {
+			for y := maxInt(area.Y, 0); y < minInt(area.Y+area.H, world.Height); y++ {
+				for x := maxInt(area.X, 0); x < minInt(area.X+area.W, world.Width); x++ {
+					blocked[y][x] = true
+				}
+			}
+		}
+		m := opts.RoadMargin
+		for y := range world.Tiles {
+			for x, t := range world.Tiles[y] {
+				if t == TileRoad || t == TileBridge {
+					block(Rect{X: x - m, Y: y - m, W: m*2 + 1, H: m*2 + 1})
+				}
+			}
+		}
+		for _, footprint := range opts.Footprints {
+			block(footprint)
+		}
+
+		b := world.Border
+		canGrow := func(x, y int) bool {
+			if x < b || y < b || x >= world.Width-b || y >= world.Height-b {
+				return false
+			}
+			return ground[world.Tiles[y][x]] && !blocked[y][x] && layer[y][x] == TileVoid
+		}
+		seeds := make([]Point, 0)
+		for y := 0; y < world.Height; y++ {
+			for x := 0; x < world.Width; x++ {
+				if canGrow(x, y) {
+					seeds = append(seeds, Point{X: x, Y: y})
+				}
+			}
+		}
+
+		tooClose := func(p Point) bool {
+			r := int(math.Ceil(spacing))
+			for y := maxInt(p.Y-r, 0); y <= minInt(p.Y+r, world.Height-1); y++ {
+				for x := maxInt(p.X-r, 0); x <= minInt(p.X+r, world.Width-1); x++ {
+					if layer[y][x] != TileVoid && p.distance(Point{X: x, Y: y}) < spacing {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		plant := func(p, center Point) {
+			i := int(p.distance(center) / opts.Radius * float64(len(opts.Tiles)))
+			layer[p.Y][p.X] = opts.Tiles[minInt(i, len(opts.Tiles)-1)]
+		}
+
+		for c := 0; c < opts.Clusters && len(seeds) > 0; c++ {
+			center := seeds[rng.Intn(len(seeds))]
+			if !canGrow(center.X, center.Y) || tooClose(center) {
+				continue
+			}
+			plant(center, center)
+			active := []Point{center}
+			for len(active) > 0 {
+				i := rng.Intn(len(active))
+				from := active[i]
+				grew := false
+				for a := 0; a < vegetationAttempts; a++ {
+					angle := rng.Float64() * math.Pi * 2
+					dist := spacing * (1 + rng.Float64())
+					p := Point{
+						X: from.X + int(math.Round(math.Cos(angle)*dist)),
+						Y: from.Y + int(math.Round(math.Sin(angle)*dist)),
+					}
+					d := p.distance(center) / opts.Radius
+					if d > 1 || !canGrow(p.X, p.Y) || tooClose(p) {
+						continue
+					}
+					// Thin out towards the edge of the clump
+					if rng.Float64() < d*d {
+						continue
+					}
+					plant(p, center)
+					active = append(active, p)
+					grew = true
+					break
+				}
+				if !grew {
+					active = append(active[:i], active[i+1:]...)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package generate
+
+import (
+	"math"
+	"sort"
+)
+
+// WaterTableOptions controls AddWaterTable
+type WaterTableOptions struct {
+	Flooded float64 // fraction (0-1) of the floor below the water level before any of it is drained
+	Scale   float64 // size of the dips and rises of the floor in tiles, larger for bigger pools
+}
+
+// DefaultWaterTableOptions returns options which flood about a third of the floor
+func DefaultWaterTableOptions() WaterTableOptions {
+	return WaterTableOptions{Flooded: 0.3, Scale: 10}
+}
+
+// waterDrainCost is the cost of draining a water tile when routing a dry path, so paths go around pools if they can
+const waterDrainCost = 4
+
+// AddWaterTable floods caves, such as those made by GenerateRandomWalk. The floor is given noise
+// heights and a water level is picked so that opts.Flooded of the floor is below it, and every TileFloor below the
+// level becomes TileWater. Drainage channels are then dug back to floor so a dry path spans the map along its longest
+// side and every dry pocket is joined to it. The number of tiles left flooded is returned, ErrNoRooms if the world
+// has no floor
+func (world *World) AddWaterTable(opts WaterTableOptions) (int, error) {
+	height := newNoise(world.rng)
+	scale := math.Max(opts.Scale, 1)
+	floor := make([]Point, 0)
+	heights := make(map[Point]float64)
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if world.Tiles[y][x] == TileFloor {
+				p := Point{X: x, Y: y}
+				floor = append(floor, p)
+				heights[p] = height.Fractal(float64(x)/scale, float64(y)/scale, 3)
+			}
+		}
+	}
+	if len(floor) == 0 {
+		return 0, ErrNoRooms
+	}
+
+	// The water level is the height of the highest flooded tile
+	sort.SliceStable(floor, func(i, j int) bool { return heights[floor[i]] < heights[floor[j]] })
+	flooded := int(clampFloat(opts.Flooded, 0, 1) * float64(len(floor)))
+	for _, p := range floor[:flooded] {
+		world.SetTile(p.X, p.Y, TileWater)
+	}
+
+	// Drain a path between the dry tiles furthest apart along the longest side of the map
+	dry := floor[flooded:]
+	if len(dry) == 0 {
+		return flooded, nil
+	}
+	along := func(p Point) int {
+		if world.Width >= world.Height {
+			return p.X
+		}
+		return p.Y
+	}
+	from, to := dry[0], dry[0]
+	for _, p := range dry {
+		if along(p) < along(from) {
+			from = p
+		}
+		if along(p) > along(to) {
+			to = p
+		}
+	}
+	path := world.findPath(from, to, 1, func(x, y int) float64 {
+		switch {
+		case world.walkable(x, y):
+			return 1
+		case world.Tiles[y][x] == TileWater:
+			return waterDrainCost
+		}
+		return -1
+	})
+	flooded -= world.drain(path)
+
+	// Join every dry pocket the water cut off to the rest, draining the shortest way through
+	wet := func(x, y int) bool {
+		return world.walkable(x, y) || world.Tiles[y][x] == TileWater
+	}
+	stranded := make(map[Point]bool)
+	for {
+		reached := world.bfsLinks(from, world.walkable, world.portalLinks())
+		var pocket Point
+		found := false
+		for _, p := range dry {
+			if reached[p.Y][p.X] < 0 && !stranded[p] && world.Tiles[p.Y][p.X] == TileFloor {
+				pocket, found = p, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+
+		dist := world.bfs(pocket, wet)
+		best := Point{X: -1}
+		for y := range dist {
+			for x, d := range dist[y] {
+				if d >= 0 && reached[y][x] >= 0 && (best.X < 0 || d < dist[best.Y][best.X]) {
+					best = Point{X: x, Y: y}
+				}
+			}
+		}
+		if best.X < 0 {
+			// The pocket was never joined to the rest of the cave
+			for y := range dist {
+				for x, d := range dist[y] {
+					if d >= 0 {
+						stranded[Point{X: x, Y: y}] = true
+					}
+				}
+			}
+			continue
+		}
+		flooded -= world.drain(pathFromDistances(dist, best, nil))
+	}
+	return flooded, nil
+}
+
+// drain turns the water on path back to floor, returning how many tiles were drained
+func (world *World) drain(path []Point) int {
+	drained := 0
+	for _, p := range path {
+		if world.Tiles[p.Y][p.X] == TileWater {
+			world.SetTile(p.X, p.Y, TileFloor)
+			drained++
+		}
+	}
+	return drained
+}
@@ -0,0 +1,132 @@
+package generate
+
+// WeatherFunc approves a hole Weather has just knocked through a wall, the hole is walled up again if it returns false
+type WeatherFunc func(world *World, hole []Point) bool
+
+// Weather knocks holes through the walls between floor for aged or battle-damaged versions of a map. Each straight run
+// of wall up to the wall thickness long with walkable tiles at both ends and wall either side becomes floor with the
+// given chance (0-1), as long as world.WeatherCheck approves it. Holes only join floor which could already reach each
+// other without going through a locked door or gate or up a ledge, so they make shortcuts but never let the player
+// skip a lock or get somewhere they couldn't before. The number of holes made is returned
+func (world *World) Weather(chance float64) int {
+	region := world.lockRegions()
+	maxRun := wallGap(world.WallThickness)
+	holes := 0
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			for _, step := range [2]Point{{X: 1}, {Y: 1}} {
+				hole, ok := world.wallRun(x, y, step, maxRun)
+				if !ok || world.rng.Float64() >= chance {
+					continue
+				}
+				before := Point{X: x - step.X, Y: y - step.Y}
+				after := Point{X: hole[len(hole)-1].X + step.X, Y: hole[len(hole)-1].Y + step.Y}
+				r := region[before.Y][before.X]
+				if r < 0 || r != region[after.Y][after.X] || !world.openHole(hole) {
+					continue
+				}
+				for _, p := range hole {
+					region[p.Y][p.X] = r
+				}
+				holes++
+			}
+		}
+	}
+	return holes
+}
+
+// wallRun returns the run of wall starting at x,y going along step, if there's walkable floor just before it and just
+// after it within maxRun tiles, and wall either side of every tile of it
+func (world *World) wallRun(x, y int, step Point, maxRun int) ([]Point, bool) {
+	if !world.walkable(x-step.X, y-step.Y) {
+		return nil, false
+	}
+	run := make([]Point, 0, maxRun)
+	for n := 0; n < maxRun; n++ {
+		p := Point{X: x + step.X*n, Y: y + step.Y*n}
+		if p.X >= world.Width || p.Y >= world.Height || world.Tiles[p.Y][p.X] != TileWall {
+			return nil, false
+		}
+		if world.walkable(p.X-step.Y, p.Y-step.X) || world.walkable(p.X+step.Y, p.Y+step.X) {
+			return nil, false
+		}
+		run = append(run, p)
+		if world.walkable(p.X+step.X, p.Y+step.Y) {
+			return run, true
+		}
+	}
+	return nil, false
+}
+
+// openHole turns hole into floor, putting the walls back and returning false if any of it is in the border or
+// world.WeatherCheck doesn't approve
+func (world *World) openHole(hole []Point) bool {
+	for i, p := range hole {
+		if err := world.setFloor(p.X, p.Y, FloorKindCorridor); err != nil {
+			for _, q := range hole[:i] {
+				world.SetTile(q.X, q.Y, TileWall)
+			}
+			return false
+		}
+	}
+	if world.WeatherCheck != nil && !world.WeatherCheck(world, hole) {
+		for _, p := range hole {
+			world.SetTile(p.X, p.Y, TileWall)
+		}
+		return false
+	}
+	return true
+}
+
+// lockRegions labels each walkable tile with the area it's in when locked doors, gates and ledges can't be passed,
+// -1 for tiles which can't be walked on or are one of those doors
+func (world *World) lockRegions() [][]int {
+	barrier := make(map[Point]bool)
+	bar := func(door Rect) {
+		for y := door.Y; y < door.Y+door.H; y++ {
+			for x := door.X; x < door.X+door.W; x++ {
+				barrier[Point{X: x, Y: y}] = true
+			}
+		}
+	}
+	for door := range world.Doors {
+		if world.DoorHasTag(door, TagLocked) {
+			bar(door)
+		}
+	}
+	for _, g := range world.Gates {
+		bar(g.Door)
+	}
+	for door := range world.Ledges {
+		bar(door)
+	}
+	passable := func(x, y int) bool {
+		return world.walkable(x, y) && !barrier[Point{X: x, Y: y}]
+	}
+
+	region := make([][]int, world.Height)
+	for y := range region {
+		region[y] = make([]int, world.Width)
+		for x := range region[y] {
+			region[y][x] = -1
+		}
+	}
+	links := world.portalLinks()
+	n := 0
+	for y := 0; y < world.Height; y++ {
+		for x := 0; x < world.Width; x++ {
+			if region[y][x] >= 0 || !passable(x, y) {
+				continue
+			}
+			for ry, row := range world.bfsLinks(Point{X: x, Y: y}, passable, links) {
+				for rx, d := range row {
+					if d >= 0 {
+						region[ry][rx] = n
+					}
+				}
+			}
+			n++
+		}
+	}
+	return region
+}
@@ -0,0 +1,300 @@
+package generate
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrSampleTooSmall is returned by GenerateWFC when the sample is smaller than a pattern
+var ErrSampleTooSmall = errors.New("Sample is smaller than the pattern size")
+
+// WFCOptions controls GenerateWFC
+type WFCOptions struct {
+	PatternSize int  // width and height of the patterns taken from the sample, 2 or 3 is usual
+	Symmetry    bool // add the rotations and reflections of every pattern, for samples which don't have a direction
+}
+
+// DefaultWFCOptions returns options for 3x3 patterns used as they are in the sample
+func DefaultWFCOptions() WFCOptions {
+	return WFCOptions{
+		PatternSize: 3,
+	}
+}
+
+// wfcPattern is a square of tiles from the sample and how many times it appears
+type wfcPattern struct {
+	tiles  [][]Tile
+	weight float64
+}
+
+// GenerateWFC generates the world from a small hand drawn sample with the overlapping Wave Function Collapse
+// algorithm, so every opts.PatternSize square of the map is one which appears in the sample, about as often as it
+// does there. Draw the style wanted, such as a few rooms and corridors or a patch of cave, as a [y][x] grid of tiles
+// and the map is filled inside world.Border with more of it. Each step the square with the fewest patterns left which
+// could go there is settled on one of them, picked by how common it is, and the patterns its neighbours can no longer
+// have are ruled out. If a square runs out of patterns the map is started again, until world.DurationBeforeError is
+// exceeded and ErrGenerationTimeout is returned. The map isn't guaranteed to be connected, use CleanIslands or
+// ConnectPOIs if it has to be. Rooms are found with InferRooms. ErrSampleTooSmall is returned if the sample is smaller
+// than a pattern
+func (world *World) GenerateWFC(sample [][]Tile, opts WFCOptions, overrides ...Option) (err error) {
+	defer world.override(overrides)()
+	world.beginReport("WFC", 0)
+	defer func() { world.endReport(err) }()
+
+	world.genStartTime = time.Now()
+	world.ResetWorld(world.Width, world.Height)
+
+	n := opts.PatternSize
+	if n < 1 || len(sample) < n || len(sample[0]) < n {
+		return ErrSampleTooSmall
+	}
+	for _, row := range sample {
+		if len(row) != len(sample[0]) {
+			return ErrSampleTooSmall
+		}
+	}
+	area := Rect{X: world.Border, Y: world.Border, W: world.Width - world.Border*2, H: world.Height - world.Border*2}
+	if area.W < n || area.H < n {
+		return ErrNotEnoughSpace
+	}
+
+	patterns := wfcPatterns(sample, n, opts.Symmetry)
+	// fits[p][d] lists the patterns which can be one step in direction d from pattern p
+	fits := make([][4][]int, len(patterns))
+	for p := range patterns {
+		for d := DirectionNorth; d <= DirectionWest; d++ {
+			for q := range patterns {
+				if wfcOverlaps(patterns[p].tiles, patterns[q].tiles, d.Dx(), d.Dy()) {
+					fits[p][d] = append(fits[p][d], q)
+				}
+			}
+		}
+	}
+
+	// Each cell is where the top left of a pattern goes
+	cw, ch := area.W-n+1, area.H-n+1
+	for {
+		if time.Now().Sub(world.genStartTime) > world.DurationBeforeError {
+			return ErrGenerationTimeout
+		}
+		if chosen, ok := world.collapseWFC(patterns, fits, cw, ch); ok {
+			for y := 0; y < area.H; y++ {
+				for x := 0; x < area.W; x++ {
+					// Cells past the last row and column come from the patterns which overlap them
+					cx, cy := minInt(x, cw-1), minInt(y, ch-1)
+					t := patterns[chosen[cy][cx]].tiles[y-cy][x-cx]
+					if t != TileVoid {
+						world.SetTile(area.X+x, area.Y+y, t)
+					}
+				}
+			}
+			world.InferRooms()
+			return nil
+		}
+		world.Report.Retries++
+	}
+}
+
+// collapseWFC settles every cell of a cw x ch grid on a pattern, returning the pattern of each cell or false if a cell
+// ran out of patterns
+func (world *World) collapseWFC(patterns []wfcPattern, fits [][4][]int, cw, ch int) ([][]int, bool) {
+	// wave[y][x][p] is whether pattern p can still go at x,y
+	wave := make([][][]bool, ch)
+	left := make([][]int, ch)
+	for y := range wave {
+		wave[y] = make([][]bool, cw)
+		left[y] = make([]int, cw)
+		for x := range wave[y] {
+			wave[y][x] = make([]bool, len(patterns))
+			for p := range patterns {
+				wave[y][x][p] = true
+			}
+			left[y][x] = len(patterns)
+		}
+	}
+
+	entropy := func(x, y int) float64 {
+		sum, sumLog := 0.0, 0.0
+		for p, ok := range wave[y][x] {
+			if ok {
+				w := patterns[p].weight
+				sum += w
+				sumLog += w * math.Log(w)
+			}
+		}
+		return math.Log(sum) - sumLog/sum
+	}
+
+	for {
+		// Settle the cell with the least entropy, ties broken at random
+		best, bx, by := math.Inf(1), -1, -1
+		for y := 0; y < ch; y++ {
+			for x := 0; x < cw; x++ {
+				if left[y][x] == 0 {
+					return nil, false
+				}
+				if left[y][x] == 1 {
+					continue
+				}
+				if e := entropy(x, y) + world.rng.Float64()*1e-6; e < best {
+					best, bx, by = e, x, y
+				}
+			}
+		}
+		if bx < 0 {
+			break
+		}
+
+		total := 0.0
+		for p, ok := range wave[by][bx] {
+			if ok {
+				total += patterns[p].weight
+			}
+		}
+		r := world.rng.Float64() * total
+		pick := -1
+		for p, ok := range wave[by][bx] {
+			if !ok {
+				continue
+			}
+			pick = p
+			if r -= patterns[p].weight; r < 0 {
+				break
+			}
+		}
+		for p := range wave[by][bx] {
+			wave[by][bx][p] = p == pick
+		}
+		left[by][bx] = 1
+
+		// Rule out the patterns of the neighbours which no pattern left in the cell fits next to
+		stack := []Point{{X: bx, Y: by}}
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for d := DirectionNorth; d <= DirectionWest; d++ {
+				nx, ny := c.X+d.Dx(), c.Y+d.Dy()
+				if nx < 0 || ny < 0 || nx >= cw || ny >= ch {
+					continue
+				}
+				allowed := make([]bool, len(patterns))
+				for p, ok := range wave[c.Y][c.X] {
+					if ok {
+						for _, q := range fits[p][d] {
+							allowed[q] = true
+						}
+					}
+				}
+				changed := false
+				for q, ok := range wave[ny][nx] {
+					if ok && !allowed[q] {
+						wave[ny][nx][q] = false
+						left[ny][nx]--
+						changed = true
+					}
+				}
+				if left[ny][nx] == 0 {
+					return nil, false
+				}
+				if changed {
+					stack = append(stack, Point{X: nx, Y: ny})
+				}
+			}
+		}
+	}
+
+	chosen := make([][]int, ch)
+	for y := range chosen {
+		chosen[y] = make([]int, cw)
+		for x := range chosen[y] {
+			for p, ok := range wave[y][x] {
+				if ok {
+					chosen[y][x] = p
+				}
+			}
+		}
+	}
+	return chosen, true
+}
+
+// wfcPatterns returns every n x n square of sample, with their rotations and reflections if symmetry is set, counting
+// how often each appears. Patterns are in the order they're first found so the same sample always gives the same list
+func wfcPatterns(sample [][]Tile, n int, symmetry bool) []wfcPattern {
+	patterns := make([]wfcPattern, 0)
+	index := make(map[string]int)
+	add := func(tiles [][]Tile) {
+		key := make([]byte, 0, n*n*2)
+		for _, row := range tiles {
+			for _, t := range row {
+				key = append(key, byte(t), byte(t>>8))
+			}
+		}
+		if i, ok := index[string(key)]; ok {
+			patterns[i].weight++
+			return
+		}
+		index[string(key)] = len(patterns)
+		patterns = append(patterns, wfcPattern{tiles: tiles, weight: 1})
+	}
+
+	for y := 0; y+n <= len(sample); y++ {
+		for x := 0; x+n <= len(sample[0]); x++ {
+			tiles := make([][]Tile, n)
+			for dy := range tiles {
+				tiles[dy] = append([]Tile(nil), sample[y+dy][x:x+n]...)
+			}
+			add(tiles)
+			if !symmetry {
+				continue
+			}
+			for r := 0; r < 4; r++ {
+				if r > 0 {
+					tiles = wfcRotate(tiles)
+					add(tiles)
+				}
+				add(wfcReflect(tiles))
+			}
+		}
+	}
+	return patterns
+}
+
+// wfcRotate returns tiles turned a quarter clockwise
+func wfcRotate(tiles [][]Tile) [][]Tile {
+	n := len(tiles)
+	rotated := make([][]Tile, n)
+	for y := range rotated {
+		rotated[y] = make([]Tile, n)
+		for x := range rotated[y] {
+			rotated[y][x] = tiles[n-1-x][y]
+		}
+	}
+	return rotated
+}
+
+// wfcReflect returns tiles flipped left to right
+func wfcReflect(tiles [][]Tile) [][]Tile {
+	n := len(tiles)
+	reflected := make([][]Tile, n)
+	for y := range reflected {
+		reflected[y] = make([]Tile, n)
+		for x := range reflected[y] {
+			reflected[y][x] = tiles[y][n-1-x]
+		}
+	}
+	return reflected
+}
+
+// wfcOverlaps returns whether b can be placed dx,dy from a, with the tiles where they overlap matching
+func wfcOverlaps(a, b [][]Tile, dx, dy int) bool {
+	n := len(a)
+	for y := maxInt(0, dy); y < minInt(n, n+dy); y++ {
+		for x := maxInt(0, dx); x < minInt(n, n+dx); x++ {
+			if a[y][x] != b[y-dy][x-dx] {
+				return false
+			}
+		}
+	}
+	return true
+}
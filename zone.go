@@ -0,0 +1,77 @@
+package generate
+
+// ZoneOverrides replaces some of the world's parameters inside of a zone, zero values keep the world's value
+type ZoneOverrides struct {
+	WallThickness   int
+	NoWallThickness bool // use a WallThickness of 0 in the zone, which WallThickness can't set as 0 keeps the world's
+	MinCorridorSize int
+	MaxCorridorSize int
+	MaxRoomWidth    int
+	MaxRoomHeight   int
+	MinRoomWidth    int
+	MinRoomHeight   int
+}
+
+// Zone is an area of the world which is generated with different parameters
+type Zone struct {
+	Area      Rect
+	Overrides ZoneOverrides
+}
+
+// AddZone overrides the world's parameters inside of area. Zones are kept between generations and when zones overlap
+// the one added last wins. GenerateDungeon, Expand, GenerateRandomWalk and AddWalls use the parameters of the zone
+// they're working in, rooms use the zone of the room they're attached to
+func (world *World) AddZone(area Rect, overrides ZoneOverrides) {
+	world.Zones = append(world.Zones, Zone{Area: area, Overrides: overrides})
+}
+
+// ClearZones removes every zone
+func (world *World) ClearZones() {
+	world.Zones = nil
+}
+
+// paramsAt returns the world's parameters at x,y with the overrides of the zone there applied
+func (world *World) paramsAt(x, y int) ZoneOverrides {
+	p := ZoneOverrides{
+		WallThickness:   world.WallThickness,
+		MinCorridorSize: world.MinCorridorSize,
+		MaxCorridorSize: world.MaxCorridorSize,
+		MaxRoomWidth:    world.MaxRoomWidth,
+		MaxRoomHeight:   world.MaxRoomHeight,
+		MinRoomWidth:    world.MinRoomWidth,
+		MinRoomHeight:   world.MinRoomHeight,
+	}
+	for i := len(world.Zones) - 1; i >= 0; i-- {
+		zone := world.Zones[i]
+		if !zone.Area.contains(x, y) {
+			continue
+		}
+		o := zone.Overrides
+		override := func(v *int, with int) {
+			if with != 0 {
+				*v = with
+			}
+		}
+		override(&p.WallThickness, o.WallThickness)
+		if o.NoWallThickness {
+			p.WallThickness = 0
+		}
+		override(&p.MinCorridorSize, o.MinCorridorSize)
+		override(&p.MaxCorridorSize, o.MaxCorridorSize)
+		override(&p.MaxRoomWidth, o.MaxRoomWidth)
+		override(&p.MaxRoomHeight, o.MaxRoomHeight)
+		override(&p.MinRoomWidth, o.MinRoomWidth)
+		override(&p.MinRoomHeight, o.MinRoomHeight)
+		break
+	}
+	return p
+}
+
+// maxWallThickness returns the thickest walls used anywhere in the world
+func (world *World) maxWallThickness() int {
+	t := world.WallThickness
+	for _, zone := range world.Zones {
+		t = maxInt(t, zone.Overrides.WallThickness)
+	}
+	return t
+}